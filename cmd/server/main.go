@@ -11,10 +11,19 @@ import (
 	"syscall"
 	"time"
 
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/attachment"
 	"blinkchat-backend/internal/auth"
+	"blinkchat-backend/internal/blob"
+	"blinkchat-backend/internal/broker"
 	"blinkchat-backend/internal/chat"
 	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/events"
+	"blinkchat-backend/internal/mailer"
+	"blinkchat-backend/internal/media"
 	"blinkchat-backend/internal/middleware"
+	"blinkchat-backend/internal/migrate"
+	"blinkchat-backend/internal/ratelimit"
 	"blinkchat-backend/internal/store"
 	"blinkchat-backend/internal/user"
 	"blinkchat-backend/internal/websocket"
@@ -33,42 +42,170 @@ func main() {
 	log.Println("Chat Backend Starting...")
 	log.Printf("Server will run on port: %s", config.Cfg.ServerPort)
 	log.Printf("JWT Secret (first 5 chars for check): %s...", previewSecret(config.Cfg.JWTSecret))
-	log.Printf("Database URL Host (for check): %s", getDBHostForMain(config.Cfg.DatabaseURL))
 
 	dbCtx := context.Background()
-	dbpool, err := pgxpool.New(dbCtx, config.Cfg.DatabaseURL)
-	if err != nil {
-		log.Fatalf("Unable to create connection pool: %v\n", err)
-	}
-	defer dbpool.Close()
 
-	err = dbpool.Ping(dbCtx)
-	if err != nil {
-		log.Fatalf("Unable to connect to database: %v\n", err)
-	}
-	log.Println("Successfully connected to the database!")
+	var userStore store.UserStore
+	var chatStore store.ChatStore
+	var messageStore store.MessageStore
+	var sessionStore store.SessionStore
+	var eventStore store.EventStore
+	var loginStore store.LoginAttemptStore
+	var tokenStore store.ActionTokenStore
+	var blockStore store.BlockStore
+	var attachmentStore store.AttachmentStore
+	var hubBroker broker.Broker
+
+	switch config.Cfg.StoreBackend {
+	case config.StoreBackendMemory:
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			log.Fatal("Migration commands require STORE_BACKEND=postgres.")
+		}
+		if len(os.Args) > 1 && os.Args[1] == "unlock" {
+			log.Fatal("The unlock command requires STORE_BACKEND=postgres.")
+		}
+		if config.Cfg.BrokerBackend == config.BrokerBackendPostgres {
+			log.Fatal("BROKER_BACKEND=postgres requires STORE_BACKEND=postgres.")
+		}
+		log.Println("Using in-memory store backend; data will be lost on restart.")
+		memDB := store.NewMemoryDB()
+		userStore = store.NewMemoryUserStore(memDB)
+		chatStore = store.NewMemoryChatStore(memDB)
+		messageStore = store.NewMemoryMessageStore(memDB)
+		sessionStore = store.NewMemorySessionStore(memDB)
+		eventStore = store.NewMemoryEventStore(memDB)
+		loginStore = store.NewMemoryLoginAttemptStore(memDB)
+		tokenStore = store.NewMemoryActionTokenStore(memDB)
+		blockStore = store.NewMemoryBlockStore(memDB)
+		attachmentStore = store.NewMemoryAttachmentStore(memDB)
+		hubBroker = broker.NewInProcess()
+
+	default:
+		log.Printf("Database URL Host (for check): %s", getDBHostForMain(config.Cfg.DatabaseURL))
+		dbpool, err := pgxpool.New(dbCtx, config.Cfg.DatabaseURL)
+		if err != nil {
+			log.Fatalf("Unable to create connection pool: %v\n", err)
+		}
+		defer dbpool.Close()
+
+		err = dbpool.Ping(dbCtx)
+		if err != nil {
+			log.Fatalf("Unable to connect to database: %v\n", err)
+		}
+		log.Println("Successfully connected to the database!")
+
+		migrator, err := migrate.NewMigrator(dbpool)
+		if err != nil {
+			log.Fatalf("Unable to load migrations: %v\n", err)
+		}
+
+		if len(os.Args) > 1 && os.Args[1] == "migrate" {
+			if err := runMigrateCommand(dbCtx, migrator, os.Args[2:]); err != nil {
+				log.Fatalf("Migration command failed: %v\n", err)
+			}
+			return
+		}
+
+		if config.Cfg.MigrateOnStart {
+			applied, err := migrator.Up(dbCtx)
+			if err != nil {
+				log.Fatalf("Unable to apply database migrations: %v\n", err)
+			}
+			log.Printf("Database migrations up to date (%d applied).", applied)
+		}
 
-	userStore := store.NewPostgresUserStore(dbpool)
+		userStore = store.NewPostgresUserStore(dbpool)
+		chatStore = store.NewPostgresChatStore(dbpool)
+		messageStore = store.NewPostgresMessageStore(dbpool)
+		sessionStore = store.NewPostgresSessionStore(dbpool)
+		eventStore = store.NewPostgresEventStore(dbpool)
+		loginStore = store.NewPostgresLoginAttemptStore(dbpool)
+		tokenStore = store.NewPostgresActionTokenStore(dbpool)
+		blockStore = store.NewPostgresBlockStore(dbpool)
+		attachmentStore = store.NewPostgresAttachmentStore(dbpool)
+
+		if len(os.Args) > 1 && os.Args[1] == "unlock" {
+			if err := runUnlockCommand(dbCtx, loginStore, os.Args[2:]); err != nil {
+				log.Fatalf("Unlock command failed: %v\n", err)
+			}
+			return
+		}
+
+		if config.Cfg.BrokerBackend == config.BrokerBackendPostgres {
+			pgBroker, err := broker.NewPostgres(dbCtx, dbpool)
+			if err != nil {
+				log.Fatalf("Unable to start Postgres broker: %v\n", err)
+			}
+			defer pgBroker.Close()
+			hubBroker = pgBroker
+		} else {
+			hubBroker = broker.NewInProcess()
+		}
+	}
 	log.Printf("UserStore initialized: %T", userStore)
-	chatStore := store.NewPostgresChatStore(dbpool)
 	log.Printf("ChatStore initialized: %T", chatStore)
-	messageStore := store.NewPostgresMessageStore(dbpool)
 	log.Printf("MessageStore initialized: %T", messageStore)
+	log.Printf("SessionStore initialized: %T", sessionStore)
+	log.Printf("EventStore initialized: %T", eventStore)
+	log.Printf("LoginAttemptStore initialized: %T", loginStore)
+	log.Printf("ActionTokenStore initialized: %T", tokenStore)
+	log.Printf("BlockStore initialized: %T", blockStore)
+	log.Printf("AttachmentStore initialized: %T", attachmentStore)
+
+	var appMailer mailer.Mailer
+	if config.Cfg.MailerBackend == config.MailerBackendSMTP {
+		appMailer = mailer.NewSMTP(config.Cfg.SMTPHost, config.Cfg.SMTPPort, config.Cfg.SMTPUsername, config.Cfg.SMTPPassword, config.Cfg.MailFrom)
+	} else {
+		appMailer = mailer.NewLog(config.Cfg.MailDir, config.Cfg.MailFrom)
+	}
+	log.Printf("Mailer initialized: %T", appMailer)
 
-	wsHub := websocket.NewHub(userStore, chatStore, messageStore)
+	var blobStore blob.BlobStore
+	if config.Cfg.BlobBackend == config.BlobBackendS3 {
+		s3Store, err := blob.NewS3(blob.S3Config{
+			Endpoint:  config.Cfg.S3Endpoint,
+			Region:    config.Cfg.S3Region,
+			Bucket:    config.Cfg.S3Bucket,
+			AccessKey: config.Cfg.S3AccessKey,
+			SecretKey: config.Cfg.S3SecretKey,
+		})
+		if err != nil {
+			log.Fatalf("Unable to configure S3 blob store: %v\n", err)
+		}
+		blobStore = s3Store
+	} else {
+		blobStore = blob.NewLocal(config.Cfg.BlobDir)
+	}
+	log.Printf("BlobStore initialized: %T", blobStore)
+	log.Printf("Broker initialized: %T", hubBroker)
+
+	guard := access.NewGuard(chatStore, messageStore, blockStore, attachmentStore)
+	eventLog := events.NewLog(eventStore, guard)
+
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), config.Cfg.RateLimits)
+	log.Printf("Rate limits: %v", config.Cfg.RateLimits)
+
+	wsHub := websocket.NewHub(userStore, chatStore, messageStore, guard, eventLog, hubBroker, limiter)
 	go wsHub.Run()
+
+	mediaProcessor := media.NewProcessor(attachmentStore, blobStore, wsHub, config.Cfg.ThumbnailSizes, config.Cfg.AttachmentMaxBytes, config.Cfg.MediaWorkers)
+	go mediaProcessor.Run(context.Background())
+	log.Printf("Media processor started with %d worker(s), thumbnail sizes %v", config.Cfg.MediaWorkers, config.Cfg.ThumbnailSizes)
 	log.Println("WebSocket Hub initialized and running.")
 
-	authHandler := auth.NewAuthHandler(userStore)
+	authHandler := auth.NewAuthHandler(userStore, sessionStore, chatStore, loginStore, tokenStore, appMailer, wsHub)
 	log.Printf("AuthHandler initialized: %T", authHandler)
 
-	userHandler := user.NewUserHandler(userStore)
+	userHandler := user.NewUserHandler(userStore, blockStore, guard, wsHub)
 	log.Printf("UserHandler initialized: %T", userHandler)
 
-	chatRestHandler := chat.NewRestHandler(chatStore, messageStore, userStore, wsHub)
+	chatRestHandler := chat.NewRestHandler(chatStore, messageStore, userStore, attachmentStore, guard, eventLog, wsHub)
 	log.Printf("ChatRestHandler initialized: %T", chatRestHandler)
 
-	wsHandler := websocket.NewWSHandler(wsHub)
+	attachmentHandler := attachment.NewHandler(attachmentStore, messageStore, blobStore, guard, mediaProcessor)
+	log.Printf("AttachmentHandler initialized: %T", attachmentHandler)
+
+	wsHandler := websocket.NewWSHandler(wsHub, sessionStore)
 	log.Printf("WSHandler initialized: %T", wsHandler)
 
 	gin.SetMode(gin.ReleaseMode) // Or gin.DebugMode
@@ -88,25 +225,66 @@ func main() {
 		c.JSON(http.StatusOK, gin.H{"status": "UP"})
 	})
 
-	r.GET("/ws", wsHandler.HandleWebSocketConnection)
+	r.GET("/ws", middleware.RateLimit(limiter, ratelimit.PolicyWSConnect, middleware.ByClientIP), wsHandler.HandleWebSocketConnection)
 
 	apiV1 := r.Group("/api/v1")
 	{
 		publicAuthRoutes := apiV1.Group("/auth")
+		publicAuthRoutes.Use(middleware.RateLimit(limiter, ratelimit.PolicyAuth, middleware.ByClientIP))
 		{
 			publicAuthRoutes.POST("/register", authHandler.Register)
 			publicAuthRoutes.POST("/login", authHandler.Login)
+			publicAuthRoutes.POST("/refresh", authHandler.Refresh)
+			publicAuthRoutes.POST("/verify", authHandler.VerifyEmail)
+			publicAuthRoutes.POST("/verify/resend", authHandler.ResendVerification)
+			publicAuthRoutes.POST("/forgot-password", authHandler.ForgotPassword)
+			publicAuthRoutes.POST("/reset-password", authHandler.ResetPassword)
 		}
 
+		// Signed download links; see attachment.Handler.Download.
+		apiV1.GET("/attachments/:id/content", attachmentHandler.Download)
+		apiV1.GET("/attachments/:id/thumbnails/:size", attachmentHandler.DownloadThumbnail)
+
 		protected := apiV1.Group("/")
-		protected.Use(middleware.AuthMiddleware())
+		protected.Use(middleware.AuthMiddleware(sessionStore), middleware.RateLimit(limiter, ratelimit.PolicyAPI, middleware.ByUser))
 		{
 			protected.GET("/auth/me", authHandler.GetMe)
+			protected.POST("/auth/logout", authHandler.Logout)
+			protected.POST("/auth/logout-all", authHandler.LogoutAll)
+			protected.PATCH("/users/me", authHandler.UpdateProfile)
+			protected.DELETE("/users/me", authHandler.DeleteAccount)
+			protected.POST("/users/me/password", authHandler.ChangePassword)
+			protected.GET("/users/me/settings", userHandler.GetSettings)
+			protected.PATCH("/users/me/settings", userHandler.UpdateSettings)
+			protected.GET("/users/me/blocks", userHandler.GetBlockedUsers)
 			protected.GET("/users/:id", userHandler.GetUserByID)
-			protected.GET("/users", userHandler.SearchUsers)
-			protected.POST("/messages", chatRestHandler.PostMessage)
+			protected.GET("/users/:id/presence", userHandler.GetUserPresence)
+			protected.POST("/users/:id/block", userHandler.BlockUser)
+			protected.DELETE("/users/:id/block", userHandler.UnblockUser)
+			protected.GET("/users", middleware.RateLimit(limiter, ratelimit.PolicySearch, middleware.ByUser), userHandler.SearchUsers)
+			protected.POST("/messages", middleware.RateLimit(limiter, ratelimit.PolicyMessages, middleware.ByUser), chatRestHandler.PostMessage)
 			protected.GET("/messages", chatRestHandler.GetMessagesByChatID)
+			protected.PATCH("/messages/:id", chatRestHandler.EditMessage)
+			protected.DELETE("/messages/:id", chatRestHandler.DeleteMessage)
+			protected.GET("/messages/:id/history", chatRestHandler.GetMessageHistory)
+			protected.GET("/messages/:id/receipts", chatRestHandler.GetMessageReceipts)
+			protected.GET("/messages/:id/thread", chatRestHandler.GetThread)
+			protected.POST("/messages/:id/reactions", chatRestHandler.AddReaction)
+			protected.DELETE("/messages/:id/reactions", chatRestHandler.RemoveReaction)
+			protected.POST("/attachments", middleware.RateLimit(limiter, ratelimit.PolicyUploads, middleware.ByUser), attachmentHandler.Upload)
+			protected.GET("/attachments/:id", attachmentHandler.GetAttachment)
 			protected.GET("/chats", chatRestHandler.GetChats)
+			protected.GET("/chats/unread", chatRestHandler.GetUnreadTotal)
+			protected.POST("/chats", chatRestHandler.CreateGroupChat)
+			protected.GET("/chats/:id/members", chatRestHandler.GetChatMembers)
+			protected.POST("/chats/:id/members", chatRestHandler.AddChatMembers)
+			protected.PATCH("/chats/:id/members/:userId", chatRestHandler.UpdateChatMember)
+			protected.DELETE("/chats/:id/members/:userId", chatRestHandler.RemoveChatMember)
+			protected.POST("/chats/:id/leave", chatRestHandler.LeaveChat)
+			protected.POST("/chats/:id/read", chatRestHandler.MarkChatRead)
+			protected.POST("/chats/:id/mute", chatRestHandler.MuteChat)
+			protected.DELETE("/chats/:id/mute", chatRestHandler.UnmuteChat)
+			protected.POST("/sync", chatRestHandler.Sync)
 		}
 	}
 
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"blinkchat-backend/internal/auth"
+	"blinkchat-backend/internal/store"
+)
+
+const unlockUsage = "usage: server unlock <email> | server unlock --ip <address>"
+
+// runUnlockCommand implements the `unlock` subcommand, which lifts a login lockout and
+// resets the failure count of an account or client IP.
+func runUnlockCommand(ctx context.Context, loginStore store.LoginAttemptStore, args []string) error {
+	var key string
+	switch {
+	case len(args) == 1 && args[0] != "--ip":
+		key = auth.AccountThrottleKey(args[0])
+	case len(args) == 2 && args[0] == "--ip":
+		key = auth.IPThrottleKey(args[1])
+	default:
+		return fmt.Errorf(unlockUsage)
+	}
+
+	cleared, err := loginStore.ClearLoginThrottles(ctx, []string{key})
+	if err != nil {
+		return err
+	}
+	if cleared == 0 {
+		log.Printf("Unlock: No failed logins recorded for %s", key)
+		return nil
+	}
+	log.Printf("Unlock: Cleared failed logins for %s", key)
+	return nil
+}
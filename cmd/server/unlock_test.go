@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/auth"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func TestUnlockCommand(t *testing.T) {
+	ctx := context.Background()
+	account, ip := auth.AccountThrottleKey("someone@example.com"), auth.IPThrottleKey("192.0.2.1")
+	for _, tt := range []struct {
+		name    string
+		args    []string
+		wantErr bool
+		cleared string
+	}{
+		{name: "account", args: []string{" Someone@Example.com "}, cleared: account},
+		{name: "IP", args: []string{"--ip", "192.0.2.1"}, cleared: ip},
+		{name: "unknown account", args: []string{"nobody@example.com"}},
+		{name: "no arguments", wantErr: true},
+		{name: "IP without address", args: []string{"--ip"}, wantErr: true},
+		{name: "too many arguments", args: []string{"a@example.com", "b@example.com"}, wantErr: true},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			logins := store.NewMemoryLoginAttemptStore(store.NewMemoryDB())
+			attempt := &models.FailedLogin{ID: uuid.New(), Email: "someone@example.com", IPAddress: "192.0.2.1", Reason: models.FailedLoginWrongPassword, AttemptedAt: time.Now()}
+			if _, err := logins.RecordFailedLogin(ctx, attempt, []string{account, ip}, time.Hour); err != nil {
+				t.Fatalf("RecordFailedLogin: %v", err)
+			}
+
+			err := runUnlockCommand(ctx, logins, tt.args)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("runUnlockCommand(%q) error = %v, want error %v", tt.args, err, tt.wantErr)
+			}
+			throttles, err := logins.GetLoginThrottles(ctx, []string{account, ip})
+			if err != nil {
+				t.Fatalf("GetLoginThrottles: %v", err)
+			}
+			for _, th := range throttles {
+				if th.Key == tt.cleared {
+					t.Errorf("%s still has %d failures", th.Key, th.Failures)
+				}
+			}
+			want := 2
+			if tt.cleared != "" {
+				want = 1
+			}
+			if len(throttles) != want {
+				t.Errorf("%d throttles left, want %d", len(throttles), want)
+			}
+		})
+	}
+}
@@ -0,0 +1,231 @@
+package access
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+var (
+	// ErrForbidden is returned when the user is not a participant of the chat.
+	ErrForbidden = errors.New("user is not a participant of this chat")
+	// ErrNotAdmin is returned when an admin-only action is attempted by a regular member.
+	ErrNotAdmin = errors.New("only chat admins can perform this action")
+	// ErrBlocked is returned when one user has blocked the other from messaging them.
+	ErrBlocked = errors.New("messaging between these users is blocked")
+	// ErrInvalidReply is returned when a reply names a message that is missing, deleted or
+	// in another chat.
+	ErrInvalidReply = errors.New("reply target must be an existing message in the same chat")
+	// ErrInvalidAttachment is returned when a message names an attachment that is missing,
+	// was uploaded by someone else or has already been sent.
+	ErrInvalidAttachment = errors.New("attachments must be unsent uploads of the sender")
+)
+
+// Guard centralises chat membership checks shared by the REST handlers and the WebSocket hub.
+type Guard struct {
+	chatStore       store.ChatStore
+	messageStore    store.MessageStore
+	blockStore      store.BlockStore
+	attachmentStore store.AttachmentStore
+}
+
+// NewGuard returns a Guard backed by the given stores.
+func NewGuard(cs store.ChatStore, ms store.MessageStore, bs store.BlockStore, as store.AttachmentStore) *Guard {
+	return &Guard{chatStore: cs, messageStore: ms, blockStore: bs, attachmentStore: as}
+}
+
+// RequireParticipant returns ErrForbidden unless userID is a member of chatID.
+func (g *Guard) RequireParticipant(ctx context.Context, chatID, userID uuid.UUID) error {
+	ok, err := g.chatStore.IsParticipant(ctx, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to verify chat membership: %w", err)
+	}
+	if !ok {
+		return ErrForbidden
+	}
+	return nil
+}
+
+// Relationship classifies how viewerID relates to userID for profile privacy: the same
+// user, someone they share a chat with, or a stranger.
+func (g *Guard) Relationship(ctx context.Context, viewerID, userID uuid.UUID) (models.Relationship, error) {
+	if viewerID == userID {
+		return models.RelationshipSelf, nil
+	}
+	shares, err := g.chatStore.SharesChat(ctx, viewerID, userID)
+	if err != nil {
+		return models.RelationshipStranger, fmt.Errorf("failed to check shared chats: %w", err)
+	}
+	if shares {
+		return models.RelationshipContact, nil
+	}
+	return models.RelationshipStranger, nil
+}
+
+// RequireNotBlocked returns ErrBlocked if either user has blocked the other.
+func (g *Guard) RequireNotBlocked(ctx context.Context, senderID, recipientID uuid.UUID) error {
+	blocked, err := g.blockStore.IsBlockedBetween(ctx, senderID, recipientID)
+	if err != nil {
+		return fmt.Errorf("failed to check blocks: %w", err)
+	}
+	if blocked {
+		return ErrBlocked
+	}
+	return nil
+}
+
+// RequireCanMessage returns ErrBlocked when chatID is a direct chat and its two members
+// have blocked one another. Blocks do not silence group chats.
+func (g *Guard) RequireCanMessage(ctx context.Context, chatID, senderID uuid.UUID) error {
+	chat, err := g.chatStore.GetChatByID(ctx, chatID)
+	if err != nil {
+		return fmt.Errorf("failed to load chat: %w", err)
+	}
+	if chat.IsGroup {
+		return nil
+	}
+	for _, p := range chat.OtherParticipants {
+		if p.ID == senderID {
+			continue
+		}
+		if err := g.RequireNotBlocked(ctx, senderID, p.ID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WithoutBlockedPeers drops from userIDs everyone who has blocked userID or been blocked
+// by them, so that typing and presence updates do not cross a block.
+func (g *Guard) WithoutBlockedPeers(ctx context.Context, userID uuid.UUID, userIDs []uuid.UUID) ([]uuid.UUID, error) {
+	peerIDs, err := g.blockStore.GetBlockedPeerIDs(ctx, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load blocks: %w", err)
+	}
+	if len(peerIDs) == 0 {
+		return userIDs, nil
+	}
+	blocked := make(map[uuid.UUID]bool, len(peerIDs))
+	for _, id := range peerIDs {
+		blocked[id] = true
+	}
+	kept := make([]uuid.UUID, 0, len(userIDs))
+	for _, id := range userIDs {
+		if !blocked[id] {
+			kept = append(kept, id)
+		}
+	}
+	return kept, nil
+}
+
+// RequireRole returns the caller's role, failing with ErrForbidden for non-members
+// and ErrNotAdmin when minRole is admin and the caller is a regular member.
+func (g *Guard) RequireRole(ctx context.Context, chatID, userID uuid.UUID, minRole models.ChatRole) (models.ChatRole, error) {
+	role, err := g.chatStore.GetParticipantRole(ctx, chatID, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotParticipant) {
+			return "", ErrForbidden
+		}
+		return "", fmt.Errorf("failed to verify chat membership: %w", err)
+	}
+	if minRole == models.RoleAdmin && role != models.RoleAdmin {
+		return "", ErrNotAdmin
+	}
+	return role, nil
+}
+
+// RequireReplyTarget loads the message a new message in chatID replies to, failing with
+// ErrInvalidReply unless it is a live message of that chat.
+func (g *Guard) RequireReplyTarget(ctx context.Context, chatID, replyToID uuid.UUID) (*models.Message, error) {
+	parent, err := g.messageStore.GetMessageByID(ctx, replyToID)
+	if err != nil {
+		if errors.Is(err, store.ErrMessageNotFound) {
+			return nil, ErrInvalidReply
+		}
+		return nil, fmt.Errorf("failed to load reply target: %w", err)
+	}
+	if parent.ChatID != chatID || parent.DeletedAt != nil {
+		return nil, ErrInvalidReply
+	}
+	return parent, nil
+}
+
+// RequireMessageAccess loads a message and checks that userID belongs to its chat.
+// A missing message and a message in someone else's chat both surface as
+// store.ErrMessageNotFound / ErrForbidden respectively.
+func (g *Guard) RequireMessageAccess(ctx context.Context, messageID, userID uuid.UUID) (*models.Message, error) {
+	msg, err := g.messageStore.GetMessageByID(ctx, messageID)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.RequireParticipant(ctx, msg.ChatID, userID); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+// RequireSendableAttachments loads the attachments a new message by senderID carries, in
+// the order given, failing with ErrInvalidAttachment unless each is an unsent upload of
+// the sender. Duplicate IDs are dropped.
+func (g *Guard) RequireSendableAttachments(ctx context.Context, senderID uuid.UUID, attachmentIDs []uuid.UUID) ([]*models.Attachment, error) {
+	if len(attachmentIDs) == 0 {
+		return nil, nil
+	}
+	ids := make([]uuid.UUID, 0, len(attachmentIDs))
+	seen := make(map[uuid.UUID]bool, len(attachmentIDs))
+	for _, id := range attachmentIDs {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	found, err := g.attachmentStore.GetAttachmentsByIDs(ctx, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load attachments: %w", err)
+	}
+	byID := make(map[uuid.UUID]*models.Attachment, len(found))
+	for _, a := range found {
+		byID[a.ID] = a
+	}
+	attachments := make([]*models.Attachment, 0, len(ids))
+	for _, id := range ids {
+		a, ok := byID[id]
+		if !ok || a.UploaderID != senderID || a.MessageID != nil {
+			return nil, ErrInvalidAttachment
+		}
+		attachments = append(attachments, a)
+	}
+	return attachments, nil
+}
+
+// RequireAttachmentAccess loads an attachment that userID may download: one they uploaded
+// and have not sent yet, or one sent with a live message in a chat they belong to. Anything
+// else surfaces as store.ErrAttachmentNotFound, or ErrForbidden for other chats' files.
+func (g *Guard) RequireAttachmentAccess(ctx context.Context, attachmentID, userID uuid.UUID) (*models.Attachment, error) {
+	a, err := g.attachmentStore.GetAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		return nil, err
+	}
+	if a.MessageID == nil {
+		if a.UploaderID != userID {
+			return nil, store.ErrAttachmentNotFound
+		}
+		return a, nil
+	}
+	msg, err := g.RequireMessageAccess(ctx, *a.MessageID, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrMessageNotFound) {
+			return nil, store.ErrAttachmentNotFound
+		}
+		return nil, err
+	}
+	if msg.DeletedAt != nil {
+		return nil, store.ErrAttachmentNotFound
+	}
+	return a, nil
+}
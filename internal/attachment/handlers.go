@@ -0,0 +1,332 @@
+// Package attachment serves file uploads and the signed download links of sent files.
+package attachment
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"path"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/blob"
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/media"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// sniffBytes is how much of an upload http.DetectContentType looks at.
+const sniffBytes = 512
+
+// multipartOverhead is the allowance for multipart headers and boundaries on top of
+// the file itself when capping the request body.
+const multipartOverhead = 1 << 20
+
+// maxFilenameRunes bounds the stored name of an upload.
+const maxFilenameRunes = 255
+
+// Handler exposes attachment upload and download endpoints.
+type Handler struct {
+	attachmentStore store.AttachmentStore
+	messageStore    store.MessageStore
+	blobs           blob.BlobStore
+	guard           *access.Guard
+	processor       *media.Processor
+}
+
+// NewHandler creates a Handler storing file contents in blobs and handing images to processor.
+func NewHandler(as store.AttachmentStore, ms store.MessageStore, blobs blob.BlobStore, guard *access.Guard, processor *media.Processor) *Handler {
+	return &Handler{attachmentStore: as, messageStore: ms, blobs: blobs, guard: guard, processor: processor}
+}
+
+// Upload stores the multipart "file" field and returns the new attachment, whose ID can
+// then be sent in a message's attachmentIds. The media type is sniffed from the content
+// rather than trusted from the client. Images are queued for the media processor and can
+// be downloaded once it has stripped their location data.
+func (h *Handler) Upload(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	maxBytes := config.Cfg.AttachmentMaxBytes
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, maxBytes+multipartOverhead)
+
+	header, err := c.FormFile("file")
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondTooLarge(c, maxBytes)
+			return
+		}
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A file is required in the 'file' form field", "details": err.Error()})
+		return
+	}
+	if header.Size > maxBytes {
+		respondTooLarge(c, maxBytes)
+		return
+	}
+	if header.Size == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "File is empty"})
+		return
+	}
+
+	file, err := header.Open()
+	if err != nil {
+		log.Printf("Upload: Failed to open upload of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	defer file.Close()
+
+	head := make([]byte, sniffBytes)
+	n, err := io.ReadFull(file, head)
+	if err != nil && !errors.Is(err, io.ErrUnexpectedEOF) {
+		log.Printf("Upload: Failed to read upload of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read upload"})
+		return
+	}
+	head = head[:n]
+	contentType := http.DetectContentType(head)
+	if !allowedType(contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("Files of type %s are not allowed", contentType)})
+		return
+	}
+	if unprocessableImage(contentType) {
+		c.JSON(http.StatusUnsupportedMediaType, gin.H{"error": fmt.Sprintf("Images of type %s cannot be processed", contentType)})
+		return
+	}
+
+	now := time.Now()
+	attachment := &models.Attachment{
+		ID:          uuid.New(),
+		UploaderID:  userID,
+		Filename:    cleanFilename(header.Filename),
+		ContentType: contentType,
+		Size:        header.Size,
+		CreatedAt:   now,
+	}
+	attachment.BlobKey = "attachments/" + attachment.ID.String()
+	if media.Processable(contentType) {
+		attachment.ProcessingStatus = models.ProcessingPending
+	}
+
+	ctx := c.Request.Context()
+	if err := h.blobs.Put(ctx, attachment.BlobKey, io.MultiReader(bytes.NewReader(head), file), header.Size, contentType); err != nil {
+		log.Printf("Upload: Failed to store upload of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store upload"})
+		return
+	}
+	if err := h.attachmentStore.CreateAttachment(ctx, attachment); err != nil {
+		log.Printf("Upload: Failed to record attachment %s of user %s: %v", attachment.ID, userID, err)
+		if delErr := h.blobs.Delete(ctx, attachment.BlobKey); delErr != nil {
+			log.Printf("Upload: Failed to remove orphaned blob %s: %v", attachment.BlobKey, delErr)
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to store upload"})
+		return
+	}
+	if attachment.ProcessingStatus == models.ProcessingPending && h.processor != nil {
+		h.processor.Enqueue(attachment.ID)
+	}
+
+	utils.SignAttachmentURLs([]*models.Attachment{attachment}, now)
+	c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAttachment returns an attachment with a fresh download link to its uploader and,
+// once it has been sent, to members of its chat.
+func (h *Handler) GetAttachment(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID format"})
+		return
+	}
+
+	attachment, err := h.guard.RequireAttachmentAccess(c.Request.Context(), attachmentID, userID)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrAttachmentNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+		case errors.Is(err, access.ErrForbidden):
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a participant of this chat"})
+		default:
+			log.Printf("GetAttachment: Failed to verify access of user %s to attachment %s: %v", userID, attachmentID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachment"})
+		}
+		return
+	}
+
+	utils.SignAttachmentURLs([]*models.Attachment{attachment}, time.Now())
+	c.JSON(http.StatusOK, attachment)
+}
+
+// Download streams an attachment's contents for a signed link. It sits outside the
+// authenticated routes so that links work in <img> tags; the signature, handed out only
+// to users allowed to see the file, is the authorisation.
+func (h *Handler) Download(c *gin.Context) {
+	attachment, ok := h.loadSigned(c)
+	if !ok {
+		return
+	}
+	h.serveBlob(c, attachment.BlobKey, attachment.Size, attachment.ContentType, contentDisposition(attachment.ContentType, attachment.Filename))
+}
+
+// DownloadThumbnail streams one of an image attachment's thumbnails for a signed link.
+func (h *Handler) DownloadThumbnail(c *gin.Context) {
+	attachment, ok := h.loadSigned(c)
+	if !ok {
+		return
+	}
+	for _, t := range attachment.Thumbnails {
+		if strconv.Itoa(t.MaxEdge) == c.Param("size") {
+			name := strings.TrimSuffix(attachment.Filename, path.Ext(attachment.Filename)) + "-" + c.Param("size") + ".jpg"
+			h.serveBlob(c, models.ThumbnailBlobKey(attachment.ID, t.MaxEdge), t.Size, "image/jpeg", contentDisposition("image/jpeg", name))
+			return
+		}
+	}
+	c.JSON(http.StatusNotFound, gin.H{"error": "Thumbnail not found"})
+}
+
+// loadSigned checks the signature of a download link and loads its attachment, refusing
+// files of deleted messages and images the media processor has not cleared or cannot handle.
+func (h *Handler) loadSigned(c *gin.Context) (*models.Attachment, bool) {
+	attachmentID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid attachment ID format"})
+		return nil, false
+	}
+	if err := utils.VerifyAttachmentURL(attachmentID, c.Query("expires"), c.Query("signature"), time.Now()); err != nil {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Download link is invalid or has expired"})
+		return nil, false
+	}
+
+	ctx := c.Request.Context()
+	attachment, err := h.attachmentStore.GetAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		if errors.Is(err, store.ErrAttachmentNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return nil, false
+		}
+		log.Printf("Download: Failed to get attachment %s: %v", attachmentID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachment"})
+		return nil, false
+	}
+	if attachment.MessageID != nil {
+		msg, err := h.messageStore.GetMessageByID(ctx, *attachment.MessageID)
+		if err != nil && !errors.Is(err, store.ErrMessageNotFound) {
+			log.Printf("Download: Failed to get message of attachment %s: %v", attachmentID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachment"})
+			return nil, false
+		}
+		if msg == nil || msg.DeletedAt != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Attachment not found"})
+			return nil, false
+		}
+	}
+	if !attachment.Downloadable() || unprocessableImage(attachment.ContentType) {
+		if attachment.ProcessingStatus == models.ProcessingPending {
+			c.JSON(http.StatusConflict, gin.H{"error": "Attachment is still being processed"})
+		} else {
+			c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Attachment could not be processed"})
+		}
+		return nil, false
+	}
+	return attachment, true
+}
+
+func (h *Handler) serveBlob(c *gin.Context, key string, size int64, contentType, disposition string) {
+	content, err := h.blobs.Open(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("Download: Failed to open blob %s: %v", key, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve attachment"})
+		return
+	}
+	defer content.Close()
+
+	c.DataFromReader(http.StatusOK, size, contentType, content, map[string]string{
+		"Content-Disposition":    disposition,
+		"Cache-Control":          "private, max-age=300",
+		"X-Content-Type-Options": "nosniff",
+	})
+}
+
+// unprocessableImage reports whether a content type is an image the media processor
+// cannot strip of location data. Such images are neither accepted nor served, even if the
+// allow list names their type.
+func unprocessableImage(contentType string) bool {
+	return strings.HasPrefix(contentType, "image/") && !media.Processable(contentType)
+}
+
+// allowedType reports whether a sniffed content type is in the configured allow list.
+func allowedType(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	for _, t := range config.Cfg.AttachmentAllowedTypes {
+		if t == mediaType {
+			return true
+		}
+	}
+	return false
+}
+
+// contentDisposition lets browsers show media inline and downloads everything else.
+func contentDisposition(contentType, filename string) string {
+	disposition := "attachment"
+	if strings.HasPrefix(contentType, "image/") || strings.HasPrefix(contentType, "video/") || strings.HasPrefix(contentType, "audio/") {
+		disposition = "inline"
+	}
+	return mime.FormatMediaType(disposition, map[string]string{"filename": filename})
+}
+
+// cleanFilename keeps the last path element of a client-supplied name, without control
+// characters and bounded in length.
+func cleanFilename(name string) string {
+	name = path.Base(strings.ReplaceAll(name, "\\", "/"))
+	name = strings.TrimSpace(strings.Map(func(r rune) rune {
+		if unicode.IsControl(r) {
+			return -1
+		}
+		return r
+	}, name))
+	if runes := []rune(name); len(runes) > maxFilenameRunes {
+		name = string(runes[:maxFilenameRunes])
+	}
+	if name == "" || name == "." || name == "/" || name == ".." {
+		return "file"
+	}
+	return name
+}
+
+func respondTooLarge(c *gin.Context, maxBytes int64) {
+	c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("File exceeds the %d MB upload limit", maxBytes>>20)})
+}
+
+// userIDFromContext returns the authenticated user's ID set by the auth middleware.
+func userIDFromContext(c *gin.Context) (uuid.UUID, bool) {
+	userIDString, _ := c.Get("userID")
+	idStr, _ := userIDString.(string)
+	userID, err := uuid.Parse(idStr)
+	if err != nil {
+		log.Printf("userIDFromContext: Invalid userID from token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user session"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
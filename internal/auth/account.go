@@ -0,0 +1,208 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
+	"blinkchat-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// UpdateProfile changes the caller's username and/or email. A new email address must be
+// confirmed with the current password and is sent a fresh verification link.
+func (h *AuthHandler) UpdateProfile(c *gin.Context) {
+	user, ok := h.currentUser(c, "UpdateProfile")
+	if !ok {
+		return
+	}
+
+	var req models.UpdateProfileRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("UpdateProfile: Bad request data: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	username, email := user.Username, user.Email
+	if req.Username != nil {
+		username = *req.Username
+	}
+	emailChanged := req.Email != nil && *req.Email != user.Email
+	if emailChanged {
+		if !utils.CheckPasswordHash(req.CurrentPassword, user.HashedPassword) {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+			return
+		}
+		email = *req.Email
+	}
+
+	ctx := c.Request.Context()
+	if err := h.userStore.UpdateUserProfile(ctx, user.ID, username, email); err != nil {
+		switch {
+		case errors.Is(err, store.ErrEmailExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "Email already exists"})
+		case errors.Is(err, store.ErrUsernameExists):
+			c.JSON(http.StatusConflict, gin.H{"error": "Username already exists"})
+		default:
+			log.Printf("UpdateProfile: Failed to update profile of user %s: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		}
+		return
+	}
+
+	updated, err := h.userStore.GetUserByID(ctx, user.ID.String())
+	if err != nil {
+		log.Printf("UpdateProfile: Failed to reload user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+	if emailChanged {
+		if err := h.sendVerificationEmail(ctx, updated); err != nil {
+			log.Printf("UpdateProfile: Failed to issue verification token for user %s: %v", user.ID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Email changed, but failed to send the verification email"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, updated.ToSelfUser())
+}
+
+// ChangePassword replaces the caller's password after checking the current one, voids
+// any password reset links still in their mailbox, and signs out every other session.
+func (h *AuthHandler) ChangePassword(c *gin.Context) {
+	user, ok := h.currentUser(c, "ChangePassword")
+	if !ok {
+		return
+	}
+	sessionIDString, _ := c.Get("sessionID")
+	sessionID, err := uuid.Parse(sessionIDString.(string))
+	if err != nil {
+		log.Printf("ChangePassword: Invalid sessionID from token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user session"})
+		return
+	}
+
+	var req models.ChangePasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("ChangePassword: Bad request data: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	if !utils.CheckPasswordHash(req.CurrentPassword, user.HashedPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Current password is incorrect"})
+		return
+	}
+
+	hashedPassword, err := utils.HashPassword(req.NewPassword)
+	if err != nil {
+		log.Printf("ChangePassword: Failed to hash password for user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+	ctx := c.Request.Context()
+	if err := h.userStore.UpdatePassword(ctx, user.ID, hashedPassword); err != nil {
+		log.Printf("ChangePassword: Failed to update password of user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to change password"})
+		return
+	}
+
+	revoked, err := h.sessionStore.RevokeUserSessions(ctx, user.ID, &sessionID)
+	if err != nil {
+		log.Printf("ChangePassword: Failed to revoke other sessions of user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Password changed, but failed to sign out other sessions"})
+		return
+	}
+	if h.wsHub != nil {
+		for _, id := range revoked {
+			h.wsHub.DisconnectSession(id)
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password changed", "revokedSessions": len(revoked)})
+}
+
+// DeleteAccount deletes the caller's account after checking their password. See
+// store.UserStore.DeleteUser for what happens to their messages and chats.
+func (h *AuthHandler) DeleteAccount(c *gin.Context) {
+	user, ok := h.currentUser(c, "DeleteAccount")
+	if !ok {
+		return
+	}
+
+	var req models.DeleteAccountRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("DeleteAccount: Bad request data: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	if !utils.CheckPasswordHash(req.Password, user.HashedPassword) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password is incorrect"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	groupIDs, err := h.userStore.DeleteUser(ctx, user.ID, time.Now())
+	if err != nil {
+		log.Printf("DeleteAccount: Failed to delete user %s: %v", user.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete account"})
+		return
+	}
+	log.Printf("DeleteAccount: Deleted user %s and removed them from %d group chat(s)", user.ID, len(groupIDs))
+
+	if h.wsHub != nil {
+		h.wsHub.DisconnectUser(user.ID)
+		for _, chatID := range groupIDs {
+			h.announceDeparture(c, chatID, user.ID)
+		}
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// announceDeparture tells the remaining members of a group that a deleted user left it.
+func (h *AuthHandler) announceDeparture(c *gin.Context, chatID uuid.UUID, userID uuid.UUID) {
+	ctx := c.Request.Context()
+	chat, err := h.chatStore.GetChatByID(ctx, chatID)
+	if err != nil {
+		log.Printf("DeleteAccount: Failed to get chat %s: %v", chatID, err)
+		return
+	}
+	participants, err := h.chatStore.GetAllParticipantsInChat(ctx, chatID)
+	if err != nil {
+		log.Printf("DeleteAccount: Failed to get participants of chat %s: %v", chatID, err)
+		return
+	}
+	recipients := make([]uuid.UUID, 0, len(participants))
+	for _, p := range participants {
+		recipients = append(recipients, p.ID)
+	}
+	h.wsHub.PublishChatEvent(chatID, recipients, websocket.MessageTypeChatMemberRemoved, websocket.ChatMemberEventPayload{
+		ChatID:   chat.ID,
+		ChatName: chat.Name,
+		Member:   &models.PublicUser{ID: userID},
+		ActorID:  userID,
+	})
+}
+
+// currentUser loads the authenticated caller, responding with an error if that fails.
+func (h *AuthHandler) currentUser(c *gin.Context, op string) (*models.User, bool) {
+	userIDString, _ := c.Get("userID")
+	user, err := h.userStore.GetUserByID(c.Request.Context(), userIDString.(string))
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return nil, false
+		}
+		log.Printf("%s: Failed to get user %s: %v", op, userIDString, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user information"})
+		return nil, false
+	}
+	return user, true
+}
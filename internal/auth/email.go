@@ -0,0 +1,238 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/mailer"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// mailTimeout bounds how long a background mail delivery may take.
+const mailTimeout = 30 * time.Second
+
+// issueActionToken stores a new token of the given purpose for the user and returns it.
+func (h *AuthHandler) issueActionToken(ctx context.Context, userID uuid.UUID, purpose string, maxAge time.Duration) (string, error) {
+	token, hash, err := utils.GenerateActionToken(purpose)
+	if err != nil {
+		return "", err
+	}
+	now := time.Now()
+	record := &models.ActionToken{
+		ID:        uuid.New(),
+		UserID:    userID,
+		Purpose:   purpose,
+		TokenHash: hash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(maxAge),
+	}
+	if err := h.tokenStore.CreateActionToken(ctx, record); err != nil {
+		return "", err
+	}
+	return token, nil
+}
+
+// consumeActionToken checks a presented token and marks it used, returning its user.
+func (h *AuthHandler) consumeActionToken(ctx context.Context, token string, purpose string) (uuid.UUID, error) {
+	hash, err := utils.ActionTokenHash(token, purpose)
+	if err != nil {
+		return uuid.Nil, store.ErrActionTokenNotFound
+	}
+	record, err := h.tokenStore.ConsumeActionToken(ctx, purpose, hash)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return record.UserID, nil
+}
+
+// sendMail delivers msg in the background so that slow relays do not hold up requests
+// and response times do not reveal whether an account exists.
+func (h *AuthHandler) sendMail(msg mailer.Message) {
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), mailTimeout)
+		defer cancel()
+		if err := h.mailer.Send(ctx, msg); err != nil {
+			log.Printf("Mailer: Failed to send %q to %s: %v", msg.Subject, msg.To, err)
+		}
+	}()
+}
+
+// sendVerificationEmail mails the user a link that verifies their email address.
+func (h *AuthHandler) sendVerificationEmail(ctx context.Context, user *models.User) error {
+	token, err := h.issueActionToken(ctx, user.ID, models.ActionVerifyEmail, config.Cfg.EmailVerificationTokenMaxAge)
+	if err != nil {
+		return err
+	}
+	link := config.Cfg.AppBaseURL + "/verify-email?token=" + url.QueryEscape(token)
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Verify your BlinkChat email address",
+		Body: fmt.Sprintf("Hi %s,\n\nConfirm your email address by opening the link below:\n\n%s\n\nThe link expires in %v. If you did not sign up for BlinkChat, ignore this email.\n",
+			user.Username, link, config.Cfg.EmailVerificationTokenMaxAge),
+	})
+	return nil
+}
+
+// sendPasswordResetEmail mails the user a link for choosing a new password.
+func (h *AuthHandler) sendPasswordResetEmail(ctx context.Context, user *models.User) error {
+	token, err := h.issueActionToken(ctx, user.ID, models.ActionResetPassword, config.Cfg.PasswordResetTokenMaxAge)
+	if err != nil {
+		return err
+	}
+	link := config.Cfg.AppBaseURL + "/reset-password?token=" + url.QueryEscape(token)
+	h.sendMail(mailer.Message{
+		To:      user.Email,
+		Subject: "Reset your BlinkChat password",
+		Body: fmt.Sprintf("Hi %s,\n\nSomeone asked to reset the password of your BlinkChat account. Choose a new password here:\n\n%s\n\nThe link expires in %v. If this wasn't you, ignore this email; your password stays the same.\n",
+			user.Username, link, config.Cfg.PasswordResetTokenMaxAge),
+	})
+	return nil
+}
+
+// VerifyEmail confirms the caller's email address with a token from a verification mail.
+func (h *AuthHandler) VerifyEmail(c *gin.Context) {
+	var req models.VerifyEmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID, err := h.consumeActionToken(ctx, req.Token, models.ActionVerifyEmail)
+	if err != nil {
+		respondActionTokenError(c, "VerifyEmail", err)
+		return
+	}
+	if err := h.userStore.MarkEmailVerified(ctx, userID, time.Now()); err != nil {
+		log.Printf("VerifyEmail: Failed to mark email of user %s verified: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify email"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Email verified"})
+}
+
+// ResendVerification mails a fresh verification link. It answers the same way whether
+// or not the address belongs to an unverified account.
+func (h *AuthHandler) ResendVerification(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.userStore.GetUserByEmail(ctx, req.Email)
+	switch {
+	case err == nil && !user.EmailVerified():
+		if err := h.sendVerificationEmail(ctx, user); err != nil {
+			log.Printf("ResendVerification: Failed to issue verification token for user %s: %v", user.ID, err)
+		}
+	case err != nil && !errors.Is(err, store.ErrUserNotFound):
+		log.Printf("ResendVerification: Failed to get user by email %s: %v", req.Email, err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the address belongs to an unverified account, a verification email is on its way"})
+}
+
+// ForgotPassword mails a password reset link. It answers the same way whether or not
+// the address belongs to an account.
+func (h *AuthHandler) ForgotPassword(c *gin.Context) {
+	var req models.EmailRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	ctx := c.Request.Context()
+	user, err := h.userStore.GetUserByEmail(ctx, req.Email)
+	switch {
+	case err == nil:
+		if err := h.sendPasswordResetEmail(ctx, user); err != nil {
+			log.Printf("ForgotPassword: Failed to issue reset token for user %s: %v", user.ID, err)
+		}
+	case !errors.Is(err, store.ErrUserNotFound):
+		log.Printf("ForgotPassword: Failed to get user by email %s: %v", req.Email, err)
+	}
+
+	c.JSON(http.StatusAccepted, gin.H{"message": "If the address belongs to an account, a password reset email is on its way"})
+}
+
+// ResetPassword sets a new password with a token from a reset mail. It also verifies the
+// email address, lifts any login lockout and signs out every existing session.
+func (h *AuthHandler) ResetPassword(c *gin.Context) {
+	var req models.ResetPasswordRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	if _, err := utils.ActionTokenHash(req.Token, models.ActionResetPassword); err != nil {
+		respondActionTokenError(c, "ResetPassword", store.ErrActionTokenNotFound)
+		return
+	}
+
+	// Hash first so that a hashing failure does not burn the token.
+	hashedPassword, err := utils.HashPassword(req.Password)
+	if err != nil {
+		log.Printf("ResetPassword: Failed to hash password: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	ctx := c.Request.Context()
+	userID, err := h.consumeActionToken(ctx, req.Token, models.ActionResetPassword)
+	if err != nil {
+		respondActionTokenError(c, "ResetPassword", err)
+		return
+	}
+	user, err := h.userStore.GetUserByID(ctx, userID.String())
+	if err != nil {
+		log.Printf("ResetPassword: Failed to get user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+	if err := h.userStore.UpdatePassword(ctx, userID, hashedPassword); err != nil {
+		log.Printf("ResetPassword: Failed to update password of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reset password"})
+		return
+	}
+
+	if err := h.userStore.MarkEmailVerified(ctx, userID, time.Now()); err != nil {
+		log.Printf("ResetPassword: Failed to mark email of user %s verified: %v", userID, err)
+	}
+	if _, err := h.loginStore.ClearLoginThrottles(ctx, []string{AccountThrottleKey(user.Email)}); err != nil {
+		log.Printf("ResetPassword: Failed to reset login throttle for user %s: %v", userID, err)
+	}
+	if _, err := h.sessionStore.RevokeUserSessions(ctx, userID, nil); err != nil {
+		log.Printf("ResetPassword: Failed to revoke sessions of user %s: %v", userID, err)
+	}
+	if h.wsHub != nil {
+		h.wsHub.DisconnectUser(userID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Password has been reset; please log in again"})
+}
+
+// respondActionTokenError maps ConsumeActionToken failures to responses.
+func respondActionTokenError(c *gin.Context, op string, err error) {
+	switch {
+	case errors.Is(err, store.ErrActionTokenNotFound),
+		errors.Is(err, store.ErrActionTokenUsed),
+		errors.Is(err, store.ErrActionTokenExpired):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid or expired token"})
+	default:
+		log.Printf("%s: Failed to consume token: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to process token"})
+	}
+}
@@ -3,12 +3,17 @@ package auth
 import (
 	"errors"
 	"log"
+	"math"
 	"net/http"
+	"strconv"
 	"time"
 
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/mailer"
 	"blinkchat-backend/internal/models"
 	"blinkchat-backend/internal/store"
 	"blinkchat-backend/internal/utils"
+	"blinkchat-backend/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -16,12 +21,24 @@ import (
 
 // AuthHandler handles authentication-related HTTP requests.
 type AuthHandler struct {
-	userStore store.UserStore
+	userStore    store.UserStore
+	sessionStore store.SessionStore
+	chatStore    store.ChatStore
+	loginStore   store.LoginAttemptStore
+	tokenStore   store.ActionTokenStore
+	mailer       mailer.Mailer
+	wsHub        *websocket.Hub
 }
 
-func NewAuthHandler(userStore store.UserStore) *AuthHandler {
+func NewAuthHandler(userStore store.UserStore, sessionStore store.SessionStore, chatStore store.ChatStore, loginStore store.LoginAttemptStore, tokenStore store.ActionTokenStore, m mailer.Mailer, hub *websocket.Hub) *AuthHandler {
 	return &AuthHandler{
-		userStore: userStore,
+		userStore:    userStore,
+		sessionStore: sessionStore,
+		chatStore:    chatStore,
+		loginStore:   loginStore,
+		tokenStore:   tokenStore,
+		mailer:       m,
+		wsHub:        hub,
 	}
 }
 
@@ -42,12 +59,13 @@ func (h *AuthHandler) Register(c *gin.Context) {
 	}
 
 	user := &models.User{
-		ID:             uuid.New(),
-		Username:       req.Username,
-		Email:          req.Email,
-		HashedPassword: hashedPassword,
-		CreatedAt:      time.Now(),
-		UpdatedAt:      time.Now(),
+		ID:              uuid.New(),
+		Username:        req.Username,
+		Email:           req.Email,
+		HashedPassword:  hashedPassword,
+		CreatedAt:       time.Now(),
+		UpdatedAt:       time.Now(),
+		EmailVisibility: models.VisibilityNobody,
 	}
 
 	err = h.userStore.CreateUser(c.Request.Context(), user)
@@ -65,17 +83,31 @@ func (h *AuthHandler) Register(c *gin.Context) {
 		return
 	}
 
-	token, err := utils.GenerateJWT(user.ID)
+	if err := h.sendVerificationEmail(c.Request.Context(), user); err != nil {
+		log.Printf("Register: Failed to issue verification token for user %s: %v", user.ID, err)
+	}
+	if config.Cfg.RequireEmailVerification {
+		c.JSON(http.StatusCreated, gin.H{
+			"message":                   "User registered successfully; check your email to verify your address before logging in",
+			"emailVerificationRequired": true,
+			"user":                      user.ToSelfUser(),
+		})
+		return
+	}
+
+	tokens, err := h.startSession(c, user.ID)
 	if err != nil {
-		log.Printf("Register: Failed to generate JWT for user %s: %v", user.ID, err)
+		log.Printf("Register: Failed to start session for user %s: %v", user.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Registration successful, but failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusCreated, gin.H{
-		"message": "User registered successfully",
-		"token":   token,
-		"user":    user.ToPublicUser(),
+		"message":      "User registered successfully",
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+		"user":         user.ToSelfUser(),
 	})
 }
 
@@ -88,9 +120,25 @@ func (h *AuthHandler) Login(c *gin.Context) {
 		return
 	}
 
-	user, err := h.userStore.GetUserByEmail(c.Request.Context(), req.Email)
+	ctx := c.Request.Context()
+	throttles, err := h.loginStore.GetLoginThrottles(ctx, loginThrottleKeys(c, req.Email))
+	if err != nil {
+		log.Printf("Login: Failed to check login throttles for %s: %v", req.Email, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login failed"})
+		return
+	}
+	if wait := loginBlockedFor(throttles, time.Now()); wait > 0 {
+		h.recordFailedLogin(ctx, c, req.Email, nil, models.FailedLoginLocked)
+		retryAfter := int(math.Ceil(wait.Seconds()))
+		c.Header("Retry-After", strconv.Itoa(retryAfter))
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many failed login attempts, please try again later", "retryAfter": retryAfter})
+		return
+	}
+
+	user, err := h.userStore.GetUserByEmail(ctx, req.Email)
 	if err != nil {
 		if errors.Is(err, store.ErrUserNotFound) {
+			h.recordFailedLogin(ctx, c, req.Email, nil, models.FailedLoginUnknownEmail)
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 			return
 		}
@@ -100,21 +148,33 @@ func (h *AuthHandler) Login(c *gin.Context) {
 	}
 
 	if !utils.CheckPasswordHash(req.Password, user.HashedPassword) {
+		h.recordFailedLogin(ctx, c, req.Email, &user.ID, models.FailedLoginWrongPassword)
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid email or password"})
 		return
 	}
 
-	token, err := utils.GenerateJWT(user.ID)
+	if _, err := h.loginStore.ClearLoginThrottles(ctx, []string{AccountThrottleKey(req.Email)}); err != nil {
+		log.Printf("Login: Failed to reset login throttle for user %s: %v", user.ID, err)
+	}
+
+	if config.Cfg.RequireEmailVerification && !user.EmailVerified() {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Email address has not been verified", "emailVerificationRequired": true})
+		return
+	}
+
+	tokens, err := h.startSession(c, user.ID)
 	if err != nil {
-		log.Printf("Login: Failed to generate JWT for user %s: %v", user.ID, err)
+		log.Printf("Login: Failed to start session for user %s: %v", user.ID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Login successful, but failed to generate token"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message": "Login successful",
-		"token":   token,
-		"user":    user.ToPublicUser(),
+		"message":      "Login successful",
+		"token":        tokens.AccessToken,
+		"refreshToken": tokens.RefreshToken,
+		"expiresIn":    tokens.ExpiresIn,
+		"user":         user.ToSelfUser(),
 	})
 }
 
@@ -138,5 +198,5 @@ func (h *AuthHandler) GetMe(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user.ToPublicUser())
+	c.JSON(http.StatusOK, user.ToSelfUser())
 }
@@ -0,0 +1,111 @@
+package auth
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Throttle key prefixes for the two things failed logins are counted against.
+const (
+	accountThrottlePrefix = "account:"
+	ipThrottlePrefix      = "ip:"
+)
+
+// maxDelayDoublings caps the exponent of the progressive delay; the lockout duration
+// caps the delay itself long before that.
+const maxDelayDoublings = 20
+
+// AccountThrottleKey returns the throttle key counting failed logins for an email,
+// whether or not it belongs to an account.
+func AccountThrottleKey(email string) string {
+	return accountThrottlePrefix + strings.ToLower(strings.TrimSpace(email))
+}
+
+// IPThrottleKey returns the throttle key counting failed logins from a client IP.
+func IPThrottleKey(ip string) string {
+	return ipThrottlePrefix + ip
+}
+
+// loginThrottleKeys returns the keys a login attempt for email from the request's IP is charged to.
+func loginThrottleKeys(c *gin.Context, email string) []string {
+	return []string{AccountThrottleKey(email), IPThrottleKey(c.ClientIP())}
+}
+
+// loginBlockedFor returns how much longer the throttles block login attempts; zero
+// means an attempt may go ahead.
+func loginBlockedFor(throttles []*models.LoginThrottle, now time.Time) time.Duration {
+	var wait time.Duration
+	for _, t := range throttles {
+		if until := t.LastFailureAt.Add(throttleBlock(t)).Sub(now); until > wait {
+			wait = until
+		}
+	}
+	return wait
+}
+
+// throttleBlock returns how long after its last failure a throttle blocks further attempts.
+func throttleBlock(t *models.LoginThrottle) time.Duration {
+	cfg := config.Cfg
+	if maxFailures := throttleMaxFailures(t); maxFailures > 0 && t.Failures >= maxFailures {
+		return cfg.LoginLockoutDuration
+	}
+
+	// Progressive delays only apply to accounts; IPs may be shared by many users.
+	if !strings.HasPrefix(t.Key, accountThrottlePrefix) || cfg.LoginDelayAfterFailures <= 0 || t.Failures < cfg.LoginDelayAfterFailures {
+		return 0
+	}
+	doublings := t.Failures - cfg.LoginDelayAfterFailures
+	if doublings > maxDelayDoublings {
+		doublings = maxDelayDoublings
+	}
+	delay := cfg.LoginBaseDelay << doublings
+	if cfg.LoginLockoutDuration > 0 && delay > cfg.LoginLockoutDuration {
+		delay = cfg.LoginLockoutDuration
+	}
+	return delay
+}
+
+// throttleMaxFailures returns the failure count that locks a throttle's key.
+func throttleMaxFailures(t *models.LoginThrottle) int {
+	if strings.HasPrefix(t.Key, ipThrottlePrefix) {
+		return config.Cfg.LoginMaxIPFailures
+	}
+	return config.Cfg.LoginMaxFailures
+}
+
+// recordFailedLogin audits a rejected login and, unless it was rejected by a lockout,
+// counts it against the account and client IP.
+func (h *AuthHandler) recordFailedLogin(ctx context.Context, c *gin.Context, email string, userID *uuid.UUID, reason string) {
+	attempt := &models.FailedLogin{
+		ID:          uuid.New(),
+		Email:       email,
+		UserID:      userID,
+		IPAddress:   c.ClientIP(),
+		UserAgent:   c.Request.UserAgent(),
+		Reason:      reason,
+		AttemptedAt: time.Now(),
+	}
+	var keys []string
+	if reason != models.FailedLoginLocked {
+		keys = loginThrottleKeys(c, email)
+	}
+
+	throttles, err := h.loginStore.RecordFailedLogin(ctx, attempt, keys, config.Cfg.LoginFailureWindow)
+	if err != nil {
+		log.Printf("Login: Failed to record failed login for %s: %v", email, err)
+		return
+	}
+	for _, t := range throttles {
+		if t.Failures == throttleMaxFailures(t) {
+			log.Printf("Login: %s locked for %v after %d failed attempts", t.Key, config.Cfg.LoginLockoutDuration, t.Failures)
+		}
+	}
+}
@@ -0,0 +1,203 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// lockoutConfig locks accounts at 5 failures and IPs at 20 for 15 minutes, and delays
+// account attempts from the third failure on, starting at one second.
+func lockoutConfig() *config.AppConfig {
+	return &config.AppConfig{
+		JWTSecret:               "test-secret",
+		AccessTokenMaxAge:       15 * time.Minute,
+		RefreshTokenMaxAge:      24 * time.Hour,
+		LoginMaxFailures:        5,
+		LoginMaxIPFailures:      20,
+		LoginFailureWindow:      15 * time.Minute,
+		LoginLockoutDuration:    15 * time.Minute,
+		LoginDelayAfterFailures: 3,
+		LoginBaseDelay:          time.Second,
+	}
+}
+
+func TestThrottleBlock(t *testing.T) {
+	account, ip := AccountThrottleKey("someone@example.com"), IPThrottleKey("192.0.2.1")
+	for _, tt := range []struct {
+		name     string
+		key      string
+		failures int
+		tweak    func(cfg *config.AppConfig)
+		want     time.Duration
+	}{
+		{name: "account below the delay", key: account, failures: 2, want: 0},
+		{name: "account at the first delay", key: account, failures: 3, want: time.Second},
+		{name: "account delay doubles", key: account, failures: 4, want: 2 * time.Second},
+		{name: "account locked", key: account, failures: 5, want: 15 * time.Minute},
+		{name: "account past the lock", key: account, failures: 9, want: 15 * time.Minute},
+		{name: "IPs get no progressive delay", key: ip, failures: 19, want: 0},
+		{name: "IP locked", key: ip, failures: 20, want: 15 * time.Minute},
+		{
+			name: "delay capped by the lockout duration", key: account, failures: 60,
+			tweak: func(cfg *config.AppConfig) { cfg.LoginMaxFailures = 0 },
+			want:  15 * time.Minute,
+		},
+		{
+			name: "delays disabled", key: account, failures: 4,
+			tweak: func(cfg *config.AppConfig) { cfg.LoginDelayAfterFailures = 0 },
+			want:  0,
+		},
+		{
+			name: "lockout disabled", key: account, failures: 5,
+			tweak: func(cfg *config.AppConfig) { cfg.LoginMaxFailures, cfg.LoginDelayAfterFailures = 0, 0 },
+			want:  0,
+		},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			config.Cfg = lockoutConfig()
+			if tt.tweak != nil {
+				tt.tweak(config.Cfg)
+			}
+			if got := throttleBlock(&models.LoginThrottle{Key: tt.key, Failures: tt.failures}); got != tt.want {
+				t.Errorf("throttleBlock(%s at %d failures) = %v, want %v", tt.key, tt.failures, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoginBlockedFor(t *testing.T) {
+	config.Cfg = lockoutConfig()
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	throttle := func(key string, failures int, ago time.Duration) *models.LoginThrottle {
+		return &models.LoginThrottle{Key: key, Failures: failures, LastFailureAt: now.Add(-ago)}
+	}
+	account, ip := AccountThrottleKey("someone@example.com"), IPThrottleKey("192.0.2.1")
+	for _, tt := range []struct {
+		name      string
+		throttles []*models.LoginThrottle
+		want      time.Duration
+	}{
+		{"no failures", nil, 0},
+		{"delay running", []*models.LoginThrottle{throttle(account, 4, 500*time.Millisecond)}, 1500 * time.Millisecond},
+		{"delay over", []*models.LoginThrottle{throttle(account, 4, 2*time.Second)}, 0},
+		{"lock running", []*models.LoginThrottle{throttle(account, 5, 10*time.Minute)}, 5 * time.Minute},
+		{"lock over", []*models.LoginThrottle{throttle(account, 5, 15*time.Minute)}, 0},
+		{
+			"longest block wins",
+			[]*models.LoginThrottle{throttle(account, 3, 0), throttle(ip, 20, 14*time.Minute)},
+			time.Minute,
+		},
+	} {
+		if got := loginBlockedFor(tt.throttles, now); got != tt.want {
+			t.Errorf("%s: loginBlockedFor = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+// loginFixture serves Login on in-memory stores for one user.
+type loginFixture struct {
+	router *gin.Engine
+	logins store.LoginAttemptStore
+	user   *models.User
+}
+
+const testPassword = "correct horse"
+
+func newLoginFixture(t *testing.T) *loginFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	db := store.NewMemoryDB()
+	hash, err := utils.HashPassword(testPassword)
+	if err != nil {
+		t.Fatalf("HashPassword: %v", err)
+	}
+	now := time.Now()
+	user := &models.User{ID: uuid.New(), Username: "someone", Email: "someone@example.com", HashedPassword: hash, CreatedAt: now, UpdatedAt: now}
+	users := store.NewMemoryUserStore(db)
+	if err := users.CreateUser(context.Background(), user); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	logins := store.NewMemoryLoginAttemptStore(db)
+	h := NewAuthHandler(users, store.NewMemorySessionStore(db), store.NewMemoryChatStore(db), logins, store.NewMemoryActionTokenStore(db), nil, nil)
+	router := gin.New()
+	router.POST("/login", h.Login)
+	return &loginFixture{router: router, logins: logins, user: user}
+}
+
+func (f *loginFixture) login(t *testing.T, password string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(models.LoginUserRequest{Email: f.user.Email, Password: password})
+	req := httptest.NewRequest(http.MethodPost, "/login", bytes.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.RemoteAddr = "192.0.2.1:4321"
+	w := httptest.NewRecorder()
+	f.router.ServeHTTP(w, req)
+	return w
+}
+
+func (f *loginFixture) failures(t *testing.T) (account, ip int) {
+	t.Helper()
+	throttles, err := f.logins.GetLoginThrottles(context.Background(), []string{AccountThrottleKey(f.user.Email), IPThrottleKey("192.0.2.1")})
+	if err != nil {
+		t.Fatalf("GetLoginThrottles: %v", err)
+	}
+	for _, th := range throttles {
+		if th.Key == AccountThrottleKey(f.user.Email) {
+			account = th.Failures
+		} else {
+			ip = th.Failures
+		}
+	}
+	return account, ip
+}
+
+func TestLoginThrottling(t *testing.T) {
+	config.Cfg = lockoutConfig()
+	config.Cfg.LoginMaxFailures, config.Cfg.LoginDelayAfterFailures = 3, 0
+	f := newLoginFixture(t)
+
+	for i := 0; i < 2; i++ {
+		if w := f.login(t, "wrong password"); w.Code != http.StatusUnauthorized {
+			t.Fatalf("wrong password #%d: status %d, want 401", i+1, w.Code)
+		}
+	}
+	if account, ip := f.failures(t); account != 2 || ip != 2 {
+		t.Fatalf("after two failures: account %d, IP %d; want 2 and 2", account, ip)
+	}
+	// A successful login clears the account's count but not the IP's.
+	if w := f.login(t, testPassword); w.Code != http.StatusOK {
+		t.Fatalf("correct password: status %d, want 200: %s", w.Code, w.Body)
+	}
+	if account, ip := f.failures(t); account != 0 || ip != 2 {
+		t.Fatalf("after a successful login: account %d, IP %d; want 0 and 2", account, ip)
+	}
+
+	for i := 0; i < 3; i++ {
+		f.login(t, "wrong password")
+	}
+	w := f.login(t, testPassword)
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("locked account with the correct password: status %d, want 429", w.Code)
+	}
+	if got, want := w.Header().Get("Retry-After"), strconv.Itoa(int((15 * time.Minute).Seconds())); got != want {
+		t.Errorf("Retry-After = %q, want %q", got, want)
+	}
+	// Attempts turned away by the lock are not counted again.
+	if account, ip := f.failures(t); account != 3 || ip != 5 {
+		t.Errorf("after the lock: account %d, IP %d; want 3 and 5", account, ip)
+	}
+}
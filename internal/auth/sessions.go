@@ -0,0 +1,162 @@
+package auth
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// tokenPair is the access/refresh token pair handed to clients.
+type tokenPair struct {
+	AccessToken  string
+	RefreshToken string
+	ExpiresIn    int
+}
+
+// startSession records a new session for the request's device and issues its first token pair.
+func (h *AuthHandler) startSession(c *gin.Context, userID uuid.UUID) (*tokenPair, error) {
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	session := &models.Session{
+		ID:        uuid.New(),
+		UserID:    userID,
+		UserAgent: c.Request.UserAgent(),
+		IPAddress: c.ClientIP(),
+		CreatedAt: now,
+	}
+	token := &models.RefreshToken{
+		ID:        uuid.New(),
+		SessionID: session.ID,
+		TokenHash: refreshHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(config.Cfg.RefreshTokenMaxAge),
+	}
+	if err := h.sessionStore.CreateSession(c.Request.Context(), session, token); err != nil {
+		return nil, err
+	}
+
+	accessToken, err := utils.GenerateJWT(userID, session.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &tokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(config.Cfg.AccessTokenMaxAge.Seconds()),
+	}, nil
+}
+
+// Refresh exchanges a refresh token for a new access/refresh pair. Each refresh token
+// is single-use; presenting one twice revokes the session it belongs to.
+func (h *AuthHandler) Refresh(c *gin.Context) {
+	var req models.RefreshTokenRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	refreshToken, refreshHash, err := utils.GenerateRefreshToken()
+	if err != nil {
+		log.Printf("Refresh: Failed to generate refresh token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+	now := time.Now()
+	next := &models.RefreshToken{
+		ID:        uuid.New(),
+		TokenHash: refreshHash,
+		CreatedAt: now,
+		ExpiresAt: now.Add(config.Cfg.RefreshTokenMaxAge),
+	}
+
+	session, err := h.sessionStore.RotateRefreshToken(c.Request.Context(), utils.HashRefreshToken(req.RefreshToken), next)
+	if err != nil {
+		switch {
+		case errors.Is(err, store.ErrRefreshTokenReused):
+			log.Printf("Refresh: Refresh token reuse detected for session %s (user %s); session revoked", session.ID, session.UserID)
+			if h.wsHub != nil {
+				h.wsHub.DisconnectSession(session.ID)
+			}
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Refresh token has already been used; session revoked"})
+		case errors.Is(err, store.ErrRefreshTokenNotFound),
+			errors.Is(err, store.ErrRefreshTokenExpired),
+			errors.Is(err, store.ErrSessionRevoked):
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired refresh token"})
+		default:
+			log.Printf("Refresh: Failed to rotate refresh token: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		}
+		return
+	}
+
+	accessToken, err := utils.GenerateJWT(session.UserID, session.ID)
+	if err != nil {
+		log.Printf("Refresh: Failed to generate JWT for user %s: %v", session.UserID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to refresh session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"token":        accessToken,
+		"refreshToken": refreshToken,
+		"expiresIn":    int(config.Cfg.AccessTokenMaxAge.Seconds()),
+	})
+}
+
+// Logout revokes the caller's current session and closes its WebSocket connections.
+func (h *AuthHandler) Logout(c *gin.Context) {
+	sessionIDString, _ := c.Get("sessionID")
+	sessionID, err := uuid.Parse(sessionIDString.(string))
+	if err != nil {
+		log.Printf("Logout: Invalid sessionID from token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user session"})
+		return
+	}
+
+	if err := h.sessionStore.RevokeSession(c.Request.Context(), sessionID); err != nil && !errors.Is(err, store.ErrSessionNotFound) {
+		log.Printf("Logout: Failed to revoke session %s: %v", sessionID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if h.wsHub != nil {
+		h.wsHub.DisconnectSession(sessionID)
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// LogoutAll revokes every session of the caller, logging out all of their devices.
+func (h *AuthHandler) LogoutAll(c *gin.Context) {
+	userIDString, _ := c.Get("userID")
+	userID, err := uuid.Parse(userIDString.(string))
+	if err != nil {
+		log.Printf("LogoutAll: Invalid userID from token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user session"})
+		return
+	}
+
+	revoked, err := h.sessionStore.RevokeUserSessions(c.Request.Context(), userID, nil)
+	if err != nil {
+		log.Printf("LogoutAll: Failed to revoke sessions of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to log out"})
+		return
+	}
+	if h.wsHub != nil {
+		h.wsHub.DisconnectUser(userID)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"revokedSessions": len(revoked)})
+}
@@ -0,0 +1,21 @@
+// Package blob stores the contents of uploaded files.
+package blob
+
+import (
+	"context"
+	"errors"
+	"io"
+)
+
+// ErrNotFound is returned when no blob is stored under a key.
+var ErrNotFound = errors.New("blob not found")
+
+// BlobStore keeps opaque file contents under slash-separated keys. Local writes them to a
+// directory; S3 talks to any S3-compatible object store.
+type BlobStore interface {
+	// Put stores size bytes read from r under key, replacing any previous blob.
+	Put(ctx context.Context, key string, r io.Reader, size int64, contentType string) error
+	// Open returns the blob stored under key. Callers must close it.
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	Delete(ctx context.Context, key string) error
+}
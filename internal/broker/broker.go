@@ -0,0 +1,26 @@
+// Package broker carries realtime traffic between server replicas so that a WebSocket
+// hub can reach clients connected to another instance.
+package broker
+
+import (
+	"context"
+	"errors"
+)
+
+// ErrMessageTooLarge is returned by Publish when a message exceeds the backend's limit.
+var ErrMessageTooLarge = errors.New("broker message exceeds the backend's size limit")
+
+// Handler receives every message published to the broker, including the caller's own.
+type Handler func(msg []byte)
+
+// Broker is a fan-out pub/sub channel shared by all replicas. Messages are opaque bytes;
+// ordering is only guaranteed between messages from the same publisher.
+type Broker interface {
+	Publish(ctx context.Context, msg []byte) error
+	// Subscribe registers a handler. Handlers run on the broker's delivery goroutine
+	// and must not block for long.
+	Subscribe(handler Handler)
+	// MaxMessageSize is the largest message Publish accepts, or 0 for no limit.
+	MaxMessageSize() int
+	Close() error
+}
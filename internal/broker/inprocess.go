@@ -0,0 +1,43 @@
+package broker
+
+import (
+	"context"
+	"sync"
+)
+
+// InProcess is a Broker for a single server process: Publish hands the message straight
+// to every subscriber.
+type InProcess struct {
+	mu       sync.RWMutex
+	handlers []Handler
+}
+
+// NewInProcess returns an in-process Broker.
+func NewInProcess() *InProcess {
+	return &InProcess{}
+}
+
+func (b *InProcess) Publish(ctx context.Context, msg []byte) error {
+	b.mu.RLock()
+	handlers := b.handlers
+	b.mu.RUnlock()
+
+	for _, handler := range handlers {
+		handler(msg)
+	}
+	return nil
+}
+
+func (b *InProcess) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *InProcess) MaxMessageSize() int {
+	return 0
+}
+
+func (b *InProcess) Close() error {
+	return nil
+}
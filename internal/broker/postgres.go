@@ -0,0 +1,140 @@
+package broker
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	// postgresChannel is the LISTEN/NOTIFY channel shared by all replicas.
+	postgresChannel = "blinkchat_hub"
+	// postgresMaxPayload stays under NOTIFY's 8000 byte payload limit.
+	postgresMaxPayload = 7900
+
+	postgresRetryMin = 500 * time.Millisecond
+	postgresRetryMax = 30 * time.Second
+)
+
+// Postgres is a Broker built on LISTEN/NOTIFY. The listener runs on a connection taken
+// out of the pool for the lifetime of the broker; publishing uses any pooled connection.
+type Postgres struct {
+	db *pgxpool.Pool
+
+	mu       sync.RWMutex
+	handlers []Handler
+
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// NewPostgres starts listening on the shared channel and returns the broker. The first
+// LISTEN must succeed; later connection failures are retried with backoff.
+func NewPostgres(ctx context.Context, db *pgxpool.Pool) (*Postgres, error) {
+	conn, err := listenConn(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	listenCtx, cancel := context.WithCancel(context.Background())
+	b := &Postgres{db: db, cancel: cancel, done: make(chan struct{})}
+	go b.listen(listenCtx, conn)
+	return b, nil
+}
+
+func (b *Postgres) Publish(ctx context.Context, msg []byte) error {
+	if len(msg) > postgresMaxPayload {
+		return ErrMessageTooLarge
+	}
+	if _, err := b.db.Exec(ctx, "SELECT pg_notify($1, $2)", postgresChannel, string(msg)); err != nil {
+		return fmt.Errorf("failed to publish to %s: %w", postgresChannel, err)
+	}
+	return nil
+}
+
+func (b *Postgres) Subscribe(handler Handler) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handler)
+}
+
+func (b *Postgres) MaxMessageSize() int {
+	return postgresMaxPayload
+}
+
+// Close stops listening and releases the listener connection.
+func (b *Postgres) Close() error {
+	b.cancel()
+	<-b.done
+	return nil
+}
+
+// listen delivers notifications until ctx is cancelled, re-establishing the LISTEN
+// connection whenever it breaks. Messages published while disconnected are lost.
+func (b *Postgres) listen(ctx context.Context, conn *pgx.Conn) {
+	defer close(b.done)
+	retry := postgresRetryMin
+
+	for {
+		if conn != nil {
+			err := b.receive(ctx, conn)
+			_ = conn.Close(context.Background())
+			conn = nil
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("Broker (Postgres): Listener connection lost: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retry):
+		}
+
+		next, err := listenConn(ctx, b.db)
+		if err != nil {
+			log.Printf("Broker (Postgres): Failed to re-listen on %s: %v", postgresChannel, err)
+			retry = min(retry*2, postgresRetryMax)
+			continue
+		}
+		log.Printf("Broker (Postgres): Listening on %s again.", postgresChannel)
+		conn, retry = next, postgresRetryMin
+	}
+}
+
+// listenConn takes a connection out of the pool and starts listening on it.
+func listenConn(ctx context.Context, db *pgxpool.Pool) (*pgx.Conn, error) {
+	pooled, err := db.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to acquire broker connection: %w", err)
+	}
+	conn := pooled.Hijack()
+	if _, err := conn.Exec(ctx, "LISTEN "+postgresChannel); err != nil {
+		_ = conn.Close(context.Background())
+		return nil, fmt.Errorf("failed to listen on %s: %w", postgresChannel, err)
+	}
+	return conn, nil
+}
+
+func (b *Postgres) receive(ctx context.Context, conn *pgx.Conn) error {
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+		msg := []byte(notification.Payload)
+
+		b.mu.RLock()
+		handlers := b.handlers
+		b.mu.RUnlock()
+		for _, handler := range handlers {
+			handler(msg)
+		}
+	}
+}
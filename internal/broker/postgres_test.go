@@ -0,0 +1,90 @@
+package broker
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDatabaseEnv names the DSN of a Postgres database to publish through. The tests in
+// this file are skipped when it is unset.
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// deliveryTimeout bounds how long a notification may take to reach a listener.
+const deliveryTimeout = 5 * time.Second
+
+// newTestPostgres starts a broker on its own pool, so that brokers from separate calls
+// share nothing but the database.
+func newTestPostgres(t *testing.T) *Postgres {
+	t.Helper()
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	b, err := NewPostgres(ctx, pool)
+	if err != nil {
+		t.Fatalf("NewPostgres: %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+	return b
+}
+
+// subscribe returns a channel that receives every message the broker delivers.
+func subscribe(b Broker) <-chan []byte {
+	received := make(chan []byte, 16)
+	b.Subscribe(func(msg []byte) { received <- msg })
+	return received
+}
+
+func expectMessage(t *testing.T, received <-chan []byte, want string) {
+	t.Helper()
+	select {
+	case msg := <-received:
+		if string(msg) != want {
+			t.Errorf("received %d bytes %.40q, want %d bytes %.40q", len(msg), msg, len(want), want)
+		}
+	case <-time.After(deliveryTimeout):
+		t.Fatalf("no message was delivered within %s", deliveryTimeout)
+	}
+}
+
+func TestPostgresDeliversAcrossConnections(t *testing.T) {
+	publisher, listener := newTestPostgres(t), newTestPostgres(t)
+	own, other := subscribe(publisher), subscribe(listener)
+
+	if err := publisher.Publish(context.Background(), []byte(`{"kind":"heartbeat"}`)); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	expectMessage(t, other, `{"kind":"heartbeat"}`)
+	expectMessage(t, own, `{"kind":"heartbeat"}`)
+}
+
+func TestPostgresRejectsOversizedMessages(t *testing.T) {
+	publisher, listener := newTestPostgres(t), newTestPostgres(t)
+	received := subscribe(listener)
+	ctx := context.Background()
+
+	if got := publisher.MaxMessageSize(); got != postgresMaxPayload {
+		t.Fatalf("MaxMessageSize = %d, want %d", got, postgresMaxPayload)
+	}
+	if err := publisher.Publish(ctx, []byte(strings.Repeat("x", postgresMaxPayload+1))); !errors.Is(err, ErrMessageTooLarge) {
+		t.Fatalf("Publish of %d bytes: got %v, want ErrMessageTooLarge", postgresMaxPayload+1, err)
+	}
+
+	largest := strings.Repeat("x", postgresMaxPayload)
+	if err := publisher.Publish(ctx, []byte(largest)); err != nil {
+		t.Fatalf("Publish of %d bytes: %v", postgresMaxPayload, err)
+	}
+	expectMessage(t, received, largest)
+}
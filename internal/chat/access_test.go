@@ -0,0 +1,204 @@
+package chat
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/broker"
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/events"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/ratelimit"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// restFixture serves the chat routes on the memory store. Requests are authenticated as
+// whichever user the test names.
+type restFixture struct {
+	router *gin.Engine
+	users  store.UserStore
+	chats  store.ChatStore
+	msgs   store.MessageStore
+	blocks store.BlockStore
+}
+
+func newRestFixture(t *testing.T) *restFixture {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	config.Cfg = &config.AppConfig{JWTSecret: "test-secret", AttachmentURLMaxAge: 15 * time.Minute}
+	db := store.NewMemoryDB()
+	f := &restFixture{
+		users:  store.NewMemoryUserStore(db),
+		chats:  store.NewMemoryChatStore(db),
+		msgs:   store.NewMemoryMessageStore(db),
+		blocks: store.NewMemoryBlockStore(db),
+	}
+	as := store.NewMemoryAttachmentStore(db)
+	guard := access.NewGuard(f.chats, f.msgs, f.blocks, as)
+	eventLog := events.NewLog(store.NewMemoryEventStore(db), guard)
+	hub := websocket.NewHub(f.users, f.chats, f.msgs, guard, eventLog, broker.NewInProcess(), ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil))
+	h := NewRestHandler(f.chats, f.msgs, f.users, as, guard, eventLog, hub)
+
+	r := gin.New()
+	r.Use(func(c *gin.Context) {
+		c.Set("userID", c.GetHeader("X-Test-User"))
+	})
+	r.POST("/messages", h.PostMessage)
+	r.GET("/messages", h.GetMessagesByChatID)
+	r.PATCH("/messages/:id", h.EditMessage)
+	r.DELETE("/messages/:id", h.DeleteMessage)
+	r.GET("/messages/:id/history", h.GetMessageHistory)
+	r.GET("/messages/:id/receipts", h.GetMessageReceipts)
+	r.POST("/messages/:id/reactions", h.AddReaction)
+	r.POST("/chats", h.CreateGroupChat)
+	r.GET("/chats/:id/members", h.GetChatMembers)
+	r.POST("/chats/:id/members", h.AddChatMembers)
+	r.PATCH("/chats/:id/members/:userId", h.UpdateChatMember)
+	r.POST("/chats/:id/leave", h.LeaveChat)
+	r.POST("/chats/:id/read", h.MarkChatRead)
+	r.POST("/chats/:id/mute", h.MuteChat)
+	r.POST("/sync", h.Sync)
+	f.router = r
+	return f
+}
+
+func (f *restFixture) createUser(t *testing.T) uuid.UUID {
+	t.Helper()
+	u := &models.User{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+	u.Username = "user_" + u.ID.String()[:8]
+	u.Email = u.Username + "@example.com"
+	if err := f.users.CreateUser(context.Background(), u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return u.ID
+}
+
+func (f *restFixture) createMessage(t *testing.T, chatID, senderID uuid.UUID) *models.Message {
+	t.Helper()
+	msg := &models.Message{ID: uuid.New(), ChatID: chatID, SenderID: senderID, Content: "hi", Timestamp: time.Now(), Status: models.StatusSent}
+	if err := f.msgs.CreateMessage(context.Background(), msg); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	return msg
+}
+
+// do sends a request as userID and returns the status and decoded JSON body.
+func (f *restFixture) do(t *testing.T, userID uuid.UUID, method, path string, body any) (int, map[string]any) {
+	t.Helper()
+	var raw []byte
+	if body != nil {
+		var err error
+		if raw, err = json.Marshal(body); err != nil {
+			t.Fatalf("encode body: %v", err)
+		}
+	}
+	req := httptest.NewRequest(method, path, bytes.NewReader(raw))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Test-User", userID.String())
+	rec := httptest.NewRecorder()
+	f.router.ServeHTTP(rec, req)
+
+	var decoded map[string]any
+	_ = json.Unmarshal(rec.Body.Bytes(), &decoded)
+	return rec.Code, decoded
+}
+
+func TestRestRejectsNonParticipants(t *testing.T) {
+	f := newRestFixture(t)
+	ctx := context.Background()
+	alice, bob, mallory := f.createUser(t), f.createUser(t), f.createUser(t)
+	chat, err := f.chats.CreateChat(ctx, []uuid.UUID{alice, bob})
+	if err != nil {
+		t.Fatalf("CreateChat: %v", err)
+	}
+	msg := f.createMessage(t, chat.ID, alice)
+
+	const notParticipant = "You are not a participant of this chat"
+	tests := []struct {
+		name   string
+		method string
+		path   string
+		body   any
+	}{
+		{"read history", http.MethodGet, "/messages?chatId=" + chat.ID.String(), nil},
+		{"post message", http.MethodPost, "/messages", map[string]any{"chatId": chat.ID, "content": "let me in"}},
+		{"edit history", http.MethodGet, fmt.Sprintf("/messages/%s/history", msg.ID), nil},
+		{"receipts", http.MethodGet, fmt.Sprintf("/messages/%s/receipts", msg.ID), nil},
+		{"react", http.MethodPost, fmt.Sprintf("/messages/%s/reactions", msg.ID), map[string]any{"emoji": "👍"}},
+		{"members", http.MethodGet, fmt.Sprintf("/chats/%s/members", chat.ID), nil},
+		{"mark read", http.MethodPost, fmt.Sprintf("/chats/%s/read", chat.ID), nil},
+		{"mute", http.MethodPost, fmt.Sprintf("/chats/%s/mute", chat.ID), nil},
+		{"sync", http.MethodPost, "/sync", map[string]any{"chats": []map[string]any{{"chatId": chat.ID}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status, body := f.do(t, mallory, tt.method, tt.path, tt.body)
+			if status != http.StatusForbidden || body["error"] != notParticipant {
+				t.Errorf("got %d %v, want 403 with error %q", status, body, notParticipant)
+			}
+		})
+	}
+
+	t.Run("member reads history", func(t *testing.T) {
+		if status, body := f.do(t, bob, http.MethodGet, "/messages?chatId="+chat.ID.String(), nil); status != http.StatusOK {
+			t.Errorf("got %d %v, want 200", status, body)
+		}
+	})
+}
+
+func TestRestRequiresAdminToAddMembers(t *testing.T) {
+	f := newRestFixture(t)
+	admin, member, newcomer := f.createUser(t), f.createUser(t), f.createUser(t)
+	group, err := f.chats.CreateGroupChat(context.Background(), "team", admin, []uuid.UUID{member})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	status, body := f.do(t, member, http.MethodPost, fmt.Sprintf("/chats/%s/members", group.ID), map[string]any{"userIds": []uuid.UUID{newcomer}})
+	const want = "Only chat admins can perform this action"
+	if status != http.StatusForbidden || body["error"] != want {
+		t.Errorf("got %d %v, want 403 with error %q", status, body, want)
+	}
+}
+
+func TestRestRejectsBlockedInvitees(t *testing.T) {
+	f := newRestFixture(t)
+	ctx := context.Background()
+	admin, member, friend, blocker := f.createUser(t), f.createUser(t), f.createUser(t), f.createUser(t)
+	if err := f.blocks.BlockUser(ctx, blocker, admin, time.Now()); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+	group, err := f.chats.CreateGroupChat(ctx, "team", admin, []uuid.UUID{member})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	const blocked = "Unable to send messages to this user"
+	t.Run("create group", func(t *testing.T) {
+		status, body := f.do(t, admin, http.MethodPost, "/chats", map[string]any{"name": "party", "participantIds": []uuid.UUID{friend, blocker}})
+		if status != http.StatusForbidden || body["error"] != blocked {
+			t.Errorf("got %d %v, want 403 with error %q", status, body, blocked)
+		}
+	})
+	t.Run("add members", func(t *testing.T) {
+		status, body := f.do(t, admin, http.MethodPost, fmt.Sprintf("/chats/%s/members", group.ID), map[string]any{"userIds": []uuid.UUID{friend, blocker}})
+		if status != http.StatusForbidden || body["error"] != blocked {
+			t.Errorf("got %d %v, want 403 with error %q", status, body, blocked)
+		}
+		// Nobody from a rejected request joins, not even the invitees who were allowed.
+		if ok, err := f.chats.IsParticipant(ctx, group.ID, friend); err != nil || ok {
+			t.Errorf("IsParticipant of friend: got %v, %v; want false", ok, err)
+		}
+	})
+}
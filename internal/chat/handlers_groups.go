@@ -0,0 +1,486 @@
+package chat
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// CreateGroupChat creates a named group chat with the caller as admin.
+func (h *RestHandler) CreateGroupChat(c *gin.Context) {
+	var req models.CreateChatRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	creatorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	seen := map[uuid.UUID]bool{creatorID: true}
+	var memberIDs []uuid.UUID
+	for _, id := range req.ParticipantIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := h.userStore.GetUserByID(c.Request.Context(), id.String()); err != nil {
+			if errors.Is(err, store.ErrUserNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Participant not found", "details": id.String()})
+				return
+			}
+			log.Printf("CreateGroupChat: Failed to look up participant %s: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chat"})
+			return
+		}
+		if err := h.guard.RequireNotBlocked(c.Request.Context(), creatorID, id); err != nil {
+			log.Printf("CreateGroupChat: User %s may not add user %s: %v", creatorID, id, err)
+			respondAccessError(c, "CreateGroupChat", uuid.Nil, creatorID, err)
+			return
+		}
+		memberIDs = append(memberIDs, id)
+	}
+	if len(memberIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A group chat needs at least one other participant"})
+		return
+	}
+
+	newChat, err := h.chatStore.CreateGroupChat(c.Request.Context(), req.Name, creatorID, memberIDs)
+	if err != nil {
+		log.Printf("CreateGroupChat: Failed to create group for user %s: %v", creatorID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create chat"})
+		return
+	}
+
+	chat, err := h.chatStore.GetChatByID(c.Request.Context(), newChat.ID)
+	if err != nil {
+		log.Printf("CreateGroupChat: Failed to reload chat %s: %v", newChat.ID, err)
+		chat = newChat
+	}
+
+	if h.wsHub != nil {
+		for _, p := range chat.OtherParticipants {
+			if p.ID == creatorID {
+				continue
+			}
+			h.wsHub.PublishChatEvent(chat.ID, []uuid.UUID{p.ID}, websocket.MessageTypeChatMemberAdded, websocket.ChatMemberEventPayload{
+				ChatID:   chat.ID,
+				ChatName: chat.Name,
+				Member:   p,
+				Role:     models.RoleMember,
+				ActorID:  creatorID,
+			})
+		}
+	}
+
+	c.JSON(http.StatusCreated, chat)
+}
+
+// GetChatMembers lists the participants of a chat with their roles.
+func (h *RestHandler) GetChatMembers(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	chatID, ok := chatIDFromParam(c)
+	if !ok {
+		return
+	}
+	if _, ok := h.requireRole(c, chatID, userID, models.RoleMember); !ok {
+		return
+	}
+
+	members, err := h.chatStore.GetChatMembers(c.Request.Context(), chatID)
+	if err != nil {
+		log.Printf("GetChatMembers: Failed to get members of chat %s: %v", chatID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve chat members"})
+		return
+	}
+	if members == nil {
+		members = make([]*models.ChatMember, 0)
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+// AddChatMembers adds users to a group chat. Only admins may add members.
+func (h *RestHandler) AddChatMembers(c *gin.Context) {
+	var req models.AddChatMembersRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	actorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	chatID, ok := chatIDFromParam(c)
+	if !ok {
+		return
+	}
+	if _, ok := h.requireRole(c, chatID, actorID, models.RoleAdmin); !ok {
+		return
+	}
+	chat, ok := h.loadGroupChat(c, chatID)
+	if !ok {
+		return
+	}
+
+	// Check every invitee before adding anyone, so that a rejected request changes nothing.
+	var invitees []*models.User
+	seen := make(map[uuid.UUID]bool, len(req.UserIDs))
+	for _, id := range req.UserIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+		if _, err := h.chatStore.GetParticipantRole(c.Request.Context(), chatID, id); err == nil {
+			continue
+		} else if !errors.Is(err, store.ErrNotParticipant) {
+			log.Printf("AddChatMembers: Failed to check membership of %s in chat %s: %v", id, chatID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add members"})
+			return
+		}
+
+		u, err := h.userStore.GetUserByID(c.Request.Context(), id.String())
+		if err != nil {
+			if errors.Is(err, store.ErrUserNotFound) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "User not found", "details": id.String()})
+				return
+			}
+			log.Printf("AddChatMembers: Failed to look up user %s: %v", id, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add members"})
+			return
+		}
+		if err := h.guard.RequireNotBlocked(c.Request.Context(), actorID, id); err != nil {
+			log.Printf("AddChatMembers: User %s may not add user %s to chat %s: %v", actorID, id, chatID, err)
+			respondAccessError(c, "AddChatMembers", chatID, actorID, err)
+			return
+		}
+		invitees = append(invitees, u)
+	}
+
+	var added []*models.PublicUser
+	for _, u := range invitees {
+		if err := h.chatStore.AddUserToChat(c.Request.Context(), chatID, u.ID); err != nil {
+			log.Printf("AddChatMembers: Failed to add %s to chat %s: %v", u.ID, chatID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add members"})
+			return
+		}
+		added = append(added, u.ProfileFor(models.RelationshipContact))
+	}
+
+	if len(added) > 0 {
+		recipients := h.memberIDs(c, chatID)
+		for _, member := range added {
+			h.broadcastMemberEvent(recipients, websocket.MessageTypeChatMemberAdded, websocket.ChatMemberEventPayload{
+				ChatID:   chatID,
+				ChatName: chat.Name,
+				Member:   member,
+				Role:     models.RoleMember,
+				ActorID:  actorID,
+			})
+		}
+	}
+
+	h.respondWithMembers(c, chatID)
+}
+
+// RemoveChatMember removes another user from a group chat. Only admins may remove members.
+func (h *RestHandler) RemoveChatMember(c *gin.Context) {
+	actorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	chatID, ok := chatIDFromParam(c)
+	if !ok {
+		return
+	}
+	targetID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+	if targetID == actorID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Use the leave endpoint to leave a chat"})
+		return
+	}
+	if _, ok := h.requireRole(c, chatID, actorID, models.RoleAdmin); !ok {
+		return
+	}
+	chat, ok := h.loadGroupChat(c, chatID)
+	if !ok {
+		return
+	}
+
+	if _, err := h.chatStore.GetParticipantRole(c.Request.Context(), chatID, targetID); err != nil {
+		if errors.Is(err, store.ErrNotParticipant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this chat"})
+			return
+		}
+		log.Printf("RemoveChatMember: Failed to check membership of %s in chat %s: %v", targetID, chatID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	h.removeMember(c, chat, targetID, actorID)
+	if c.IsAborted() {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// UpdateChatMember promotes or demotes a group member. Only admins may change roles.
+func (h *RestHandler) UpdateChatMember(c *gin.Context) {
+	var req models.UpdateChatMemberRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	actorID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	chatID, ok := chatIDFromParam(c)
+	if !ok {
+		return
+	}
+	targetID, err := uuid.Parse(c.Param("userId"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
+	if _, ok := h.requireRole(c, chatID, actorID, models.RoleAdmin); !ok {
+		return
+	}
+	chat, ok := h.loadGroupChat(c, chatID)
+	if !ok {
+		return
+	}
+
+	currentRole, err := h.chatStore.GetParticipantRole(c.Request.Context(), chatID, targetID)
+	if err != nil {
+		if errors.Is(err, store.ErrNotParticipant) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User is not a member of this chat"})
+			return
+		}
+		log.Printf("UpdateChatMember: Failed to get role of %s in chat %s: %v", targetID, chatID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+		return
+	}
+
+	if currentRole == models.RoleAdmin && req.Role == models.RoleMember {
+		admins, err := h.countAdmins(c, chatID)
+		if err != nil {
+			log.Printf("UpdateChatMember: Failed to count admins of chat %s: %v", chatID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+			return
+		}
+		if admins <= 1 {
+			c.JSON(http.StatusConflict, gin.H{"error": "A group chat must keep at least one admin"})
+			return
+		}
+	}
+
+	if currentRole != req.Role {
+		if err := h.chatStore.SetParticipantRole(c.Request.Context(), chatID, targetID, req.Role); err != nil {
+			log.Printf("UpdateChatMember: Failed to set role of %s in chat %s: %v", targetID, chatID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update member"})
+			return
+		}
+		h.broadcastMemberEvent(h.memberIDs(c, chatID), websocket.MessageTypeChatMemberUpdated, websocket.ChatMemberEventPayload{
+			ChatID:   chatID,
+			ChatName: chat.Name,
+			Member:   h.memberProfile(c, targetID),
+			Role:     req.Role,
+			ActorID:  actorID,
+		})
+	}
+
+	h.respondWithMembers(c, chatID)
+}
+
+// LeaveChat removes the caller from a group chat, handing over admin rights if needed.
+func (h *RestHandler) LeaveChat(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	chatID, ok := chatIDFromParam(c)
+	if !ok {
+		return
+	}
+	role, ok := h.requireRole(c, chatID, userID, models.RoleMember)
+	if !ok {
+		return
+	}
+	chat, ok := h.loadGroupChat(c, chatID)
+	if !ok {
+		return
+	}
+
+	if role == models.RoleAdmin {
+		members, err := h.chatStore.GetChatMembers(c.Request.Context(), chatID)
+		if err != nil {
+			log.Printf("LeaveChat: Failed to get members of chat %s: %v", chatID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave chat"})
+			return
+		}
+		var successor *models.ChatMember
+		otherAdmins := 0
+		for _, m := range members {
+			if m.User.ID == userID {
+				continue
+			}
+			if m.Role == models.RoleAdmin {
+				otherAdmins++
+			} else if successor == nil {
+				successor = m
+			}
+		}
+		if otherAdmins == 0 && successor != nil {
+			if err := h.chatStore.SetParticipantRole(c.Request.Context(), chatID, successor.User.ID, models.RoleAdmin); err != nil {
+				log.Printf("LeaveChat: Failed to promote %s in chat %s: %v", successor.User.ID, chatID, err)
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to leave chat"})
+				return
+			}
+			log.Printf("LeaveChat: Promoted %s to admin of chat %s after last admin %s left", successor.User.ID, chatID, userID)
+			h.broadcastMemberEvent(h.memberIDs(c, chatID), websocket.MessageTypeChatMemberUpdated, websocket.ChatMemberEventPayload{
+				ChatID:   chatID,
+				ChatName: chat.Name,
+				Member:   h.memberProfile(c, successor.User.ID),
+				Role:     models.RoleAdmin,
+				ActorID:  userID,
+			})
+		}
+	}
+
+	h.removeMember(c, chat, userID, userID)
+	if c.IsAborted() {
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// removeMember deletes the membership and notifies both the remaining members and the removed user.
+func (h *RestHandler) removeMember(c *gin.Context, chat *models.Chat, targetID, actorID uuid.UUID) {
+	memberInfo := h.memberProfile(c, targetID)
+
+	if err := h.chatStore.RemoveUserFromChat(c.Request.Context(), chat.ID, targetID); err != nil {
+		log.Printf("removeMember: Failed to remove %s from chat %s: %v", targetID, chat.ID, err)
+		c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove member"})
+		return
+	}
+
+	recipients := append(h.memberIDs(c, chat.ID), targetID)
+	h.broadcastMemberEvent(recipients, websocket.MessageTypeChatMemberRemoved, websocket.ChatMemberEventPayload{
+		ChatID:   chat.ID,
+		ChatName: chat.Name,
+		Member:   memberInfo,
+		ActorID:  actorID,
+	})
+}
+
+// loadGroupChat fetches a chat and rejects non-group chats for membership management.
+func (h *RestHandler) loadGroupChat(c *gin.Context, chatID uuid.UUID) (*models.Chat, bool) {
+	chat, err := h.chatStore.GetChatByID(c.Request.Context(), chatID)
+	if err != nil {
+		if errors.Is(err, store.ErrChatNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Chat not found"})
+			return nil, false
+		}
+		log.Printf("loadGroupChat: Failed to get chat %s: %v", chatID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve chat"})
+		return nil, false
+	}
+	if !chat.IsGroup {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Membership can only be managed in group chats"})
+		return nil, false
+	}
+	return chat, true
+}
+
+// requireRole checks that userID participates in the chat with at least the given role.
+func (h *RestHandler) requireRole(c *gin.Context, chatID, userID uuid.UUID, minRole models.ChatRole) (models.ChatRole, bool) {
+	role, err := h.guard.RequireRole(c.Request.Context(), chatID, userID, minRole)
+	if err != nil {
+		respondAccessError(c, "requireRole", chatID, userID, err)
+		return "", false
+	}
+	return role, true
+}
+
+func (h *RestHandler) countAdmins(c *gin.Context, chatID uuid.UUID) (int, error) {
+	members, err := h.chatStore.GetChatMembers(c.Request.Context(), chatID)
+	if err != nil {
+		return 0, err
+	}
+	admins := 0
+	for _, m := range members {
+		if m.Role == models.RoleAdmin {
+			admins++
+		}
+	}
+	return admins, nil
+}
+
+func (h *RestHandler) memberIDs(c *gin.Context, chatID uuid.UUID) []uuid.UUID {
+	participants, err := h.chatStore.GetAllParticipantsInChat(c.Request.Context(), chatID)
+	if err != nil {
+		log.Printf("memberIDs: Failed to get participants of chat %s: %v", chatID, err)
+		return nil
+	}
+	ids := make([]uuid.UUID, 0, len(participants))
+	for _, p := range participants {
+		ids = append(ids, p.ID)
+	}
+	return ids
+}
+
+// memberProfile returns the profile member events carry for userID, or just the ID if the
+// user cannot be loaded.
+func (h *RestHandler) memberProfile(c *gin.Context, userID uuid.UUID) *models.PublicUser {
+	member, err := h.userStore.GetUserByID(c.Request.Context(), userID.String())
+	if err != nil {
+		return &models.PublicUser{ID: userID}
+	}
+	return member.ProfileFor(models.RelationshipContact)
+}
+
+func (h *RestHandler) broadcastMemberEvent(recipients []uuid.UUID, msgType string, payload websocket.ChatMemberEventPayload) {
+	if h.wsHub == nil {
+		log.Printf("broadcastMemberEvent: WebSocket Hub is nil, skipping %s broadcast.", msgType)
+		return
+	}
+	h.wsHub.PublishChatEvent(payload.ChatID, recipients, msgType, payload)
+}
+
+func (h *RestHandler) respondWithMembers(c *gin.Context, chatID uuid.UUID) {
+	members, err := h.chatStore.GetChatMembers(c.Request.Context(), chatID)
+	if err != nil {
+		log.Printf("respondWithMembers: Failed to get members of chat %s: %v", chatID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve chat members"})
+		return
+	}
+	if members == nil {
+		members = make([]*models.ChatMember, 0)
+	}
+	c.JSON(http.StatusOK, members)
+}
+
+func chatIDFromParam(c *gin.Context) (uuid.UUID, bool) {
+	chatID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid chat ID format"})
+		return uuid.Nil, false
+	}
+	return chatID, true
+}
@@ -0,0 +1,349 @@
+package chat
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
+	"blinkchat-backend/internal/websocket"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// EditMessage replaces the content of one of the caller's messages within the edit window.
+func (h *RestHandler) EditMessage(c *gin.Context) {
+	var req models.UpdateMessageRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	msg, ok := h.loadOwnMessage(c)
+	if !ok {
+		return
+	}
+
+	now := time.Now()
+	if window := config.Cfg.MessageEditWindow; window > 0 && now.Sub(msg.Timestamp) > window {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Message can no longer be edited"})
+		return
+	}
+
+	updated, err := h.messageStore.EditMessage(c.Request.Context(), msg.ID, req.Content, now)
+	if err != nil {
+		if errors.Is(err, store.ErrMessageDeleted) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Message has been deleted"})
+			return
+		}
+		log.Printf("EditMessage: Failed to edit message %s: %v", msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit message"})
+		return
+	}
+
+	if err := h.attachMessageDetails(c, msg.SenderID, []*models.Message{updated}); err != nil {
+		log.Printf("EditMessage: Failed to load details of message %s: %v", msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to edit message"})
+		return
+	}
+
+	h.redactEvents(c, "EditMessage", updated)
+	if h.wsHub != nil {
+		h.wsHub.BroadcastMessageEvent(updated, websocket.MessageTypeMessageEdited, withoutViewerReactions(updated))
+	}
+	c.JSON(http.StatusOK, updated)
+}
+
+// DeleteMessage replaces one of the caller's messages with a tombstone.
+func (h *RestHandler) DeleteMessage(c *gin.Context) {
+	msg, ok := h.loadOwnMessage(c)
+	if !ok {
+		return
+	}
+
+	deleted, err := h.messageStore.DeleteMessage(c.Request.Context(), msg.ID, time.Now())
+	if err != nil {
+		if errors.Is(err, store.ErrMessageDeleted) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Message has already been deleted"})
+			return
+		}
+		log.Printf("DeleteMessage: Failed to delete message %s: %v", msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete message"})
+		return
+	}
+
+	h.redactEvents(c, "DeleteMessage", deleted)
+	if h.wsHub != nil {
+		h.wsHub.BroadcastToChat(deleted.ChatID, websocket.MessageTypeMessageDeleted, websocket.MessageDeletedPayload{
+			MessageID: deleted.ID,
+			ChatID:    deleted.ChatID,
+			DeletedAt: models.JSONTime(*deleted.DeletedAt),
+		})
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// redactEvents keeps sync from replaying the content an edit or deletion replaced.
+func (h *RestHandler) redactEvents(c *gin.Context, op string, msg *models.Message) {
+	if err := h.eventLog.RedactMessage(c.Request.Context(), msg); err != nil {
+		log.Printf("%s: Failed to redact chat events of message %s: %v", op, msg.ID, err)
+	}
+}
+
+// GetMessageHistory lists the previous revisions of a message to members of its chat.
+func (h *RestHandler) GetMessageHistory(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	messageID, ok := messageIDFromParam(c)
+	if !ok {
+		return
+	}
+	msg, err := h.guard.RequireMessageAccess(c.Request.Context(), messageID, userID)
+	if err != nil {
+		respondAccessError(c, "GetMessageHistory", uuid.Nil, userID, err)
+		return
+	}
+
+	edits, err := h.messageStore.GetMessageEdits(c.Request.Context(), msg.ID)
+	if err != nil {
+		log.Printf("GetMessageHistory: Failed to get edit history of message %s: %v", msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve message history"})
+		return
+	}
+	c.JSON(http.StatusOK, edits)
+}
+
+// GetMessageReceipts lists, for each recipient of a message, whether it was delivered to
+// and read by them. Any member of the message's chat may look.
+func (h *RestHandler) GetMessageReceipts(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	messageID, ok := messageIDFromParam(c)
+	if !ok {
+		return
+	}
+	msg, err := h.guard.RequireMessageAccess(c.Request.Context(), messageID, userID)
+	if err != nil {
+		respondAccessError(c, "GetMessageReceipts", uuid.Nil, userID, err)
+		return
+	}
+
+	receipts, err := h.messageStore.GetMessageReceipts(c.Request.Context(), msg.ID)
+	if err != nil {
+		log.Printf("GetMessageReceipts: Failed to get receipts of message %s: %v", msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve message receipts"})
+		return
+	}
+	c.JSON(http.StatusOK, receipts)
+}
+
+// AddReaction adds the caller's emoji reaction to a message in one of their chats.
+// Adding a reaction that already exists is a no-op.
+func (h *RestHandler) AddReaction(c *gin.Context) {
+	h.updateReaction(c, "AddReaction", true)
+}
+
+// RemoveReaction removes one of the caller's emoji reactions from a message.
+func (h *RestHandler) RemoveReaction(c *gin.Context) {
+	h.updateReaction(c, "RemoveReaction", false)
+}
+
+func (h *RestHandler) updateReaction(c *gin.Context, op string, add bool) {
+	var req models.ReactionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	if !models.ValidReactionEmoji(req.Emoji) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reaction must be a single emoji"})
+		return
+	}
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	messageID, ok := messageIDFromParam(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	msg, err := h.guard.RequireMessageAccess(ctx, messageID, userID)
+	if err != nil {
+		respondAccessError(c, op, uuid.Nil, userID, err)
+		return
+	}
+
+	now := time.Now()
+	var changed bool
+	msgType := websocket.MessageTypeReactionRemoved
+	if add {
+		if msg.DeletedAt != nil {
+			c.JSON(http.StatusConflict, gin.H{"error": "Message has been deleted"})
+			return
+		}
+		if err := h.guard.RequireCanMessage(ctx, msg.ChatID, userID); err != nil {
+			respondAccessError(c, op, msg.ChatID, userID, err)
+			return
+		}
+		changed, err = h.messageStore.AddReaction(ctx, msg.ID, userID, req.Emoji, now)
+		msgType = websocket.MessageTypeReactionAdded
+	} else {
+		changed, err = h.messageStore.RemoveReaction(ctx, msg.ID, userID, req.Emoji)
+	}
+	if err != nil {
+		log.Printf("%s: Failed to update reaction of user %s to message %s: %v", op, userID, msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update reaction"})
+		return
+	}
+
+	if changed && h.wsHub != nil {
+		h.wsHub.BroadcastToChat(msg.ChatID, msgType, websocket.ReactionEventPayload{
+			MessageID: msg.ID,
+			ChatID:    msg.ChatID,
+			UserID:    userID,
+			Emoji:     req.Emoji,
+			Timestamp: models.JSONTime(now),
+		})
+	}
+
+	counts, err := h.messageStore.GetReactionCounts(ctx, []uuid.UUID{msg.ID}, userID)
+	if err != nil {
+		log.Printf("%s: Failed to count reactions to message %s: %v", op, msg.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve reactions"})
+		return
+	}
+	reactions := counts[msg.ID]
+	if reactions == nil {
+		reactions = make([]*models.ReactionCount, 0)
+	}
+	c.JSON(http.StatusOK, gin.H{"messageId": msg.ID, "reactions": reactions})
+}
+
+// withoutViewerReactions returns a copy of msg for every member of its chat, with the
+// reactedByMe flags of the viewer its details were loaded for cleared.
+func withoutViewerReactions(msg *models.Message) *models.Message {
+	shared := *msg
+	shared.Reactions = make([]*models.ReactionCount, len(msg.Reactions))
+	for i, r := range msg.Reactions {
+		shared.Reactions[i] = &models.ReactionCount{Emoji: r.Emoji, Count: r.Count}
+	}
+	return &shared
+}
+
+// attachMessageDetails fills in the reaction counts, as seen by viewerID, reply counts
+// and attachments of each message. Deleted messages keep no attachments.
+func (h *RestHandler) attachMessageDetails(c *gin.Context, viewerID uuid.UUID, messages []*models.Message) error {
+	if len(messages) == 0 {
+		return nil
+	}
+	ids := make([]uuid.UUID, len(messages))
+	for i, m := range messages {
+		ids[i] = m.ID
+	}
+	reactions, err := h.messageStore.GetReactionCounts(c.Request.Context(), ids, viewerID)
+	if err != nil {
+		return err
+	}
+	replies, err := h.messageStore.GetReplyCounts(c.Request.Context(), ids)
+	if err != nil {
+		return err
+	}
+	attachments, err := h.attachmentStore.GetMessageAttachments(c.Request.Context(), ids)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, m := range messages {
+		m.Reactions = reactions[m.ID]
+		m.ReplyCount = replies[m.ID]
+		if m.DeletedAt == nil {
+			m.Attachments = attachments[m.ID]
+			utils.SignAttachmentURLs(m.Attachments, now)
+		}
+	}
+	return nil
+}
+
+// GetThread returns a message and a page of its direct replies, newest first. Replies carry
+// their own reply counts so that clients can open nested threads.
+func (h *RestHandler) GetThread(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	messageID, ok := messageIDFromParam(c)
+	if !ok {
+		return
+	}
+	ctx := c.Request.Context()
+	root, err := h.guard.RequireMessageAccess(ctx, messageID, userID)
+	if err != nil {
+		respondAccessError(c, "GetThread", uuid.Nil, userID, err)
+		return
+	}
+
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 100 {
+		limit = 20
+	}
+	page, _, ok := cursorPageFromQuery(c, limit)
+	if !ok {
+		return
+	}
+
+	replies, err := h.messageStore.GetRepliesPage(ctx, root.ID, page)
+	if err != nil {
+		log.Printf("GetThread: Failed to get replies to message %s: %v", root.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve thread"})
+		return
+	}
+	if replies.Items == nil {
+		replies.Items = make([]*models.Message, 0)
+	}
+	if err := h.attachMessageDetails(c, userID, append([]*models.Message{root}, replies.Items...)); err != nil {
+		log.Printf("GetThread: Failed to get details of thread %s: %v", root.ID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve thread"})
+		return
+	}
+	c.JSON(http.StatusOK, models.Thread{Root: root, Replies: replies})
+}
+
+// loadOwnMessage resolves the :id message and ensures the caller sent it.
+func (h *RestHandler) loadOwnMessage(c *gin.Context) (*models.Message, bool) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return nil, false
+	}
+	messageID, ok := messageIDFromParam(c)
+	if !ok {
+		return nil, false
+	}
+	msg, err := h.guard.RequireMessageAccess(c.Request.Context(), messageID, userID)
+	if err != nil {
+		respondAccessError(c, "loadOwnMessage", uuid.Nil, userID, err)
+		return nil, false
+	}
+	if msg.SenderID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the sender can modify this message"})
+		return nil, false
+	}
+	return msg, true
+}
+
+func messageIDFromParam(c *gin.Context) (uuid.UUID, bool) {
+	messageID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID format"})
+		return uuid.Nil, false
+	}
+	return messageID, true
+}
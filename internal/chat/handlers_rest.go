@@ -7,8 +7,11 @@ import (
 	"strconv"
 	"time"
 
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/events"
 	"blinkchat-backend/internal/models"
 	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
 	"blinkchat-backend/internal/websocket"
 
 	"github.com/gin-gonic/gin"
@@ -17,18 +20,24 @@ import (
 
 // RestHandler handles REST API requests related to messaging.
 type RestHandler struct {
-	chatStore    store.ChatStore
-	messageStore store.MessageStore
-	userStore    store.UserStore
-	wsHub        *websocket.Hub
+	chatStore       store.ChatStore
+	messageStore    store.MessageStore
+	userStore       store.UserStore
+	attachmentStore store.AttachmentStore
+	guard           *access.Guard
+	eventLog        *events.Log
+	wsHub           *websocket.Hub
 }
 
-func NewRestHandler(cs store.ChatStore, ms store.MessageStore, us store.UserStore, hub *websocket.Hub) *RestHandler {
+func NewRestHandler(cs store.ChatStore, ms store.MessageStore, us store.UserStore, as store.AttachmentStore, guard *access.Guard, eventLog *events.Log, hub *websocket.Hub) *RestHandler {
 	return &RestHandler{
-		chatStore:    cs,
-		messageStore: ms,
-		userStore:    us,
-		wsHub:        hub,
+		chatStore:       cs,
+		messageStore:    ms,
+		userStore:       us,
+		attachmentStore: as,
+		guard:           guard,
+		eventLog:        eventLog,
+		wsHub:           hub,
 	}
 }
 
@@ -38,6 +47,10 @@ func (h *RestHandler) PostMessage(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
 		return
 	}
+	if req.Content == "" && len(req.AttachmentIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message needs content or attachments"})
+		return
+	}
 
 	senderIDString, _ := c.Get("userID")
 	senderID, err := uuid.Parse(senderIDString.(string))
@@ -47,17 +60,34 @@ func (h *RestHandler) PostMessage(c *gin.Context) {
 		return
 	}
 
+	attachments, err := h.guard.RequireSendableAttachments(c.Request.Context(), senderID, req.AttachmentIDs)
+	if err != nil {
+		respondAccessError(c, "PostMessage", uuid.Nil, senderID, err)
+		return
+	}
+
 	var chatID uuid.UUID
 	var createdChat *models.Chat
 
 	if req.ChatID != nil {
 		chatID = *req.ChatID
+		if !h.requireParticipant(c, chatID, senderID) {
+			return
+		}
+		if err := h.guard.RequireCanMessage(c.Request.Context(), chatID, senderID); err != nil {
+			respondAccessError(c, "PostMessage", chatID, senderID, err)
+			return
+		}
 	} else if req.ReceiverID != nil {
 		receiverID := *req.ReceiverID
 		if senderID == receiverID {
 			c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot send message to yourself in this context"})
 			return
 		}
+		if err := h.guard.RequireNotBlocked(c.Request.Context(), senderID, receiverID); err != nil {
+			respondAccessError(c, "PostMessage", uuid.Nil, senderID, err)
+			return
+		}
 
 		participantIDs := []uuid.UUID{senderID, receiverID}
 		existingChat, err := h.chatStore.GetChatByParticipantIDs(c.Request.Context(), participantIDs)
@@ -69,6 +99,9 @@ func (h *RestHandler) PostMessage(c *gin.Context) {
 
 		if existingChat != nil {
 			chatID = existingChat.ID
+		} else if req.ReplyToID != nil {
+			respondAccessError(c, "PostMessage", uuid.Nil, senderID, access.ErrInvalidReply)
+			return
 		} else {
 			newChat, err := h.chatStore.CreateChat(c.Request.Context(), participantIDs)
 			if err != nil {
@@ -84,6 +117,14 @@ func (h *RestHandler) PostMessage(c *gin.Context) {
 		return
 	}
 
+	var parent *models.Message
+	if req.ReplyToID != nil {
+		if parent, err = h.guard.RequireReplyTarget(c.Request.Context(), chatID, *req.ReplyToID); err != nil {
+			respondAccessError(c, "PostMessage", chatID, senderID, err)
+			return
+		}
+	}
+
 	message := &models.Message{
 		ID:        uuid.New(),
 		ChatID:    chatID,
@@ -91,9 +132,19 @@ func (h *RestHandler) PostMessage(c *gin.Context) {
 		Content:   req.Content,
 		Timestamp: time.Now(),
 		Status:    models.StatusSent,
+		ReplyToID: req.ReplyToID,
+
+		Attachments: attachments,
+	}
+	if parent != nil {
+		message.ReplyTo = parent.Quote()
 	}
 
 	err = h.messageStore.CreateMessage(c.Request.Context(), message)
+	if errors.Is(err, store.ErrAttachmentUnavailable) {
+		respondAccessError(c, "PostMessage", chatID, senderID, access.ErrInvalidAttachment)
+		return
+	}
 	if err != nil {
 		log.Printf("PostMessage: Failed to store message for chat %s: %v", chatID, err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
@@ -102,11 +153,12 @@ func (h *RestHandler) PostMessage(c *gin.Context) {
 
 	senderUser, err := h.userStore.GetUserByID(c.Request.Context(), senderID.String())
 	if err == nil && senderUser != nil {
-		message.Sender = senderUser.ToPublicUser()
+		message.Sender = senderUser.ProfileFor(models.RelationshipContact)
 	} else {
 		log.Printf("PostMessage: Could not fetch sender details for user %s: %v", senderID, err)
 		message.Sender = &models.PublicUser{ID: senderID, Username: "Unknown User"}
 	}
+	utils.SignAttachmentURLs(message.Attachments, message.Timestamp)
 
 	if h.wsHub != nil {
 		log.Printf("PostMessage: Attempting to broadcast message %s via WebSocket Hub", message.ID)
@@ -130,6 +182,14 @@ func (h *RestHandler) GetMessagesByChatID(c *gin.Context) {
 		return
 	}
 
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	if !h.requireParticipant(c, chatID, userID) {
+		return
+	}
+
 	limitStr := c.DefaultQuery("limit", "20")
 	offsetStr := c.DefaultQuery("offset", "0")
 
@@ -137,6 +197,27 @@ func (h *RestHandler) GetMessagesByChatID(c *gin.Context) {
 	if err != nil || limit <= 0 || limit > 100 {
 		limit = 20
 	}
+
+	page, cursorMode, ok := cursorPageFromQuery(c, limit)
+	if !ok {
+		return
+	}
+	if cursorMode {
+		result, err := h.messageStore.GetMessagesPage(c.Request.Context(), chatID, page)
+		if err != nil {
+			log.Printf("GetMessagesByChatID: Failed to get message page for chat %s: %v", chatID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve messages"})
+			return
+		}
+		if err := h.attachMessageDetails(c, userID, result.Items); err != nil {
+			log.Printf("GetMessagesByChatID: Failed to get message details for chat %s: %v", chatID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve messages"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil || offset < 0 {
 		offset = 0
@@ -152,6 +233,11 @@ func (h *RestHandler) GetMessagesByChatID(c *gin.Context) {
 	if messages == nil {
 		messages = make([]*models.Message, 0)
 	}
+	if err := h.attachMessageDetails(c, userID, messages); err != nil {
+		log.Printf("GetMessagesByChatID: Failed to get message details for chat %s: %v", chatID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve messages"})
+		return
+	}
 	c.JSON(http.StatusOK, messages)
 }
 
@@ -170,6 +256,22 @@ func (h *RestHandler) GetChats(c *gin.Context) {
 	if err != nil || limit <= 0 || limit > 50 {
 		limit = 20
 	}
+
+	page, cursorMode, ok := cursorPageFromQuery(c, limit)
+	if !ok {
+		return
+	}
+	if cursorMode {
+		result, err := h.chatStore.GetUserChatsPage(c.Request.Context(), userID, page)
+		if err != nil {
+			log.Printf("GetChats: Failed to get chat page for user %s: %v", userID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve chats"})
+			return
+		}
+		c.JSON(http.StatusOK, result)
+		return
+	}
+
 	offset, err := strconv.Atoi(offsetStr)
 	if err != nil || offset < 0 {
 		offset = 0
@@ -187,3 +289,153 @@ func (h *RestHandler) GetChats(c *gin.Context) {
 	}
 	c.JSON(http.StatusOK, chats)
 }
+
+// MarkChatRead marks every message in the chat as read by the caller, clearing its unread count.
+func (h *RestHandler) MarkChatRead(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	chatID, ok := chatIDFromParam(c)
+	if !ok {
+		return
+	}
+	if !h.requireParticipant(c, chatID, userID) {
+		return
+	}
+
+	lastMessageID, advanced, err := h.messageStore.MarkChatRead(c.Request.Context(), chatID, userID)
+	if err != nil {
+		log.Printf("MarkChatRead: Failed to mark chat %s read for user %s: %v", chatID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mark chat as read"})
+		return
+	}
+	if advanced && h.wsHub != nil {
+		h.wsHub.BroadcastReadReceipt(chatID, userID, lastMessageID)
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetUnreadTotal returns the caller's unread message count across all chats they have not muted.
+func (h *RestHandler) GetUnreadTotal(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	total, err := h.messageStore.GetTotalUnreadCount(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("GetUnreadTotal: Failed to count unread messages for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve unread count"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"totalUnread": total})
+}
+
+// MuteChat stops the chat from counting towards the caller's unread total. Messages are
+// still delivered and the chat's own unread count keeps updating. new_message events are
+// the same for every member and carry no mute flag: clients decide whether to notify from
+// the muted field of the chat list, which this response and UnmuteChat's also return.
+func (h *RestHandler) MuteChat(c *gin.Context) {
+	h.setChatMuted(c, "MuteChat", true)
+}
+
+// UnmuteChat reverses MuteChat.
+func (h *RestHandler) UnmuteChat(c *gin.Context) {
+	h.setChatMuted(c, "UnmuteChat", false)
+}
+
+func (h *RestHandler) setChatMuted(c *gin.Context, op string, muted bool) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	chatID, ok := chatIDFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := h.chatStore.SetChatMuted(c.Request.Context(), chatID, userID, muted); err != nil {
+		if errors.Is(err, store.ErrNotParticipant) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "You are not a participant of this chat"})
+			return
+		}
+		log.Printf("%s: Failed to update mute of chat %s for user %s: %v", op, chatID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update chat mute"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"chatId": chatID, "muted": muted})
+}
+
+// cursorPageFromQuery reads the before/after cursor parameters. Cursor mode is selected by the
+// presence of either parameter; an empty value starts from the newest ("before") or oldest
+// ("after") item. Without them the caller falls back to limit/offset pagination.
+func cursorPageFromQuery(c *gin.Context, limit int) (models.CursorPage, bool, bool) {
+	page := models.CursorPage{Limit: limit}
+	before, hasBefore := c.GetQuery("before")
+	after, hasAfter := c.GetQuery("after")
+	if hasBefore && hasAfter {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Only one of before and after may be given"})
+		return page, false, false
+	}
+	if !hasBefore && !hasAfter {
+		return page, false, true
+	}
+
+	var err error
+	switch {
+	case before != "":
+		page.Before, err = models.ParseCursor(before)
+	case after != "":
+		page.After, err = models.ParseCursor(after)
+	case hasAfter:
+		page.After = &models.Cursor{}
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid cursor", "details": err.Error()})
+		return page, false, false
+	}
+	return page, true, true
+}
+
+// userIDFromContext returns the authenticated user's ID set by the auth middleware.
+func userIDFromContext(c *gin.Context) (uuid.UUID, bool) {
+	userIDString, _ := c.Get("userID")
+	idStr, _ := userIDString.(string)
+	userID, err := uuid.Parse(idStr)
+	if err != nil {
+		log.Printf("userIDFromContext: Invalid userID from token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user session"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// requireParticipant writes a 403 response and returns false unless userID belongs to chatID.
+func (h *RestHandler) requireParticipant(c *gin.Context, chatID, userID uuid.UUID) bool {
+	if err := h.guard.RequireParticipant(c.Request.Context(), chatID, userID); err != nil {
+		respondAccessError(c, "requireParticipant", chatID, userID, err)
+		return false
+	}
+	return true
+}
+
+// respondAccessError maps access.Guard errors onto HTTP responses.
+func respondAccessError(c *gin.Context, op string, chatID, userID uuid.UUID, err error) {
+	switch {
+	case errors.Is(err, access.ErrForbidden):
+		c.JSON(http.StatusForbidden, gin.H{"error": "You are not a participant of this chat"})
+	case errors.Is(err, access.ErrNotAdmin):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only chat admins can perform this action"})
+	case errors.Is(err, access.ErrBlocked):
+		c.JSON(http.StatusForbidden, gin.H{"error": "Unable to send messages to this user"})
+	case errors.Is(err, access.ErrInvalidReply):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Reply target must be an existing message in the same chat"})
+	case errors.Is(err, access.ErrInvalidAttachment):
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Attachments must be your own uploads that have not been sent yet"})
+	case errors.Is(err, store.ErrMessageNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+	default:
+		log.Printf("%s: Failed to verify access of user %s to chat %s: %v", op, userID, chatID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify chat membership"})
+	}
+}
@@ -0,0 +1,40 @@
+package chat
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/models"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// Sync returns the chat events the caller missed since the given cursors. It is the REST
+// counterpart of the WebSocket "sync" request, for clients that catch up before connecting.
+func (h *RestHandler) Sync(c *gin.Context) {
+	var req models.SyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	result, err := h.eventLog.Sync(c.Request.Context(), userID, req)
+	if err != nil {
+		if errors.Is(err, access.ErrForbidden) {
+			respondAccessError(c, "Sync", uuid.Nil, userID, err)
+			return
+		}
+		log.Printf("Sync: Failed to collect events for user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sync"})
+		return
+	}
+	log.Printf("Sync: Returning %d event(s) to user %s", len(result.Events), userID)
+	c.JSON(http.StatusOK, result)
+}
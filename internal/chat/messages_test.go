@@ -0,0 +1,60 @@
+package chat
+
+import (
+	"net/http"
+	"testing"
+)
+
+func TestEditMessageKeepsDetails(t *testing.T) {
+	f := newRestFixture(t)
+	alice, bob := f.createUser(t), f.createUser(t)
+
+	status, sent := f.do(t, alice, http.MethodPost, "/messages", map[string]any{"receiverId": bob, "content": "first draft"})
+	if status != http.StatusCreated {
+		t.Fatalf("send: got %d %v", status, sent)
+	}
+	chatID, messageID := sent["chatId"], sent["id"].(string)
+	if status, body := f.do(t, alice, http.MethodPost, "/messages/"+messageID+"/reactions", map[string]any{"emoji": "👍"}); status >= 300 {
+		t.Fatalf("react: got %d %v", status, body)
+	}
+	if status, body := f.do(t, bob, http.MethodPost, "/messages", map[string]any{"chatId": chatID, "replyToId": messageID, "content": "a reply"}); status != http.StatusCreated {
+		t.Fatalf("reply: got %d %v", status, body)
+	}
+
+	status, edited := f.do(t, alice, http.MethodPatch, "/messages/"+messageID, map[string]any{"content": "second draft"})
+	if status != http.StatusOK {
+		t.Fatalf("edit: got %d %v", status, edited)
+	}
+	assertDetails(t, "edit response", edited, true)
+
+	status, synced := f.do(t, bob, http.MethodPost, "/sync", map[string]any{"chats": []map[string]any{{"chatId": chatID}}})
+	if status != http.StatusOK {
+		t.Fatalf("sync: got %d %v", status, synced)
+	}
+	var event map[string]any
+	for _, e := range synced["events"].([]any) {
+		if e := e.(map[string]any); e["type"] == "message_edited" {
+			event = e
+		}
+	}
+	if event == nil {
+		t.Fatalf("sync has no message_edited event: %v", synced)
+	}
+	// The event goes to every member, so it must not carry the editor's own flags.
+	assertDetails(t, "message_edited event", event["payload"].(map[string]any), false)
+}
+
+func assertDetails(t *testing.T, what string, msg map[string]any, reactedByMe bool) {
+	t.Helper()
+	if msg["replyCount"] != float64(1) {
+		t.Errorf("%s: replyCount = %v, want 1", what, msg["replyCount"])
+	}
+	reactions, _ := msg["reactions"].([]any)
+	if len(reactions) != 1 {
+		t.Fatalf("%s: reactions = %v, want one", what, msg["reactions"])
+	}
+	r := reactions[0].(map[string]any)
+	if r["emoji"] != "👍" || r["count"] != float64(1) || r["reactedByMe"] != reactedByMe {
+		t.Errorf("%s: reaction = %v, want 👍 x1 with reactedByMe %v", what, r, reactedByMe)
+	}
+}
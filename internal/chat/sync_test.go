@@ -0,0 +1,111 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/websocket"
+
+	"github.com/google/uuid"
+)
+
+func TestSyncDoesNotReplayReplacedContent(t *testing.T) {
+	f := newRestFixture(t)
+	alice, bob := f.createUser(t), f.createUser(t)
+
+	status, sent := f.do(t, alice, http.MethodPost, "/messages", map[string]any{"receiverId": bob, "content": "first draft"})
+	if status != http.StatusCreated {
+		t.Fatalf("send: got %d %v", status, sent)
+	}
+	chatID, messageID := sent["chatId"], sent["id"]
+	if status, body := f.do(t, bob, http.MethodPost, "/messages", map[string]any{"chatId": chatID, "replyToId": messageID, "content": "a reply"}); status != http.StatusCreated {
+		t.Fatalf("reply: got %d %v", status, body)
+	}
+
+	syncText := func(t *testing.T) string {
+		t.Helper()
+		status, body := f.do(t, bob, http.MethodPost, "/sync", map[string]any{"chats": []map[string]any{{"chatId": chatID}}})
+		if status != http.StatusOK {
+			t.Fatalf("sync: got %d %v", status, body)
+		}
+		raw, _ := json.Marshal(body)
+		return string(raw)
+	}
+
+	if status, body := f.do(t, alice, http.MethodPatch, "/messages/"+messageID.(string), map[string]any{"content": "second draft"}); status != http.StatusOK {
+		t.Fatalf("edit: got %d %v", status, body)
+	}
+	if got := syncText(t); strings.Contains(got, "first draft") || !strings.Contains(got, "second draft") {
+		t.Errorf("sync after edit replays the previous revision: %s", got)
+	}
+
+	if status, body := f.do(t, alice, http.MethodDelete, "/messages/"+messageID.(string), nil); status != http.StatusNoContent {
+		t.Fatalf("delete: got %d %v", status, body)
+	}
+	if got := syncText(t); strings.Contains(got, "draft") {
+		t.Errorf("sync after delete replays deleted content: %s", got)
+	}
+}
+
+func TestSyncReplaysRoleChanges(t *testing.T) {
+	f := newRestFixture(t)
+	admin, member, onlooker := f.createUser(t), f.createUser(t), f.createUser(t)
+	group, err := f.chats.CreateGroupChat(context.Background(), "team", admin, []uuid.UUID{member, onlooker})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	// roleEvents returns the role of every chat_member_updated event onlooker can sync.
+	roleEvents := func(t *testing.T) []string {
+		t.Helper()
+		status, body := f.do(t, onlooker, http.MethodPost, "/sync", map[string]any{"chats": []map[string]any{{"chatId": group.ID}}})
+		if status != http.StatusOK {
+			t.Fatalf("sync: got %d %v", status, body)
+		}
+		raw, _ := json.Marshal(body["events"])
+		var events []models.ChatEvent
+		if err := json.Unmarshal(raw, &events); err != nil {
+			t.Fatalf("decode events: %v", err)
+		}
+		var roles []string
+		for _, e := range events {
+			if e.Type != websocket.MessageTypeChatMemberUpdated {
+				continue
+			}
+			var p websocket.ChatMemberEventPayload
+			if err := json.Unmarshal(e.Payload, &p); err != nil {
+				t.Fatalf("decode payload: %v", err)
+			}
+			if p.Member == nil {
+				t.Fatalf("event %d carries no member", e.Seq)
+			}
+			roles = append(roles, fmt.Sprintf("%s:%s", p.Member.ID, p.Role))
+		}
+		return roles
+	}
+	memberPath := fmt.Sprintf("/chats/%s/members/%s", group.ID, member)
+
+	for _, role := range []models.ChatRole{models.RoleAdmin, models.RoleAdmin, models.RoleMember} {
+		if status, body := f.do(t, admin, http.MethodPatch, memberPath, map[string]any{"role": role}); status != http.StatusOK {
+			t.Fatalf("set role %s: got %d %v", role, status, body)
+		}
+	}
+	// Setting the role a member already has changes nothing and is not recorded.
+	want := []string{fmt.Sprintf("%s:%s", member, models.RoleAdmin), fmt.Sprintf("%s:%s", member, models.RoleMember)}
+	if got := roleEvents(t); strings.Join(got, ",") != strings.Join(want, ",") {
+		t.Fatalf("role events after promote and demote = %v, want %v", got, want)
+	}
+
+	if status, body := f.do(t, admin, http.MethodPost, fmt.Sprintf("/chats/%s/leave", group.ID), nil); status != http.StatusNoContent {
+		t.Fatalf("leave: got %d %v", status, body)
+	}
+	got := roleEvents(t)
+	if len(got) != len(want)+1 || !strings.HasSuffix(got[len(got)-1], ":"+string(models.RoleAdmin)) {
+		t.Errorf("role events after the last admin left = %v, want the successor's promotion last", got)
+	}
+}
@@ -0,0 +1,137 @@
+package events
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// syncLimit caps the events returned per chat, and for the LastSeq sweep, in one sync round.
+const syncLimit = 500
+
+// Log records realtime chat events and replays them to clients that were offline.
+// It is shared by the WebSocket hub, which appends to it, and the sync endpoints.
+type Log struct {
+	eventStore store.EventStore
+	guard      *access.Guard
+}
+
+// NewLog returns a Log backed by the given store.
+func NewLog(es store.EventStore, guard *access.Guard) *Log {
+	return &Log{eventStore: es, guard: guard}
+}
+
+// Append records an event for a chat. messageID is set for events about one message, so
+// that clients can sync from the message that created it and its content can be redacted.
+func (l *Log) Append(ctx context.Context, chatID uuid.UUID, messageID *uuid.UUID, eventType string, payload interface{}) (*models.ChatEvent, error) {
+	return l.eventStore.AppendChatEvent(ctx, chatID, messageID, eventType, payload)
+}
+
+// RedactMessage rewrites the recorded events of msg to its current content, after an edit
+// or deletion.
+func (l *Log) RedactMessage(ctx context.Context, msg *models.Message) error {
+	return l.eventStore.RedactMessageEvents(ctx, msg)
+}
+
+// Get returns a recorded event by seq.
+func (l *Log) Get(ctx context.Context, seq int64) (*models.ChatEvent, error) {
+	return l.eventStore.GetChatEvent(ctx, seq)
+}
+
+// Sync collects the events userID missed since the given cursors. Listed chats the user
+// does not belong to fail with access.ErrForbidden.
+func (l *Log) Sync(ctx context.Context, userID uuid.UUID, req models.SyncRequest) (*models.SyncResult, error) {
+	result := &models.SyncResult{Events: make([]*models.ChatEvent, 0), Chats: make([]models.SyncChatState, 0)}
+	listed := make(map[uuid.UUID]bool, len(req.Chats))
+
+	for _, cursor := range req.Chats {
+		if listed[cursor.ChatID] {
+			continue
+		}
+		listed[cursor.ChatID] = true
+		if err := l.guard.RequireParticipant(ctx, cursor.ChatID, userID); err != nil {
+			return nil, err
+		}
+
+		afterSeq, err := l.resolveCursor(ctx, cursor)
+		if err != nil {
+			return nil, err
+		}
+		events, err := l.eventStore.GetChatEventsAfter(ctx, cursor.ChatID, afterSeq, syncLimit+1)
+		if err != nil {
+			return nil, err
+		}
+		state := models.SyncChatState{ChatID: cursor.ChatID, LastSeq: afterSeq}
+		if len(events) > syncLimit {
+			events = events[:syncLimit]
+			state.HasMore = true
+			result.HasMore = true
+		}
+		if len(events) > 0 {
+			state.LastSeq = events[len(events)-1].Seq
+		}
+		result.Events = append(result.Events, events...)
+		result.Chats = append(result.Chats, state)
+	}
+
+	if req.LastSeq != nil {
+		events, err := l.eventStore.GetUserEventsAfter(ctx, userID, *req.LastSeq, syncLimit+1)
+		if err != nil {
+			return nil, err
+		}
+		sweepHasMore := len(events) > syncLimit
+		if sweepHasMore {
+			events = events[:syncLimit]
+			result.HasMore = true
+		}
+		result.LastSeq = *req.LastSeq
+		if len(events) > 0 {
+			result.LastSeq = events[len(events)-1].Seq
+		}
+
+		discovered := make(map[uuid.UUID]int, 0)
+		for _, e := range events {
+			if listed[e.ChatID] {
+				continue
+			}
+			result.Events = append(result.Events, e)
+			if i, ok := discovered[e.ChatID]; ok {
+				result.Chats[i].LastSeq = e.Seq
+				continue
+			}
+			discovered[e.ChatID] = len(result.Chats)
+			result.Chats = append(result.Chats, models.SyncChatState{ChatID: e.ChatID, LastSeq: e.Seq, HasMore: sweepHasMore})
+		}
+	}
+
+	sort.Slice(result.Events, func(i, j int) bool {
+		return result.Events[i].Seq < result.Events[j].Seq
+	})
+	if req.LastSeq == nil && len(result.Events) > 0 {
+		result.LastSeq = result.Events[len(result.Events)-1].Seq
+	}
+	return result, nil
+}
+
+// resolveCursor turns a chat cursor into an event seq. A message ID that predates the
+// event log falls back to LastSeq.
+func (l *Log) resolveCursor(ctx context.Context, cursor models.SyncChatCursor) (int64, error) {
+	if cursor.LastMessageID == nil {
+		return cursor.LastSeq, nil
+	}
+	seq, err := l.eventStore.GetMessageEventSeq(ctx, *cursor.LastMessageID)
+	if err != nil {
+		if errors.Is(err, store.ErrEventNotFound) {
+			return cursor.LastSeq, nil
+		}
+		return 0, fmt.Errorf("failed to resolve sync cursor for chat %s: %w", cursor.ChatID, err)
+	}
+	return seq, nil
+}
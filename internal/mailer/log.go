@@ -0,0 +1,43 @@
+package mailer
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Log writes messages to the server log and, when dir is set, to .eml files in dir.
+// It is meant for local development, where links can be copied from either.
+type Log struct {
+	dir  string
+	from string
+}
+
+// NewLog returns a Mailer that never delivers anything.
+func NewLog(dir string, from string) *Log {
+	return &Log{dir: dir, from: from}
+}
+
+func (m *Log) Send(ctx context.Context, msg Message) error {
+	log.Printf("Mailer: To=%s Subject=%q\n%s", msg.To, msg.Subject, msg.Body)
+	if m.dir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(m.dir, 0o755); err != nil {
+		return fmt.Errorf("failed to create mail directory %s: %w", m.dir, err)
+	}
+	recipient := strings.NewReplacer("/", "_", "\\", "_", "@", "_at_").Replace(msg.To)
+	name := fmt.Sprintf("%s-%s.eml", time.Now().Format("20060102T150405.000000000"), recipient)
+	path := filepath.Join(m.dir, name)
+	if err := os.WriteFile(path, formatMessage(m.from, msg), 0o644); err != nil {
+		return fmt.Errorf("failed to write mail to %s: %w", path, err)
+	}
+	return nil
+}
+
+var _ Mailer = (*Log)(nil)
@@ -0,0 +1,18 @@
+// Package mailer sends transactional email such as verification and password reset links.
+package mailer
+
+import (
+	"context"
+)
+
+// Message is a plain-text email to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Body    string
+}
+
+// Mailer delivers messages. SMTP delivers them for real; Log keeps them local for development.
+type Mailer interface {
+	Send(ctx context.Context, msg Message) error
+}
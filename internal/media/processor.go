@@ -0,0 +1,247 @@
+// Package media prepares uploaded images for display in the background: it strips their
+// location data, records their dimensions, and renders thumbnails and a blurhash
+// placeholder.
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"image"
+	_ "image/gif" // register decoders for image.Decode
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"log"
+	"sync"
+	"time"
+
+	"blinkchat-backend/internal/blob"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
+	"blinkchat-backend/internal/websocket"
+
+	"github.com/google/uuid"
+)
+
+// queueSize bounds the attachments waiting for a worker. Attachments that do not fit stay
+// pending and are picked up on the next start.
+const queueSize = 256
+
+// maxPixels refuses images that would take too much memory to decode.
+const maxPixels = 50_000_000
+
+// errUnprocessable marks failures caused by the file itself rather than by storage, so
+// retrying would not help.
+var errUnprocessable = errors.New("image cannot be processed")
+
+// Processable reports whether the processor handles files of a sniffed content type.
+func Processable(contentType string) bool {
+	switch contentType {
+	case "image/jpeg", "image/png", "image/gif":
+		return true
+	}
+	return false
+}
+
+// Processor works through pending image attachments on a pool of goroutines and tells
+// chat members, or the uploader of a file not sent yet, once one is ready.
+type Processor struct {
+	attachmentStore store.AttachmentStore
+	blobs           blob.BlobStore
+	wsHub           *websocket.Hub
+	thumbnailSizes  []int
+	maxBytes        int64
+	workers         int
+
+	jobs     chan uuid.UUID
+	mu       sync.Mutex
+	inFlight map[uuid.UUID]bool
+}
+
+// NewProcessor returns a Processor rendering a thumbnail for each of thumbnailSizes
+// (longer edge, in pixels) that is smaller than the image. Call Run to start it.
+func NewProcessor(as store.AttachmentStore, blobs blob.BlobStore, hub *websocket.Hub, thumbnailSizes []int, maxBytes int64, workers int) *Processor {
+	return &Processor{
+		attachmentStore: as,
+		blobs:           blobs,
+		wsHub:           hub,
+		thumbnailSizes:  thumbnailSizes,
+		maxBytes:        maxBytes,
+		workers:         max(1, workers),
+		jobs:            make(chan uuid.UUID, queueSize),
+		inFlight:        make(map[uuid.UUID]bool),
+	}
+}
+
+// Run starts the workers and queues the attachments a previous run left pending. With
+// several replicas each of them picks those up, which is harmless as processing is
+// deterministic.
+func (p *Processor) Run(ctx context.Context) {
+	for i := 0; i < p.workers; i++ {
+		go p.work(ctx)
+	}
+	ids, err := p.attachmentStore.GetPendingAttachmentIDs(ctx)
+	if err != nil {
+		log.Printf("Media processor: Failed to load pending attachments: %v", err)
+		return
+	}
+	if len(ids) > 0 {
+		log.Printf("Media processor: Resuming %d pending attachment(s)", len(ids))
+	}
+	for _, id := range ids {
+		p.Enqueue(id)
+	}
+}
+
+// Enqueue schedules an attachment for processing unless it is already queued.
+func (p *Processor) Enqueue(attachmentID uuid.UUID) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.inFlight[attachmentID] {
+		return
+	}
+	select {
+	case p.jobs <- attachmentID:
+		p.inFlight[attachmentID] = true
+	default:
+		log.Printf("Media processor: Queue full, attachment %s stays pending until the next start", attachmentID)
+	}
+}
+
+func (p *Processor) work(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case id := <-p.jobs:
+			if err := p.process(ctx, id); err != nil {
+				log.Printf("Media processor: Failed to process attachment %s: %v", id, err)
+			}
+			p.mu.Lock()
+			delete(p.inFlight, id)
+			p.mu.Unlock()
+		}
+	}
+}
+
+// process handles one attachment. Files that cannot be decoded are marked failed; storage
+// errors leave the attachment pending so that it is retried on the next start.
+func (p *Processor) process(ctx context.Context, attachmentID uuid.UUID) error {
+	a, err := p.attachmentStore.GetAttachmentByID(ctx, attachmentID)
+	if err != nil {
+		return fmt.Errorf("failed to load attachment: %w", err)
+	}
+	if a.ProcessingStatus != models.ProcessingPending {
+		return nil
+	}
+
+	data, err := p.readBlob(ctx, a.BlobKey)
+	if err != nil {
+		return err
+	}
+	err = p.prepare(ctx, a, data)
+	if errors.Is(err, errUnprocessable) {
+		log.Printf("Media processor: Attachment %s is not a usable image: %v", a.ID, err)
+		a.ProcessingStatus = models.ProcessingFailed
+		a.Width, a.Height, a.Blurhash, a.Thumbnails = 0, 0, "", nil
+	} else if err != nil {
+		return err
+	} else {
+		a.ProcessingStatus = models.ProcessingReady
+	}
+
+	now := time.Now()
+	a.ProcessedAt = &now
+	updated, err := p.attachmentStore.UpdateAttachmentProcessing(ctx, a)
+	if err != nil {
+		return fmt.Errorf("failed to store processing result: %w", err)
+	}
+	p.notify(updated, now)
+	return nil
+}
+
+// prepare strips location data from the stored file and fills in a's dimensions,
+// blurhash and thumbnails, writing the cleaned file and the thumbnails to the blob store.
+func (p *Processor) prepare(ctx context.Context, a *models.Attachment, data []byte) error {
+	cleaned, orientation, err := StripLocation(a.ContentType, data)
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUnprocessable, err)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(cleaned))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUnprocessable, err)
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width*cfg.Height > maxPixels {
+		return fmt.Errorf("%w: %dx%d pixels", errUnprocessable, cfg.Width, cfg.Height)
+	}
+	decoded, _, err := image.Decode(bytes.NewReader(cleaned))
+	if err != nil {
+		return fmt.Errorf("%w: %v", errUnprocessable, err)
+	}
+	img := orient(toRGBA(decoded), orientation)
+	a.Width, a.Height = img.Bounds().Dx(), img.Bounds().Dy()
+	// The orientation tag left with the EXIF segment, so a rotated file is stored upright.
+	if orientation != 1 {
+		if cleaned, err = encodeJPEG(img, uprightQuality); err != nil {
+			return fmt.Errorf("failed to encode upright image: %w", err)
+		}
+	}
+
+	sw, sh := fit(a.Width, a.Height, blurhashSampleEdge)
+	xComponents, yComponents := blurhashComponents(a.Width, a.Height)
+	a.Blurhash = Blurhash(flatten(resize(img, sw, sh)), xComponents, yComponents)
+
+	a.Thumbnails = nil
+	for _, maxEdge := range p.thumbnailSizes {
+		if a.Width <= maxEdge && a.Height <= maxEdge {
+			continue
+		}
+		tw, th := fit(a.Width, a.Height, maxEdge)
+		encoded, err := encodeJPEG(resize(img, tw, th), thumbnailQuality)
+		if err != nil {
+			return fmt.Errorf("failed to encode %dpx thumbnail: %w", maxEdge, err)
+		}
+		if err := p.blobs.Put(ctx, models.ThumbnailBlobKey(a.ID, maxEdge), bytes.NewReader(encoded), int64(len(encoded)), "image/jpeg"); err != nil {
+			return fmt.Errorf("failed to store %dpx thumbnail: %w", maxEdge, err)
+		}
+		a.Thumbnails = append(a.Thumbnails, &models.Thumbnail{MaxEdge: maxEdge, Width: tw, Height: th, Size: int64(len(encoded))})
+	}
+
+	if !bytes.Equal(cleaned, data) {
+		if err := p.blobs.Put(ctx, a.BlobKey, bytes.NewReader(cleaned), int64(len(cleaned)), a.ContentType); err != nil {
+			return fmt.Errorf("failed to store cleaned file: %w", err)
+		}
+		a.Size = int64(len(cleaned))
+	}
+	return nil
+}
+
+func (p *Processor) readBlob(ctx context.Context, key string) ([]byte, error) {
+	r, err := p.blobs.Open(ctx, key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open blob: %w", err)
+	}
+	defer r.Close()
+	data, err := io.ReadAll(io.LimitReader(r, p.maxBytes+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read blob: %w", err)
+	}
+	return data, nil
+}
+
+// notify sends the processed attachment to the members of the chat it was sent to, or
+// only to its uploader while it has not been sent.
+func (p *Processor) notify(a *models.Attachment, now time.Time) {
+	if p.wsHub == nil {
+		return
+	}
+	utils.SignAttachmentURLs([]*models.Attachment{a}, now)
+	if a.ChatID != nil {
+		p.wsHub.BroadcastToChat(*a.ChatID, websocket.MessageTypeAttachmentProcessed, a)
+		return
+	}
+	p.wsHub.BroadcastToUser(a.UploaderID, websocket.MessageTypeAttachmentProcessed, a)
+}
@@ -0,0 +1,73 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"image/jpeg"
+	"io"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/blob"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func TestProcessStoresImageWithoutEXIF(t *testing.T) {
+	ctx := context.Background()
+	data := readFixture(t)
+	blobs := blob.NewLocal(t.TempDir())
+	attachments := store.NewMemoryAttachmentStore(store.NewMemoryDB())
+	a := &models.Attachment{
+		ID:               uuid.New(),
+		UploaderID:       uuid.New(),
+		BlobKey:          "uploads/photo.jpg",
+		Filename:         "photo.jpg",
+		ContentType:      "image/jpeg",
+		Size:             int64(len(data)),
+		CreatedAt:        time.Now(),
+		ProcessingStatus: models.ProcessingPending,
+	}
+	if err := blobs.Put(ctx, a.BlobKey, bytes.NewReader(data), a.Size, a.ContentType); err != nil {
+		t.Fatalf("Put: %v", err)
+	}
+	if err := attachments.CreateAttachment(ctx, a); err != nil {
+		t.Fatalf("CreateAttachment: %v", err)
+	}
+
+	p := NewProcessor(attachments, blobs, nil, []int{4}, 1<<20, 1)
+	if err := p.process(ctx, a.ID); err != nil {
+		t.Fatalf("process: %v", err)
+	}
+
+	r, err := blobs.Open(ctx, a.BlobKey)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	stored, err := io.ReadAll(r)
+	r.Close()
+	if err != nil {
+		t.Fatalf("read processed blob: %v", err)
+	}
+	if app1 := jpegAppSegments(t, stored)[0xE1]; len(app1) != 0 {
+		t.Errorf("processed blob has APP1 segments %q, want none", app1)
+	}
+	// Without its orientation tag the file must already be upright.
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(stored))
+	if err != nil || cfg.Width != 4 || cfg.Height != 8 {
+		t.Errorf("processed blob decodes as %dx%d, %v; want 4x8", cfg.Width, cfg.Height, err)
+	}
+
+	got, err := attachments.GetAttachmentByID(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("GetAttachmentByID: %v", err)
+	}
+	if got.ProcessingStatus != models.ProcessingReady || got.Width != 4 || got.Height != 8 || got.Size != int64(len(stored)) {
+		t.Errorf("attachment = %s %dx%d, %d bytes; want ready 4x8 with the stored size %d", got.ProcessingStatus, got.Width, got.Height, got.Size, len(stored))
+	}
+	if len(got.Thumbnails) != 1 || got.Thumbnails[0].Width != 2 || got.Thumbnails[0].Height != 4 {
+		t.Errorf("thumbnails = %+v, want one of 2x4", got.Thumbnails)
+	}
+}
@@ -0,0 +1,193 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+)
+
+var (
+	jpegMarkerSOI = []byte{0xFF, 0xD8}
+	pngSignature  = []byte("\x89PNG\r\n\x1a\n")
+	exifHeader    = []byte("Exif\x00\x00")
+	xmpHeaders    = [][]byte{
+		[]byte("http://ns.adobe.com/xap/1.0/\x00"),
+		[]byte("http://ns.adobe.com/xmp/extension/\x00"),
+	}
+	pngXMPKeyword = []byte("XML:com.adobe.xmp\x00")
+)
+
+// errMalformed is returned for images whose container cannot be walked safely. Such
+// files are not served, since their location data could not be removed.
+var errMalformed = errors.New("malformed image container")
+
+const tiffTagOrientation = 0x0112
+
+// StripLocation removes location data from an encoded image: the EXIF segment of JPEGs,
+// which holds GPS coordinates alongside camera details, and XMP packets (which may repeat
+// them) in JPEG and PNG files. It returns the cleaned file and the EXIF orientation (1
+// when there is none); the orientation tag goes with the segment, so callers must apply
+// it themselves. Formats without such metadata are returned unchanged.
+func StripLocation(contentType string, data []byte) ([]byte, int, error) {
+	switch contentType {
+	case "image/jpeg":
+		return stripJPEG(data)
+	case "image/png":
+		cleaned, err := stripPNG(data)
+		return cleaned, 1, err
+	default:
+		return data, 1, nil
+	}
+}
+
+// stripJPEG walks the marker segments up to the start of the scan data, dropping EXIF
+// segments once their orientation is read, and XMP segments.
+func stripJPEG(data []byte) ([]byte, int, error) {
+	if !bytes.HasPrefix(data, jpegMarkerSOI) {
+		return nil, 0, errMalformed
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, jpegMarkerSOI...)
+	orientation := 1
+	pos := len(jpegMarkerSOI)
+	for {
+		if pos+2 > len(data) || data[pos] != 0xFF {
+			return nil, 0, errMalformed
+		}
+		marker := data[pos+1]
+		switch {
+		case marker == 0xFF:
+			// Fill byte before a marker.
+			pos++
+			continue
+		case marker == 0xD9 || marker == 0xDA:
+			// End of image, or start of scan: entropy-coded data follows, copy it verbatim.
+			return append(out, data[pos:]...), orientation, nil
+		case marker == 0x01 || (marker >= 0xD0 && marker <= 0xD7):
+			// Markers without a payload.
+			out = append(out, data[pos:pos+2]...)
+			pos += 2
+			continue
+		}
+
+		if pos+4 > len(data) {
+			return nil, 0, errMalformed
+		}
+		length := int(binary.BigEndian.Uint16(data[pos+2:]))
+		end := pos + 2 + length
+		if length < 2 || end > len(data) {
+			return nil, 0, errMalformed
+		}
+		segment := data[pos:end]
+		payload := segment[4:]
+
+		if marker == 0xE1 {
+			if isXMP(payload) {
+				pos = end
+				continue
+			}
+			if bytes.HasPrefix(payload, exifHeader) {
+				o, err := tiffOrientation(payload[len(exifHeader):])
+				if err != nil {
+					return nil, 0, err
+				}
+				if o >= 1 && o <= 8 {
+					orientation = o
+				}
+				pos = end
+				continue
+			}
+		}
+		out = append(out, segment...)
+		pos = end
+	}
+}
+
+func isXMP(payload []byte) bool {
+	for _, h := range xmpHeaders {
+		if bytes.HasPrefix(payload, h) {
+			return true
+		}
+	}
+	return false
+}
+
+// tiffOrientation returns the orientation tag of the first directory of the TIFF
+// structure in tiff, or 0 when there is none.
+func tiffOrientation(tiff []byte) (int, error) {
+	if len(tiff) < 8 {
+		return 0, errMalformed
+	}
+	var order binary.ByteOrder
+	switch string(tiff[:2]) {
+	case "II":
+		order = binary.LittleEndian
+	case "MM":
+		order = binary.BigEndian
+	default:
+		return 0, errMalformed
+	}
+	if order.Uint16(tiff[2:]) != 42 {
+		return 0, errMalformed
+	}
+
+	entries, err := tiffEntries(tiff, order, int(order.Uint32(tiff[4:])))
+	if err != nil {
+		return 0, err
+	}
+	for _, e := range entries {
+		if order.Uint16(e) == tiffTagOrientation {
+			return int(order.Uint16(e[8:])), nil
+		}
+	}
+	return 0, nil
+}
+
+// tiffEntries returns the 12-byte entries of the directory at offset.
+func tiffEntries(tiff []byte, order binary.ByteOrder, offset int) ([][]byte, error) {
+	if offset < 8 || offset+2 > len(tiff) {
+		return nil, errMalformed
+	}
+	count := int(order.Uint16(tiff[offset:]))
+	start := offset + 2
+	if start+count*12 > len(tiff) {
+		return nil, errMalformed
+	}
+	entries := make([][]byte, count)
+	for i := range entries {
+		entries[i] = tiff[start+i*12 : start+(i+1)*12]
+	}
+	return entries, nil
+}
+
+// stripPNG drops eXIf chunks and XMP text chunks.
+func stripPNG(data []byte) ([]byte, error) {
+	if !bytes.HasPrefix(data, pngSignature) {
+		return nil, errMalformed
+	}
+	out := make([]byte, 0, len(data))
+	out = append(out, pngSignature...)
+	pos := len(pngSignature)
+	for pos < len(data) {
+		if pos+8 > len(data) {
+			return nil, errMalformed
+		}
+		length := int(binary.BigEndian.Uint32(data[pos:]))
+		end := pos + 12 + length
+		if length < 0 || end > len(data) {
+			return nil, errMalformed
+		}
+		chunkType := string(data[pos+4 : pos+8])
+		body := data[pos+8 : pos+8+length]
+		drop := chunkType == "eXIf" ||
+			((chunkType == "iTXt" || chunkType == "tEXt" || chunkType == "zTXt") && bytes.HasPrefix(body, pngXMPKeyword))
+		if !drop {
+			out = append(out, data[pos:end]...)
+		}
+		pos = end
+		if chunkType == "IEND" {
+			break
+		}
+	}
+	return out, nil
+}
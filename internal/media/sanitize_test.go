@@ -0,0 +1,88 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image/jpeg"
+	"os"
+	"testing"
+)
+
+// gpsFixture is an 8x4 JPEG whose EXIF segment has orientation 6 (rotate 90° clockwise)
+// and a GPS directory, followed by an XMP packet repeating the latitude.
+const gpsFixture = "testdata/gps_exif.jpg"
+
+func readFixture(t *testing.T) []byte {
+	t.Helper()
+	data, err := os.ReadFile(gpsFixture)
+	if err != nil {
+		t.Fatalf("read fixture: %v", err)
+	}
+	return data
+}
+
+// jpegAppSegments returns the payloads of the APPn segments before the scan data, by marker.
+func jpegAppSegments(t *testing.T, data []byte) map[byte][][]byte {
+	t.Helper()
+	segments := make(map[byte][][]byte)
+	pos := 2
+	for pos+4 <= len(data) && data[pos] == 0xFF {
+		marker := data[pos+1]
+		if marker == 0xDA || marker == 0xD9 {
+			return segments
+		}
+		end := pos + 2 + int(binary.BigEndian.Uint16(data[pos+2:]))
+		if end > len(data) {
+			t.Fatalf("segment %#x runs past the end of the file", marker)
+		}
+		if marker >= 0xE0 && marker <= 0xEF {
+			segments[marker] = append(segments[marker], data[pos+4:end])
+		}
+		pos = end
+	}
+	t.Fatal("no start of scan found")
+	return nil
+}
+
+func TestStripLocationDropsEXIFAndXMP(t *testing.T) {
+	data := readFixture(t)
+	if app1 := jpegAppSegments(t, data)[0xE1]; len(app1) != 2 || !bytes.HasPrefix(app1[0], exifHeader) {
+		t.Fatalf("fixture has APP1 segments %q, want EXIF and XMP", app1)
+	}
+
+	cleaned, orientation, err := StripLocation("image/jpeg", data)
+	if err != nil {
+		t.Fatalf("StripLocation: %v", err)
+	}
+	if orientation != 6 {
+		t.Errorf("orientation = %d, want 6", orientation)
+	}
+	if app1 := jpegAppSegments(t, cleaned)[0xE1]; len(app1) != 0 {
+		t.Errorf("cleaned file still has APP1 segments %q", app1)
+	}
+	if bytes.Contains(cleaned, []byte("GPS")) {
+		t.Error("cleaned file still mentions GPS")
+	}
+	cfg, err := jpeg.DecodeConfig(bytes.NewReader(cleaned))
+	if err != nil || cfg.Width != 8 || cfg.Height != 4 {
+		t.Errorf("cleaned file decodes as %dx%d, %v; want the 8x4 original", cfg.Width, cfg.Height, err)
+	}
+}
+
+func TestStripLocationRejectsMalformedEXIF(t *testing.T) {
+	data := readFixture(t)
+	// Point the first directory past the end of the TIFF structure.
+	broken := bytes.Clone(data)
+	tiff := bytes.Index(broken, exifHeader) + len(exifHeader)
+	binary.LittleEndian.PutUint32(broken[tiff+4:], 0xFFFF)
+
+	for name, file := range map[string][]byte{
+		"bad directory offset": broken,
+		"truncated":            data[:len(data)/4],
+		"not a JPEG":           data[2:],
+	} {
+		if _, _, err := StripLocation("image/jpeg", file); err == nil {
+			t.Errorf("%s: StripLocation succeeded, want an error", name)
+		}
+	}
+}
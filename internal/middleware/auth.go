@@ -1,22 +1,28 @@
 package middleware
 
 import (
+	"errors"
+	"log"
 	"net/http"
 	"strings"
 
+	"blinkchat-backend/internal/store"
 	"blinkchat-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 )
 
 const (
 	authorizationHeaderKey  = "Authorization"
 	authorizationTypeBearer = "bearer"
 	authorizationPayloadKey = "userID"
+	sessionPayloadKey       = "sessionID"
 )
 
-// AuthMiddleware returns a Gin middleware that validates bearer tokens.
-func AuthMiddleware() gin.HandlerFunc {
+// AuthMiddleware returns a Gin middleware that validates bearer tokens and
+// rejects tokens whose session has been revoked.
+func AuthMiddleware(sessionStore store.SessionStore) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		authHeader := c.GetHeader(authorizationHeaderKey)
 
@@ -44,7 +50,28 @@ func AuthMiddleware() gin.HandlerFunc {
 			return
 		}
 
+		sessionID, err := uuid.Parse(claims.SessionID)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token", "details": "token has no session"})
+			return
+		}
+		session, err := sessionStore.GetSession(c.Request.Context(), sessionID)
+		if err != nil {
+			if !errors.Is(err, store.ErrSessionNotFound) {
+				log.Printf("AuthMiddleware: Failed to load session %s: %v", sessionID, err)
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to verify session"})
+				return
+			}
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			return
+		}
+		if !session.IsActive() || session.UserID.String() != claims.UserID {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Session has been revoked"})
+			return
+		}
+
 		c.Set(authorizationPayloadKey, claims.UserID)
+		c.Set(sessionPayloadKey, claims.SessionID)
 
 		c.Next()
 	}
@@ -0,0 +1,38 @@
+package middleware
+
+import (
+	"net/http"
+	"strconv"
+
+	"blinkchat-backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+// RateLimitKey picks the bucket a request is charged to.
+type RateLimitKey func(c *gin.Context) string
+
+// ByClientIP charges requests to the caller's IP address.
+func ByClientIP(c *gin.Context) string {
+	return c.ClientIP()
+}
+
+// ByUser charges requests to the authenticated user; it must run after AuthMiddleware.
+func ByUser(c *gin.Context) string {
+	return c.GetString(authorizationPayloadKey)
+}
+
+// RateLimit returns a Gin middleware that rejects requests over the named policy with
+// 429 Too Many Requests and a Retry-After header.
+func RateLimit(limiter *ratelimit.Limiter, policyName string, key RateLimitKey) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		result := limiter.Allow(c.Request.Context(), policyName, key(c))
+		if !result.Allowed {
+			retryAfter := result.RetryAfterSeconds()
+			c.Header("Retry-After", strconv.Itoa(retryAfter))
+			c.AbortWithStatusJSON(http.StatusTooManyRequests, gin.H{"error": "Too many requests, please slow down", "retryAfter": retryAfter})
+			return
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,75 @@
+package middleware
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin"
+)
+
+func newRateLimitedRouter(store ratelimit.Store) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	limiter := ratelimit.NewLimiter(store, map[string]ratelimit.Policy{"test": {Burst: 2, Period: 2 * time.Minute}})
+	router := gin.New()
+	router.GET("/", RateLimit(limiter, "test", ByClientIP), func(c *gin.Context) { c.Status(http.StatusNoContent) })
+	return router
+}
+
+func get(router *gin.Engine, ip string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.RemoteAddr = ip + ":4321"
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+func TestRateLimitRejectsWithRetryAfter(t *testing.T) {
+	router := newRateLimitedRouter(ratelimit.NewMemoryStore())
+
+	for i := 0; i < 2; i++ {
+		if w := get(router, "192.0.2.1"); w.Code != http.StatusNoContent {
+			t.Fatalf("request %d within the burst: status %d, want 204", i+1, w.Code)
+		}
+	}
+	w := get(router, "192.0.2.1")
+	if w.Code != http.StatusTooManyRequests {
+		t.Fatalf("request over the burst: status %d, want 429", w.Code)
+	}
+	// The next token is a minute away.
+	if got := w.Header().Get("Retry-After"); got != "60" {
+		t.Errorf("Retry-After = %q, want 60", got)
+	}
+	var body struct {
+		RetryAfter int `json:"retryAfter"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil || body.RetryAfter != 60 {
+		t.Errorf("body %s: retryAfter %d, %v; want 60", w.Body, body.RetryAfter, err)
+	}
+
+	if w := get(router, "198.51.100.7"); w.Code != http.StatusNoContent {
+		t.Errorf("another client IP: status %d, want 204", w.Code)
+	}
+}
+
+// downStore is a ratelimit.Store that always fails.
+type downStore struct{}
+
+func (downStore) Take(ctx context.Context, key string, policy ratelimit.Policy, now time.Time) (ratelimit.Result, error) {
+	return ratelimit.Result{}, errors.New("connection refused")
+}
+
+func TestRateLimitFailsOpen(t *testing.T) {
+	router := newRateLimitedRouter(downStore{})
+	for i := 0; i < 5; i++ {
+		if w := get(router, "192.0.2.1"); w.Code != http.StatusNoContent {
+			t.Fatalf("request %d with the store down: status %d, want 204", i+1, w.Code)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+-- Databases set up by hand before migrations existed already have these tables; the
+-- baseline leaves them alone so that they can be brought under migration in place.
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+CREATE TABLE IF NOT EXISTS users (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    username        TEXT        NOT NULL,
+    email           TEXT        NOT NULL,
+    hashed_password TEXT        NOT NULL,
+    created_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    CONSTRAINT users_username_key UNIQUE (username),
+    CONSTRAINT users_email_key UNIQUE (email)
+);
+
+CREATE TABLE IF NOT EXISTS chats (
+    id         UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS chat_participants (
+    chat_id    UUID        NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (chat_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_chat_participants_user_id ON chat_participants (user_id);
+
+CREATE TABLE IF NOT EXISTS messages (
+    id         UUID PRIMARY KEY,
+    chat_id    UUID        NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    sender_id  UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    content    TEXT        NOT NULL,
+    status     TEXT        NOT NULL DEFAULT 'sent' CHECK (status IN ('sent', 'delivered', 'read')),
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS idx_messages_chat_id_created_at ON messages (chat_id, created_at DESC);
@@ -0,0 +1,6 @@
+ALTER TABLE chat_participants DROP COLUMN IF EXISTS role;
+
+ALTER TABLE chats
+    DROP COLUMN IF EXISTS created_by,
+    DROP COLUMN IF EXISTS is_group,
+    DROP COLUMN IF EXISTS name;
@@ -0,0 +1,7 @@
+ALTER TABLE chats
+    ADD COLUMN name       TEXT    NOT NULL DEFAULT '',
+    ADD COLUMN is_group   BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN created_by UUID    REFERENCES users (id) ON DELETE SET NULL;
+
+ALTER TABLE chat_participants
+    ADD COLUMN role TEXT NOT NULL DEFAULT 'member' CHECK (role IN ('member', 'admin'));
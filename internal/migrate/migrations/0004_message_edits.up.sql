@@ -0,0 +1,12 @@
+ALTER TABLE messages
+    ADD COLUMN edited_at  TIMESTAMPTZ,
+    ADD COLUMN deleted_at TIMESTAMPTZ;
+
+CREATE TABLE message_edits (
+    id               UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id       UUID        NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    previous_content TEXT        NOT NULL,
+    edited_at        TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_message_edits_message_id ON message_edits (message_id, edited_at);
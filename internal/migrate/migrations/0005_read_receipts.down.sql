@@ -0,0 +1,7 @@
+ALTER TABLE chat_participants
+    DROP COLUMN IF EXISTS read_updated_at,
+    DROP COLUMN IF EXISTS last_read_message_at,
+    DROP COLUMN IF EXISTS last_read_message_id,
+    DROP COLUMN IF EXISTS delivered_updated_at,
+    DROP COLUMN IF EXISTS last_delivered_message_at,
+    DROP COLUMN IF EXISTS last_delivered_message_id;
@@ -0,0 +1,33 @@
+ALTER TABLE chat_participants
+    ADD COLUMN last_delivered_message_id UUID REFERENCES messages (id) ON DELETE SET NULL,
+    ADD COLUMN last_delivered_message_at TIMESTAMPTZ,
+    ADD COLUMN delivered_updated_at      TIMESTAMPTZ,
+    ADD COLUMN last_read_message_id      UUID REFERENCES messages (id) ON DELETE SET NULL,
+    ADD COLUMN last_read_message_at      TIMESTAMPTZ,
+    ADD COLUMN read_updated_at           TIMESTAMPTZ;
+
+-- Seed the per-user pointers from the old single status column: every participant is
+-- assumed to have read/received up to the newest message from someone else marked as such.
+UPDATE chat_participants cp
+SET last_read_message_id = latest.id,
+    last_read_message_at = latest.created_at,
+    read_updated_at      = latest.updated_at
+FROM (
+    SELECT DISTINCT ON (cp2.chat_id, cp2.user_id) cp2.chat_id, cp2.user_id, m.id, m.created_at, m.updated_at
+    FROM chat_participants cp2
+    JOIN messages m ON m.chat_id = cp2.chat_id AND m.sender_id != cp2.user_id AND m.status = 'read'
+    ORDER BY cp2.chat_id, cp2.user_id, m.created_at DESC
+) latest
+WHERE cp.chat_id = latest.chat_id AND cp.user_id = latest.user_id;
+
+UPDATE chat_participants cp
+SET last_delivered_message_id = latest.id,
+    last_delivered_message_at = latest.created_at,
+    delivered_updated_at      = latest.updated_at
+FROM (
+    SELECT DISTINCT ON (cp2.chat_id, cp2.user_id) cp2.chat_id, cp2.user_id, m.id, m.created_at, m.updated_at
+    FROM chat_participants cp2
+    JOIN messages m ON m.chat_id = cp2.chat_id AND m.sender_id != cp2.user_id AND m.status IN ('delivered', 'read')
+    ORDER BY cp2.chat_id, cp2.user_id, m.created_at DESC
+) latest
+WHERE cp.chat_id = latest.chat_id AND cp.user_id = latest.user_id;
@@ -0,0 +1,3 @@
+CREATE INDEX IF NOT EXISTS idx_messages_chat_id_created_at ON messages (chat_id, created_at DESC);
+
+DROP INDEX IF EXISTS idx_messages_chat_id_created_at_id;
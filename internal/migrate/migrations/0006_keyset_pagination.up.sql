@@ -0,0 +1,5 @@
+-- Keyset pagination orders messages by (created_at, id); the id breaks ties between
+-- messages stored within the same microsecond.
+CREATE INDEX idx_messages_chat_id_created_at_id ON messages (chat_id, created_at DESC, id DESC);
+
+DROP INDEX IF EXISTS idx_messages_chat_id_created_at;
@@ -0,0 +1,12 @@
+-- Append-only log of realtime chat events, replayed to clients that reconnect.
+CREATE TABLE chat_events (
+    seq        BIGSERIAL PRIMARY KEY,
+    chat_id    UUID        NOT NULL REFERENCES chats (id) ON DELETE CASCADE,
+    message_id UUID,
+    event_type TEXT        NOT NULL,
+    payload    JSONB       NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_chat_events_chat_id_seq ON chat_events (chat_id, seq);
+CREATE INDEX idx_chat_events_message_id ON chat_events (message_id) WHERE message_id IS NOT NULL;
@@ -0,0 +1,3 @@
+ALTER TABLE users
+    ADD COLUMN last_seen_at   TIMESTAMPTZ,
+    ADD COLUMN hide_last_seen BOOLEAN NOT NULL DEFAULT FALSE;
@@ -0,0 +1,20 @@
+-- Audit trail of rejected login attempts.
+CREATE TABLE failed_logins (
+    id           UUID PRIMARY KEY,
+    email        TEXT        NOT NULL,
+    user_id      UUID        REFERENCES users (id) ON DELETE SET NULL,
+    ip_address   TEXT        NOT NULL DEFAULT '',
+    user_agent   TEXT        NOT NULL DEFAULT '',
+    reason       TEXT        NOT NULL,
+    attempted_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_failed_logins_user_id ON failed_logins (user_id, attempted_at) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_failed_logins_attempted_at ON failed_logins (attempted_at);
+
+-- Recent failure counters per account ("account:<email>") and client IP ("ip:<addr>").
+CREATE TABLE login_throttles (
+    key             TEXT PRIMARY KEY,
+    failures        INTEGER     NOT NULL,
+    last_failure_at TIMESTAMPTZ NOT NULL
+);
@@ -0,0 +1,18 @@
+ALTER TABLE users ADD COLUMN email_verified_at TIMESTAMPTZ;
+
+-- Accounts created before verification existed keep working when it becomes mandatory.
+UPDATE users SET email_verified_at = created_at;
+
+-- Single-use tokens mailed to users for email verification and password reset.
+CREATE TABLE action_tokens (
+    id         UUID PRIMARY KEY,
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    purpose    TEXT        NOT NULL,
+    token_hash TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ NOT NULL,
+    used_at    TIMESTAMPTZ,
+    CONSTRAINT action_tokens_token_hash_key UNIQUE (token_hash)
+);
+
+CREATE INDEX idx_action_tokens_user_id_purpose ON action_tokens (user_id, purpose);
@@ -0,0 +1,2 @@
+-- Deleted accounts stay behind as anonymised tombstones so that their messages keep a sender.
+ALTER TABLE users ADD COLUMN deleted_at TIMESTAMPTZ;
@@ -0,0 +1,5 @@
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+-- Case-insensitive prefix and trigram (fuzzy) matching for user search.
+CREATE INDEX idx_users_username_prefix ON users (lower(username) text_pattern_ops);
+CREATE INDEX idx_users_username_trgm ON users USING gin (lower(username) gin_trgm_ops);
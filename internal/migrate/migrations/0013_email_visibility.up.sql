@@ -0,0 +1,4 @@
+-- Who may see a user's email address: 'everyone', 'contacts' (people sharing a chat) or 'nobody'.
+ALTER TABLE users
+    ADD COLUMN email_visibility TEXT NOT NULL DEFAULT 'nobody',
+    ADD CONSTRAINT users_email_visibility_check CHECK (email_visibility IN ('everyone', 'contacts', 'nobody'));
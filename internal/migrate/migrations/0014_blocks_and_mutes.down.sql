@@ -0,0 +1,2 @@
+ALTER TABLE chat_participants DROP COLUMN IF EXISTS muted;
+DROP TABLE IF EXISTS user_blocks;
@@ -0,0 +1,14 @@
+-- Users a user has blocked. Blocks stop direct messages in both directions.
+CREATE TABLE user_blocks (
+    blocker_id UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    blocked_id UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id != blocked_id)
+);
+
+CREATE INDEX idx_user_blocks_blocked_id ON user_blocks (blocked_id);
+
+-- Muted chats still receive messages but do not count towards the unread badge.
+ALTER TABLE chat_participants
+    ADD COLUMN muted BOOLEAN NOT NULL DEFAULT FALSE;
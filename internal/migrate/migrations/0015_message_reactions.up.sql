@@ -0,0 +1,10 @@
+-- Emoji reactions; each user can add each emoji to a message once.
+CREATE TABLE message_reactions (
+    message_id UUID        NOT NULL REFERENCES messages (id) ON DELETE CASCADE,
+    user_id    UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    emoji      TEXT        NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (message_id, user_id, emoji)
+);
+
+CREATE INDEX idx_message_reactions_message_emoji ON message_reactions (message_id, emoji);
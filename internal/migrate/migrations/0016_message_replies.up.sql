@@ -0,0 +1,5 @@
+-- Replies point at the message they answer, which is always in the same chat.
+ALTER TABLE messages
+    ADD COLUMN reply_to_id UUID REFERENCES messages (id) ON DELETE SET NULL;
+
+CREATE INDEX idx_messages_reply_to_id ON messages (reply_to_id, created_at, id) WHERE reply_to_id IS NOT NULL;
@@ -0,0 +1,15 @@
+-- Uploaded files. An attachment belongs to its uploader until it is sent with a message,
+-- after which members of the message's chat may download it.
+CREATE TABLE attachments (
+    id           UUID PRIMARY KEY,
+    uploader_id  UUID        NOT NULL REFERENCES users (id) ON DELETE CASCADE,
+    message_id   UUID        REFERENCES messages (id) ON DELETE CASCADE,
+    chat_id      UUID        REFERENCES chats (id) ON DELETE CASCADE,
+    blob_key     TEXT        NOT NULL,
+    filename     TEXT        NOT NULL,
+    content_type TEXT        NOT NULL,
+    size_bytes   BIGINT      NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_attachments_message_id ON attachments (message_id) WHERE message_id IS NOT NULL;
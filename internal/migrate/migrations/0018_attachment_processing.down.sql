@@ -0,0 +1,9 @@
+DROP INDEX IF EXISTS idx_attachments_pending;
+
+ALTER TABLE attachments
+    DROP COLUMN IF EXISTS processed_at,
+    DROP COLUMN IF EXISTS thumbnails,
+    DROP COLUMN IF EXISTS blurhash,
+    DROP COLUMN IF EXISTS height,
+    DROP COLUMN IF EXISTS width,
+    DROP COLUMN IF EXISTS processing_status;
@@ -0,0 +1,11 @@
+-- Image metadata filled in by the media processor. processing_status is empty for files
+-- that are not processed, otherwise 'pending', 'ready' or 'failed'.
+ALTER TABLE attachments
+    ADD COLUMN processing_status TEXT        NOT NULL DEFAULT '',
+    ADD COLUMN width             INT,
+    ADD COLUMN height            INT,
+    ADD COLUMN blurhash          TEXT,
+    ADD COLUMN thumbnails        JSONB       NOT NULL DEFAULT '[]',
+    ADD COLUMN processed_at      TIMESTAMPTZ;
+
+CREATE INDEX idx_attachments_pending ON attachments (created_at) WHERE processing_status = 'pending';
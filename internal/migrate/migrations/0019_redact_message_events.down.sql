@@ -0,0 +1,2 @@
+-- Redacted payloads cannot be restored; only the backfilled message IDs are undone.
+UPDATE chat_events SET message_id = NULL WHERE event_type = 'message_edited';
@@ -0,0 +1,31 @@
+-- Edit events are recorded against their message so that later edits and deletions can
+-- redact them. Backfill the ones recorded before.
+UPDATE chat_events
+SET message_id = (payload->>'id')::uuid
+WHERE event_type = 'message_edited' AND message_id IS NULL AND payload ? 'id';
+
+-- Bring the logged copies of edited and deleted messages up to date, as the event stores
+-- now do on every edit and deletion.
+UPDATE chat_events e
+SET payload = CASE
+        WHEN m.deleted_at IS NOT NULL THEN (e.payload - 'attachments')
+            || jsonb_build_object('content', '', 'deleted', true, 'deletedAt', m.deleted_at)
+        ELSE e.payload || jsonb_build_object('content', m.content, 'editedAt', m.edited_at)
+    END
+FROM messages m
+WHERE e.message_id = m.id
+  AND e.payload ? 'content'
+  AND (m.deleted_at IS NOT NULL OR m.edited_at IS NOT NULL);
+
+UPDATE chat_events e
+SET payload = jsonb_set(
+        jsonb_set(e.payload, '{replyTo,content}', to_jsonb(CASE
+            WHEN m.deleted_at IS NOT NULL THEN ''
+            WHEN char_length(m.content) > 200 THEN left(m.content, 200) || '…'
+            ELSE m.content
+        END)),
+        '{replyTo,deleted}', to_jsonb(m.deleted_at IS NOT NULL))
+FROM messages m
+WHERE e.chat_id = m.chat_id
+  AND e.payload->'replyTo'->>'id' = m.id::text
+  AND (m.deleted_at IS NOT NULL OR m.edited_at IS NOT NULL);
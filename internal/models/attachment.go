@@ -0,0 +1,70 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// MaxMessageAttachments bounds how many attachments a single message may carry.
+const MaxMessageAttachments = 10
+
+// ProcessingStatus tracks the media processor's work on an image attachment. Files it does
+// not handle have no status.
+type ProcessingStatus string
+
+const (
+	ProcessingNone    ProcessingStatus = ""
+	ProcessingPending ProcessingStatus = "pending"
+	ProcessingReady   ProcessingStatus = "ready"
+	ProcessingFailed  ProcessingStatus = "failed"
+)
+
+// Attachment is an uploaded file. It is private to its uploader until it is sent with a
+// message; from then on members of the message's chat may download it.
+type Attachment struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	UploaderID  uuid.UUID  `json:"uploaderId" db:"uploader_id"`
+	MessageID   *uuid.UUID `json:"messageId,omitempty" db:"message_id"`
+	ChatID      *uuid.UUID `json:"chatId,omitempty" db:"chat_id"`
+	BlobKey     string     `json:"-" db:"blob_key"`
+	Filename    string     `json:"filename" db:"filename"`
+	ContentType string     `json:"contentType" db:"content_type"`
+	Size        int64      `json:"size" db:"size_bytes"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+
+	// Image metadata, set once ProcessingStatus is ready. Width and Height are as displayed,
+	// after applying the EXIF orientation.
+	ProcessingStatus ProcessingStatus `json:"processingStatus,omitempty" db:"processing_status"`
+	Width            int              `json:"width,omitempty" db:"width"`
+	Height           int              `json:"height,omitempty" db:"height"`
+	Blurhash         string           `json:"blurhash,omitempty" db:"blurhash"`
+	Thumbnails       []*Thumbnail     `json:"thumbnails,omitempty" db:"thumbnails"`
+	ProcessedAt      *time.Time       `json:"processedAt,omitempty" db:"processed_at"`
+
+	// URL is a signed, expiring download link filled in for the response.
+	URL          string     `json:"url,omitempty" db:"-"`
+	URLExpiresAt *time.Time `json:"urlExpiresAt,omitempty" db:"-"`
+}
+
+// Downloadable reports whether the file may be served. Images are held back until the
+// processor has stripped their location data.
+func (a *Attachment) Downloadable() bool {
+	return a.ProcessingStatus == ProcessingNone || a.ProcessingStatus == ProcessingReady
+}
+
+// Thumbnail is a downscaled JPEG rendition of an image attachment, named by the longer
+// edge it was fitted into.
+type Thumbnail struct {
+	MaxEdge int    `json:"maxEdge"`
+	Width   int    `json:"width"`
+	Height  int    `json:"height"`
+	Size    int64  `json:"size"`
+	URL     string `json:"url,omitempty"`
+}
+
+// ThumbnailBlobKey is where the thumbnail of an attachment fitted into maxEdge is stored.
+func ThumbnailBlobKey(attachmentID uuid.UUID, maxEdge int) string {
+	return fmt.Sprintf("thumbnails/%s/%d.jpg", attachmentID, maxEdge)
+}
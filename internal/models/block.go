@@ -0,0 +1,9 @@
+package models
+
+import "time"
+
+// BlockedUser is an entry in the caller's blocked list.
+type BlockedUser struct {
+	User      *PublicUser `json:"user"`
+	BlockedAt time.Time   `json:"blockedAt"`
+}
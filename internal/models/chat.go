@@ -6,27 +6,68 @@ import (
 	"github.com/google/uuid"
 )
 
-// Chat represents a conversation between one or more users.
+// ChatRole is a participant's permission level within a chat.
+type ChatRole string
+
+const (
+	RoleMember ChatRole = "member"
+	RoleAdmin  ChatRole = "admin"
+)
+
+// Chat represents a conversation between one or more users. UnreadCount and Muted are the
+// viewer's own; Muted is the only place clients learn whether to notify for a chat.
 type Chat struct {
 	ID                uuid.UUID     `json:"id" db:"id"`
+	Name              string        `json:"name,omitempty" db:"name"`
+	IsGroup           bool          `json:"isGroup" db:"is_group"`
+	CreatedBy         *uuid.UUID    `json:"createdBy,omitempty" db:"created_by"`
 	CreatedAt         time.Time     `json:"createdAt" db:"created_at"`
 	OtherParticipants []*PublicUser `json:"otherParticipants,omitempty"`
 	LastMessage       *Message      `json:"lastMessage,omitempty"`
-	UnreadCount       int           `json:"unreadCount,omitempty"`
+	UnreadCount       int           `json:"unreadCount"`
+	Muted             bool          `json:"muted"`
+}
+
+// Cursor returns the chat's position in a chat list, which is ordered by last activity:
+// the newest message, or the chat's creation if it has none.
+func (c *Chat) Cursor() Cursor {
+	if c.LastMessage != nil {
+		return Cursor{Timestamp: c.LastMessage.Timestamp, ID: c.ID}
+	}
+	return Cursor{Timestamp: c.CreatedAt, ID: c.ID}
 }
 
 // ChatParticipant links a user to a chat.
 type ChatParticipant struct {
 	ChatID    uuid.UUID `json:"chatId" db:"chat_id"`
 	UserID    uuid.UUID `json:"userId" db:"user_id"`
+	Role      ChatRole  `json:"role" db:"role"`
 	CreatedAt time.Time `json:"createdAt" db:"created_at"`
 }
 
+// ChatMember is a participant together with their profile and role.
+type ChatMember struct {
+	User     *PublicUser `json:"user"`
+	Role     ChatRole    `json:"role"`
+	JoinedAt time.Time   `json:"joinedAt"`
+}
+
 // --- DTOs for Chat operations ---
 
-// CreateChatRequest defines the payload for creating a chat.
+// CreateChatRequest defines the payload for creating a group chat.
 type CreateChatRequest struct {
+	Name           string      `json:"name" binding:"required,min=1,max=100"`
 	ParticipantIDs []uuid.UUID `json:"participantIds" binding:"required,min=1"`
 }
 
+// AddChatMembersRequest lists users to add to a group chat.
+type AddChatMembersRequest struct {
+	UserIDs []uuid.UUID `json:"userIds" binding:"required,min=1"`
+}
+
+// UpdateChatMemberRequest changes a member's role in a group chat.
+type UpdateChatMemberRequest struct {
+	Role ChatRole `json:"role" binding:"required,oneof=member admin"`
+}
+
 // ChatResponse is reserved for future single-chat responses.
@@ -0,0 +1,50 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// ChatEvent is a realtime event recorded in a chat's event log. Seq increases
+// monotonically across all chats and doubles as the client's sync cursor.
+type ChatEvent struct {
+	Seq       int64           `json:"seq" db:"seq"`
+	ChatID    uuid.UUID       `json:"chatId" db:"chat_id"`
+	MessageID *uuid.UUID      `json:"messageId,omitempty" db:"message_id"`
+	Type      string          `json:"type" db:"event_type"`
+	Payload   json.RawMessage `json:"payload" db:"payload"`
+	CreatedAt time.Time       `json:"createdAt" db:"created_at"`
+}
+
+// SyncChatCursor is the last point a client has seen in one chat: either an event seq
+// or, for clients that only kept message history, the ID of the last message.
+type SyncChatCursor struct {
+	ChatID        uuid.UUID  `json:"chatId" binding:"required"`
+	LastSeq       int64      `json:"lastSeq"`
+	LastMessageID *uuid.UUID `json:"lastMessageId,omitempty"`
+}
+
+// SyncRequest asks for every event newer than the given cursors. LastSeq, when set,
+// covers the user's chats that are not listed, including chats created while offline.
+type SyncRequest struct {
+	LastSeq *int64           `json:"lastSeq,omitempty"`
+	Chats   []SyncChatCursor `json:"chats" binding:"dive"`
+}
+
+// SyncChatState is the cursor a client should resume a chat from.
+type SyncChatState struct {
+	ChatID  uuid.UUID `json:"chatId"`
+	LastSeq int64     `json:"lastSeq"`
+	HasMore bool      `json:"hasMore"`
+}
+
+// SyncResult carries the missed events in seq order. When HasMore is set the client
+// should sync again from the returned cursors.
+type SyncResult struct {
+	Events  []*ChatEvent    `json:"events"`
+	Chats   []SyncChatState `json:"chats"`
+	LastSeq int64           `json:"lastSeq"`
+	HasMore bool            `json:"hasMore"`
+}
@@ -0,0 +1,33 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Reasons recorded for rejected login attempts.
+const (
+	FailedLoginUnknownEmail  = "unknown_email"
+	FailedLoginWrongPassword = "wrong_password"
+	FailedLoginLocked        = "locked" // rejected without checking the password
+)
+
+// FailedLogin is an audit record of a rejected login attempt. UserID is set when the
+// email belongs to an account.
+type FailedLogin struct {
+	ID          uuid.UUID  `json:"id" db:"id"`
+	Email       string     `json:"email" db:"email"`
+	UserID      *uuid.UUID `json:"userId,omitempty" db:"user_id"`
+	IPAddress   string     `json:"ipAddress" db:"ip_address"`
+	UserAgent   string     `json:"userAgent" db:"user_agent"`
+	Reason      string     `json:"reason" db:"reason"`
+	AttemptedAt time.Time  `json:"attemptedAt" db:"attempted_at"`
+}
+
+// LoginThrottle counts recent failed logins against one account or client IP.
+type LoginThrottle struct {
+	Key           string    `json:"key" db:"key"`
+	Failures      int       `json:"failures" db:"failures"`
+	LastFailureAt time.Time `json:"lastFailureAt" db:"last_failure_at"`
+}
@@ -2,6 +2,8 @@ package models
 
 import (
 	"time"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 )
@@ -23,14 +25,129 @@ type Message struct {
 	Content   string        `json:"content" db:"content"`
 	Timestamp time.Time     `json:"timestamp" db:"created_at"`
 	Status    MessageStatus `json:"status" db:"status"`
+	EditedAt  *time.Time    `json:"editedAt,omitempty" db:"edited_at"`
+	Deleted   bool          `json:"deleted" db:"-"`
+	DeletedAt *time.Time    `json:"deletedAt,omitempty" db:"deleted_at"`
+	ReplyToID *uuid.UUID    `json:"replyToId,omitempty" db:"reply_to_id"`
 
-	Sender *PublicUser `json:"sender,omitempty" db:"-"`
+	Sender      *PublicUser      `json:"sender,omitempty" db:"-"`
+	ReplyTo     *QuotedMessage   `json:"replyTo,omitempty" db:"-"`
+	ReplyCount  int              `json:"replyCount,omitempty" db:"-"`
+	Reactions   []*ReactionCount `json:"reactions,omitempty" db:"-"`
+	Attachments []*Attachment    `json:"attachments,omitempty" db:"-"`
 }
 
+// Cursor returns the message's position in a chat's history.
+func (m *Message) Cursor() Cursor {
+	return Cursor{Timestamp: m.Timestamp, ID: m.ID}
+}
+
+// quotePreviewRunes bounds the content carried in a QuotedMessage.
+const quotePreviewRunes = 200
+
+// QuotedMessage is the compact preview of a replied-to message embedded in its replies.
+type QuotedMessage struct {
+	ID             uuid.UUID `json:"id"`
+	SenderID       uuid.UUID `json:"senderId"`
+	SenderUsername string    `json:"senderUsername"`
+	Content        string    `json:"content"`
+	Timestamp      time.Time `json:"timestamp"`
+	Deleted        bool      `json:"deleted"`
+}
+
+// NewQuotedMessage builds the preview of a message, shortening long content.
+func NewQuotedMessage(id, senderID uuid.UUID, senderUsername, content string, timestamp time.Time, deleted bool) *QuotedMessage {
+	if runes := []rune(content); len(runes) > quotePreviewRunes {
+		content = string(runes[:quotePreviewRunes]) + "…"
+	}
+	return &QuotedMessage{
+		ID:             id,
+		SenderID:       senderID,
+		SenderUsername: senderUsername,
+		Content:        content,
+		Timestamp:      timestamp,
+		Deleted:        deleted,
+	}
+}
+
+// Quote returns the preview of m shown in replies to it.
+func (m *Message) Quote() *QuotedMessage {
+	var username string
+	if m.Sender != nil {
+		username = m.Sender.Username
+	}
+	return NewQuotedMessage(m.ID, m.SenderID, username, m.Content, m.Timestamp, m.DeletedAt != nil)
+}
+
+// Thread is a message together with a page of its direct replies.
+type Thread struct {
+	Root    *Message        `json:"root"`
+	Replies *Page[*Message] `json:"replies"`
+}
+
+// MessageEdit is a previous revision of an edited message.
+type MessageEdit struct {
+	ID              uuid.UUID `json:"id" db:"id"`
+	MessageID       uuid.UUID `json:"messageId" db:"message_id"`
+	PreviousContent string    `json:"previousContent" db:"previous_content"`
+	EditedAt        time.Time `json:"editedAt" db:"edited_at"`
+}
+
+// CreateMessageRequest carries a new message. It needs content, attachments or both.
 type CreateMessageRequest struct {
-	ChatID     *uuid.UUID `json:"chatId,omitempty"`
-	ReceiverID *uuid.UUID `json:"receiverId,omitempty"`
-	Content    string     `json:"content" binding:"required,max=4096"`
+	ChatID        *uuid.UUID  `json:"chatId,omitempty"`
+	ReceiverID    *uuid.UUID  `json:"receiverId,omitempty"`
+	ReplyToID     *uuid.UUID  `json:"replyToId,omitempty"`
+	Content       string      `json:"content" binding:"max=4096"`
+	AttachmentIDs []uuid.UUID `json:"attachmentIds,omitempty" binding:"max=10"`
+}
+
+// MessageReceipt describes how far one recipient has got with a message.
+type MessageReceipt struct {
+	User        *PublicUser   `json:"user"`
+	Status      MessageStatus `json:"status"`
+	DeliveredAt *time.Time    `json:"deliveredAt,omitempty"`
+	ReadAt      *time.Time    `json:"readAt,omitempty"`
+}
+
+// UpdateMessageRequest carries the new content of an edited message.
+type UpdateMessageRequest struct {
+	Content string `json:"content" binding:"required,max=4096"`
+}
+
+// ReactionCount aggregates the reactions to a message with one emoji.
+type ReactionCount struct {
+	Emoji       string `json:"emoji"`
+	Count       int    `json:"count"`
+	ReactedByMe bool   `json:"reactedByMe"`
+}
+
+// ReactionRequest names the emoji to add to or remove from a message.
+type ReactionRequest struct {
+	Emoji string `json:"emoji" binding:"required,max=64"`
+}
+
+// maxReactionRunes bounds an emoji's length; ZWJ sequences such as family emoji run to
+// about a dozen code points.
+const maxReactionRunes = 16
+
+// ValidReactionEmoji reports whether s looks like a single emoji rather than text: it is
+// short, has no letters, spaces or control characters, and contains at least one
+// non-ASCII symbol.
+func ValidReactionEmoji(s string) bool {
+	if s == "" || !utf8.ValidString(s) || utf8.RuneCountInString(s) > maxReactionRunes {
+		return false
+	}
+	hasSymbol := false
+	for _, r := range s {
+		if unicode.IsLetter(r) || unicode.IsSpace(r) || unicode.IsControl(r) {
+			return false
+		}
+		if r > unicode.MaxASCII {
+			hasSymbol = true
+		}
+	}
+	return hasSymbol
 }
 
 // MessageAcknowledgementRequest captures status updates for a message.
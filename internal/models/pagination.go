@@ -0,0 +1,95 @@
+package models
+
+import (
+	"encoding/base64"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Cursor identifies a position in a list ordered by (timestamp, id). Clients treat the
+// encoded form as opaque.
+type Cursor struct {
+	Timestamp time.Time
+	ID        uuid.UUID
+}
+
+// Encode returns the opaque string form of the cursor.
+func (c Cursor) Encode() string {
+	raw := strconv.FormatInt(c.Timestamp.UnixNano(), 10) + ":" + c.ID.String()
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+// ParseCursor decodes a cursor produced by Cursor.Encode.
+func ParseCursor(s string) (*Cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor encoding")
+	}
+	nanos, id, ok := strings.Cut(string(raw), ":")
+	if !ok {
+		return nil, fmt.Errorf("invalid cursor format")
+	}
+	ts, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor timestamp")
+	}
+	cursorID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid cursor ID")
+	}
+	return &Cursor{Timestamp: time.Unix(0, ts).UTC(), ID: cursorID}, nil
+}
+
+// Less reports whether c sorts before other in (timestamp, id) order.
+func (c Cursor) Less(other Cursor) bool {
+	if !c.Timestamp.Equal(other.Timestamp) {
+		return c.Timestamp.Before(other.Timestamp)
+	}
+	return c.ID.String() < other.ID.String()
+}
+
+// CursorPage requests up to Limit items older than Before or newer than After. With
+// neither set the page starts at the newest item.
+type CursorPage struct {
+	Limit  int
+	Before *Cursor
+	After  *Cursor
+}
+
+// Forward reports whether the page walks towards newer items.
+func (p CursorPage) Forward() bool {
+	return p.After != nil
+}
+
+// Page is one page of a cursor-paginated list. Items are always newest first; NextCursor
+// continues in the direction that was requested.
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"nextCursor,omitempty"`
+	HasMore    bool   `json:"hasMore"`
+}
+
+// NewPage builds a page from up to Limit+1 items fetched in walking order: newest first
+// for backward pages, oldest first for forward ones.
+func NewPage[T any](items []T, req CursorPage, cursorOf func(T) Cursor) *Page[T] {
+	page := &Page[T]{Items: items}
+	if len(page.Items) > req.Limit {
+		page.Items = page.Items[:req.Limit]
+		page.HasMore = true
+	}
+	if len(page.Items) > 0 {
+		page.NextCursor = cursorOf(page.Items[len(page.Items)-1]).Encode()
+	}
+	if req.Forward() {
+		slices.Reverse(page.Items)
+	}
+	if page.Items == nil {
+		page.Items = make([]T, 0)
+	}
+	return page
+}
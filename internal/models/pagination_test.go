@@ -0,0 +1,119 @@
+package models
+
+import (
+	"encoding/base64"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	id := uuid.New()
+	for _, ts := range []time.Time{
+		time.Date(2024, 5, 1, 12, 30, 45, 123456789, time.UTC),
+		time.Date(2024, 5, 1, 14, 30, 45, 0, time.FixedZone("CEST", 2*60*60)),
+		time.Unix(0, 0).UTC(),
+		time.Date(1969, 7, 20, 20, 17, 0, 0, time.UTC),
+	} {
+		encoded := Cursor{Timestamp: ts, ID: id}.Encode()
+		got, err := ParseCursor(encoded)
+		if err != nil {
+			t.Fatalf("ParseCursor(%q) for %v: %v", encoded, ts, err)
+		}
+		if !got.Timestamp.Equal(ts) || got.ID != id {
+			t.Errorf("round trip of %v/%s = %v/%s", ts, id, got.Timestamp, got.ID)
+		}
+	}
+}
+
+func TestParseCursorRejectsMalformed(t *testing.T) {
+	valid := Cursor{Timestamp: time.Now(), ID: uuid.New()}.Encode()
+	encode := func(raw string) string { return base64.RawURLEncoding.EncodeToString([]byte(raw)) }
+	for name, s := range map[string]string{
+		"empty":            "",
+		"not base64":       "not a cursor!",
+		"padded base64":    base64.URLEncoding.EncodeToString([]byte("1:" + uuid.NewString())),
+		"truncated":        valid[:len(valid)-6],
+		"no separator":     encode("1714566645000000000"),
+		"bad timestamp":    encode("yesterday:" + uuid.NewString()),
+		"bad ID":           encode("1714566645000000000:not-a-uuid"),
+		"timestamp only":   encode("1714566645000000000:"),
+		"overflowing time": encode("99999999999999999999:" + uuid.NewString()),
+	} {
+		if got, err := ParseCursor(s); err == nil {
+			t.Errorf("%s: ParseCursor(%q) = %+v, want an error", name, s, got)
+		}
+	}
+}
+
+func TestCursorLessBreaksTiesByID(t *testing.T) {
+	at := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	low := uuid.MustParse("00000000-0000-0000-0000-000000000001")
+	high := uuid.MustParse("ffffffff-0000-0000-0000-000000000000")
+	for _, tt := range []struct {
+		name string
+		a, b Cursor
+		want bool
+	}{
+		{"earlier timestamp", Cursor{at, high}, Cursor{at.Add(time.Nanosecond), low}, true},
+		{"later timestamp", Cursor{at.Add(time.Nanosecond), low}, Cursor{at, high}, false},
+		{"tie, lower ID", Cursor{at, low}, Cursor{at, high}, true},
+		{"tie, higher ID", Cursor{at, high}, Cursor{at, low}, false},
+		{"same position", Cursor{at, low}, Cursor{at, low}, false},
+		{"same instant in another zone", Cursor{at.In(time.FixedZone("X", 3600)), low}, Cursor{at, high}, true},
+	} {
+		if got := tt.a.Less(tt.b); got != tt.want {
+			t.Errorf("%s: Less = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestNewPage(t *testing.T) {
+	base := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	cursors := make([]Cursor, 4)
+	for i := range cursors {
+		cursors[i] = Cursor{Timestamp: base.Add(time.Duration(i) * time.Second), ID: uuid.New()}
+	}
+	newestFirst := []Cursor{cursors[3], cursors[2], cursors[1], cursors[0]}
+	oldestFirst := cursors
+	id := func(c Cursor) Cursor { return c }
+
+	for _, tt := range []struct {
+		name     string
+		items    []Cursor
+		req      CursorPage
+		want     []Cursor
+		hasMore  bool
+		nextFrom *Cursor
+	}{
+		{"backward with more", newestFirst, CursorPage{Limit: 3}, newestFirst[:3], true, &cursors[1]},
+		{"backward, exactly full", newestFirst[:3], CursorPage{Limit: 3}, newestFirst[:3], false, &cursors[1]},
+		{"forward with more", oldestFirst, CursorPage{Limit: 3, After: &Cursor{}}, newestFirst[1:], true, &cursors[2]},
+		{"empty", nil, CursorPage{Limit: 3}, nil, false, nil},
+	} {
+		page := NewPage(slices.Clone(tt.items), tt.req, id)
+		if page.Items == nil {
+			t.Errorf("%s: Items is nil, want an empty list so it encodes as []", tt.name)
+		}
+		if len(page.Items) != len(tt.want) {
+			t.Fatalf("%s: got %d items, want %d", tt.name, len(page.Items), len(tt.want))
+		}
+		for i := range tt.want {
+			if page.Items[i] != tt.want[i] {
+				t.Errorf("%s: item %d = %v, want %v (newest first)", tt.name, i, page.Items[i], tt.want[i])
+			}
+		}
+		if page.HasMore != tt.hasMore {
+			t.Errorf("%s: HasMore = %v, want %v", tt.name, page.HasMore, tt.hasMore)
+		}
+		wantNext := ""
+		if tt.nextFrom != nil {
+			wantNext = tt.nextFrom.Encode()
+		}
+		if page.NextCursor != wantNext {
+			t.Errorf("%s: NextCursor = %q, want %q", tt.name, page.NextCursor, wantNext)
+		}
+	}
+}
@@ -0,0 +1,42 @@
+package models
+
+import (
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// Presence is a user's online status as shown to other users. LastSeenAt is only set
+// while the user is offline and has not hidden it.
+type Presence struct {
+	UserID     uuid.UUID  `json:"userId"`
+	Online     bool       `json:"online"`
+	LastSeenAt *time.Time `json:"lastSeenAt,omitempty"`
+}
+
+// UserSettings holds a user's privacy preferences.
+type UserSettings struct {
+	HideLastSeen    bool       `json:"hideLastSeen"`
+	EmailVisibility Visibility `json:"emailVisibility"`
+}
+
+// UpdateUserSettingsRequest captures settings changes; omitted fields are left unchanged.
+type UpdateUserSettingsRequest struct {
+	HideLastSeen    *bool       `json:"hideLastSeen"`
+	EmailVisibility *Visibility `json:"emailVisibility" binding:"omitempty,oneof=everyone contacts nobody"`
+}
+
+// Settings returns the user's current settings.
+func (u *User) Settings() UserSettings {
+	return UserSettings{HideLastSeen: u.HideLastSeen, EmailVisibility: u.EmailVisibility}
+}
+
+// PresenceFor returns the user's presence as seen by viewerID. Users always see their own
+// last-seen time, even when they hide it from others.
+func (u *User) PresenceFor(viewerID uuid.UUID, online bool) *Presence {
+	presence := &Presence{UserID: u.ID, Online: online}
+	if !online && (!u.HideLastSeen || viewerID == u.ID) {
+		presence.LastSeenAt = u.LastSeenAt
+	}
+	return presence
+}
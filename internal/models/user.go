@@ -8,31 +8,106 @@ import (
 
 // User represents an application user.
 type User struct {
-	ID             uuid.UUID `json:"id" db:"id"`
-	Username       string    `json:"username" db:"username"`
-	Email          string    `json:"email" db:"email"`
-	HashedPassword string    `json:"-" db:"hashed_password"`
-	CreatedAt      time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt      time.Time `json:"updatedAt" db:"updated_at"`
+	ID             uuid.UUID  `json:"id" db:"id"`
+	Username       string     `json:"username" db:"username"`
+	Email          string     `json:"email" db:"email"`
+	HashedPassword string     `json:"-" db:"hashed_password"`
+	CreatedAt      time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt      time.Time  `json:"updatedAt" db:"updated_at"`
+	LastSeenAt     *time.Time `json:"lastSeenAt,omitempty" db:"last_seen_at"`
+	HideLastSeen   bool       `json:"hideLastSeen" db:"hide_last_seen"`
+
+	EmailVerifiedAt *time.Time `json:"emailVerifiedAt,omitempty" db:"email_verified_at"`
+	DeletedAt       *time.Time `json:"-" db:"deleted_at"`
+	EmailVisibility Visibility `json:"emailVisibility" db:"email_visibility"`
+}
+
+// EmailVerified reports whether the user has confirmed their email address.
+func (u *User) EmailVerified() bool {
+	return u.EmailVerifiedAt != nil
+}
+
+// Relationship is how a viewer relates to the user whose profile they look at.
+type Relationship int
+
+const (
+	RelationshipStranger Relationship = iota
+	RelationshipContact               // shares at least one chat with the user
+	RelationshipSelf
+)
+
+// Visibility is a per-field privacy setting naming who may see the field.
+type Visibility string
+
+const (
+	VisibilityEveryone Visibility = "everyone"
+	VisibilityContacts Visibility = "contacts"
+	VisibilityNobody   Visibility = "nobody"
+)
+
+// Allows reports whether a viewer with the given relationship may see the field.
+func (v Visibility) Allows(rel Relationship) bool {
+	switch v {
+	case VisibilityEveryone:
+		return true
+	case VisibilityContacts:
+		return rel >= RelationshipContact
+	default:
+		return rel == RelationshipSelf
+	}
 }
 
-// PublicUser is the safe representation returned via APIs.
+// PublicUser is a user as seen by other users. Email is only set when the user's
+// EmailVisibility lets the viewer see it.
 type PublicUser struct {
 	ID        uuid.UUID `json:"id"`
 	Username  string    `json:"username"`
-	Email     string    `json:"email"`
+	Email     string    `json:"email,omitempty"`
 	CreatedAt time.Time `json:"createdAt"`
 	UpdatedAt time.Time `json:"updatedAt"`
 }
 
+// SelfUser is the caller's own account.
+type SelfUser struct {
+	ID              uuid.UUID  `json:"id"`
+	Username        string     `json:"username"`
+	Email           string     `json:"email"`
+	EmailVerified   bool       `json:"emailVerified"`
+	EmailVisibility Visibility `json:"emailVisibility"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// ToPublicUser returns the profile a stranger sees.
 func (u *User) ToPublicUser() *PublicUser {
-	return &PublicUser{
+	return u.ProfileFor(RelationshipStranger)
+}
+
+// ProfileFor returns the profile a viewer with the given relationship sees.
+func (u *User) ProfileFor(rel Relationship) *PublicUser {
+	p := &PublicUser{
 		ID:        u.ID,
 		Username:  u.Username,
-		Email:     u.Email,
 		CreatedAt: u.CreatedAt,
 		UpdatedAt: u.UpdatedAt,
 	}
+	if u.EmailVisibility.Allows(rel) {
+		p.Email = u.Email
+	}
+	return p
+}
+
+// ToSelfUser returns the user's view of their own account.
+func (u *User) ToSelfUser() *SelfUser {
+	return &SelfUser{
+		ID:              u.ID,
+		Username:        u.Username,
+		Email:           u.Email,
+		EmailVerified:   u.EmailVerified(),
+		EmailVisibility: u.EmailVisibility,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+	}
 }
 
 // CreateUserRequest captures registration input.
@@ -47,3 +122,47 @@ type LoginUserRequest struct {
 	Email    string `json:"email" binding:"required,email"`
 	Password string `json:"password" binding:"required,min=6,max=72"`
 }
+
+// VerifyEmailRequest carries an email verification token.
+type VerifyEmailRequest struct {
+	Token string `json:"token" binding:"required"`
+}
+
+// EmailRequest names the account a verification or password reset mail is sent to.
+type EmailRequest struct {
+	Email string `json:"email" binding:"required,email"`
+}
+
+// ResetPasswordRequest sets a new password using a password reset token.
+type ResetPasswordRequest struct {
+	Token    string `json:"token" binding:"required"`
+	Password string `json:"password" binding:"required,min=6,max=72"`
+}
+
+// UpdateProfileRequest changes the caller's username and/or email. Changing the email
+// requires the current password.
+type UpdateProfileRequest struct {
+	Username        *string `json:"username" binding:"omitempty,min=3,max=50"`
+	Email           *string `json:"email" binding:"omitempty,email"`
+	CurrentPassword string  `json:"currentPassword"`
+}
+
+// ChangePasswordRequest replaces the caller's password.
+type ChangePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword" binding:"required"`
+	NewPassword     string `json:"newPassword" binding:"required,min=6,max=72"`
+}
+
+// DeleteAccountRequest confirms account deletion with the current password.
+type DeleteAccountRequest struct {
+	Password string `json:"password" binding:"required"`
+}
+
+// UserSearch asks for users matching Query on behalf of SearcherID, who is never part of
+// the results. Limit and Offset page through the ranked matches.
+type UserSearch struct {
+	Query      string
+	SearcherID uuid.UUID
+	Limit      int
+	Offset     int
+}
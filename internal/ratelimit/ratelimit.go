@@ -0,0 +1,124 @@
+// Package ratelimit enforces named token-bucket policies on REST routes and WebSocket
+// message types. Buckets live in a Store so that replicas can share them.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Policy names used by the router and the WebSocket hub.
+const (
+	PolicyAuth      = "auth"       // login, registration and token refresh, per client IP
+	PolicyAPI       = "api"        // every authenticated REST request, per user
+	PolicyMessages  = "messages"   // sending messages over REST, per user
+	PolicySearch    = "search"     // user search, per user
+	PolicyUploads   = "uploads"    // attachment uploads, per user
+	PolicyWSConnect = "ws_connect" // opening WebSocket connections, per client IP
+	PolicyWSDefault = "ws_default" // WebSocket frames without a policy of their own, per user
+)
+
+// WSPolicy returns the policy name for a WebSocket message type.
+func WSPolicy(msgType string) string {
+	return "ws_" + msgType
+}
+
+// Policy is a token bucket holding up to Burst tokens that refills at Burst per Period.
+// A zero Policy is disabled and allows everything.
+type Policy struct {
+	Burst  int
+	Period time.Duration
+}
+
+// ParsePolicy reads a "N/duration" spec such as "10/1m", or "off".
+func ParsePolicy(spec string) (Policy, error) {
+	spec = strings.TrimSpace(spec)
+	if strings.EqualFold(spec, "off") {
+		return Policy{}, nil
+	}
+	burstStr, periodStr, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Policy{}, fmt.Errorf("rate limit %q is not of the form N/duration", spec)
+	}
+	burst, err := strconv.Atoi(burstStr)
+	if err != nil || burst <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q needs a positive request count", spec)
+	}
+	period, err := time.ParseDuration(periodStr)
+	if err != nil || period <= 0 {
+		return Policy{}, fmt.Errorf("rate limit %q needs a positive duration", spec)
+	}
+	// Buckets refill one token per Period/Burst, which must not round down to nothing.
+	if period < time.Duration(burst) {
+		return Policy{}, fmt.Errorf("rate limit %q allows more than one request per nanosecond", spec)
+	}
+	return Policy{Burst: burst, Period: period}, nil
+}
+
+// Enabled reports whether the policy limits anything.
+func (p Policy) Enabled() bool {
+	return p.Burst > 0 && p.Period > 0
+}
+
+func (p Policy) String() string {
+	if !p.Enabled() {
+		return "off"
+	}
+	return fmt.Sprintf("%d/%s", p.Burst, p.Period)
+}
+
+// Result is the outcome of taking a token.
+type Result struct {
+	Allowed    bool
+	Remaining  int
+	RetryAfter time.Duration // until a token is available again; zero when allowed
+}
+
+// RetryAfterSeconds rounds RetryAfter up to whole seconds, as used by Retry-After headers.
+func (r Result) RetryAfterSeconds() int {
+	return int(math.Ceil(r.RetryAfter.Seconds()))
+}
+
+// Store keeps token buckets by key. MemoryStore serves a single process; a shared
+// implementation (e.g. Redis) makes replicas enforce one budget per key.
+type Store interface {
+	Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error)
+}
+
+// Limiter applies named policies on top of a Store.
+type Limiter struct {
+	store    Store
+	policies map[string]Policy
+}
+
+// NewLimiter returns a Limiter enforcing the given policies.
+func NewLimiter(store Store, policies map[string]Policy) *Limiter {
+	return &Limiter{store: store, policies: policies}
+}
+
+// Policy returns the named policy and whether it is configured at all, even as "off".
+func (l *Limiter) Policy(name string) (Policy, bool) {
+	policy, ok := l.policies[name]
+	return policy, ok
+}
+
+// Allow takes a token from key's bucket under the named policy. Unknown and disabled
+// policies allow everything, and so do store failures: a broken limiter should not
+// take the API down with it.
+func (l *Limiter) Allow(ctx context.Context, policyName, key string) Result {
+	policy, ok := l.Policy(policyName)
+	if !ok || !policy.Enabled() {
+		return Result{Allowed: true}
+	}
+	result, err := l.store.Take(ctx, policyName+":"+key, policy, time.Now())
+	if err != nil {
+		log.Printf("ratelimit: Error taking token for %s (%s): %v", policyName, key, err)
+		return Result{Allowed: true}
+	}
+	return result
+}
@@ -0,0 +1,103 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestParsePolicy(t *testing.T) {
+	for _, tt := range []struct {
+		spec    string
+		want    Policy
+		wantErr bool
+	}{
+		{spec: "10/1m", want: Policy{Burst: 10, Period: time.Minute}},
+		{spec: " 30/10s ", want: Policy{Burst: 30, Period: 10 * time.Second}},
+		{spec: "off", want: Policy{}},
+		{spec: "OFF", want: Policy{}},
+		{spec: "5/5ns", want: Policy{Burst: 5, Period: 5}},
+		{spec: "10", wantErr: true},
+		{spec: "0/1m", wantErr: true},
+		{spec: "-1/1m", wantErr: true},
+		{spec: "x/1m", wantErr: true},
+		{spec: "10/0s", wantErr: true},
+		{spec: "10/-1s", wantErr: true},
+		{spec: "10/soon", wantErr: true},
+		// Period/Burst would be zero, and the bucket would divide by it.
+		{spec: "10/5ns", wantErr: true},
+	} {
+		got, err := ParsePolicy(tt.spec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParsePolicy(%q) = %v, %v; want %v, error %v", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestMemoryStoreRefillsAndCapsBurst(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore()
+	policy := Policy{Burst: 3, Period: 3 * time.Second} // one token a second
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, step := range []struct {
+		at         time.Duration
+		key        string
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}{
+		{at: 0, key: "a", allowed: true, remaining: 2},
+		{at: 0, key: "a", allowed: true, remaining: 1},
+		{at: 0, key: "a", allowed: true, remaining: 0},
+		{at: 0, key: "a", retryAfter: time.Second},
+		{at: 0, key: "b", allowed: true, remaining: 2},
+		{at: 500 * time.Millisecond, key: "a", retryAfter: 500 * time.Millisecond},
+		{at: time.Second, key: "a", allowed: true, remaining: 0},
+		// A long pause refills the bucket only up to the burst.
+		{at: time.Hour, key: "a", allowed: true, remaining: 2},
+	} {
+		got, err := s.Take(ctx, step.key, policy, start.Add(step.at))
+		if err != nil {
+			t.Fatalf("step %d: Take: %v", i, err)
+		}
+		if got.Allowed != step.allowed || got.Remaining != step.remaining || got.RetryAfter != step.retryAfter {
+			t.Errorf("step %d: Take(%s at +%v) = %+v, want allowed %v, remaining %d, retry after %v",
+				i, step.key, step.at, got, step.allowed, step.remaining, step.retryAfter)
+		}
+	}
+}
+
+func TestRetryAfterSecondsRoundsUp(t *testing.T) {
+	for _, tt := range []struct {
+		after time.Duration
+		want  int
+	}{{0, 0}, {time.Millisecond, 1}, {time.Second, 1}, {1500 * time.Millisecond, 2}} {
+		if got := (Result{RetryAfter: tt.after}).RetryAfterSeconds(); got != tt.want {
+			t.Errorf("RetryAfterSeconds(%v) = %d, want %d", tt.after, got, tt.want)
+		}
+	}
+}
+
+// failingStore is a Store that is down.
+type failingStore struct{ takes int }
+
+func (s *failingStore) Take(ctx context.Context, key string, policy Policy, now time.Time) (Result, error) {
+	s.takes++
+	return Result{}, errors.New("store unavailable")
+}
+
+func TestLimiterAllowsWhenItCannotLimit(t *testing.T) {
+	store := &failingStore{}
+	l := NewLimiter(store, map[string]Policy{"on": {Burst: 1, Period: time.Minute}, "off": {}})
+
+	for _, policy := range []string{"on", "off", "unknown"} {
+		if got := l.Allow(context.Background(), policy, "key"); !got.Allowed {
+			t.Errorf("Allow under %q with a failing store = %+v, want allowed", policy, got)
+		}
+	}
+	if store.takes != 1 {
+		t.Errorf("store consulted %d times, want once, for the only enabled policy", store.takes)
+	}
+}
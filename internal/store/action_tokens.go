@@ -0,0 +1,109 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// ActionTokenStore persists the single-use tokens behind email verification and password reset.
+type ActionTokenStore interface {
+	// CreateActionToken stores a token, invalidating the user's earlier unused tokens
+	// for the same purpose.
+	CreateActionToken(ctx context.Context, token *models.ActionToken) error
+	// ConsumeActionToken marks the token with the given purpose and hash as used and returns it.
+	ConsumeActionToken(ctx context.Context, purpose string, tokenHash string) (*models.ActionToken, error)
+}
+
+// PostgresActionTokenStore implements ActionTokenStore with PostgreSQL.
+type PostgresActionTokenStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresActionTokenStore returns a Postgres-backed ActionTokenStore implementation.
+func NewPostgresActionTokenStore(db *pgxpool.Pool) *PostgresActionTokenStore {
+	return &PostgresActionTokenStore{db: db}
+}
+
+func (s *PostgresActionTokenStore) CreateActionToken(ctx context.Context, token *models.ActionToken) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	invalidateQuery := `
+        UPDATE action_tokens SET used_at = $3
+        WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL
+    `
+	if _, err = tx.Exec(ctx, invalidateQuery, token.UserID, token.Purpose, token.CreatedAt); err != nil {
+		return fmt.Errorf("failed to invalidate %s tokens of user %s: %w", token.Purpose, token.UserID, err)
+	}
+
+	insertQuery := `
+        INSERT INTO action_tokens (id, user_id, purpose, token_hash, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5, $6)
+    `
+	_, err = tx.Exec(ctx, insertQuery, token.ID, token.UserID, token.Purpose, token.TokenHash, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to store %s token for user %s: %w", token.Purpose, token.UserID, err)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresActionTokenStore) ConsumeActionToken(ctx context.Context, purpose string, tokenHash string) (*models.ActionToken, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+        SELECT id, user_id, purpose, token_hash, created_at, expires_at, used_at
+        FROM action_tokens
+        WHERE token_hash = $1 AND purpose = $2
+        FOR UPDATE
+    `
+	token := &models.ActionToken{}
+	err = tx.QueryRow(ctx, query, tokenHash, purpose).Scan(
+		&token.ID, &token.UserID, &token.Purpose, &token.TokenHash,
+		&token.CreatedAt, &token.ExpiresAt, &token.UsedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrActionTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to look up %s token: %w", purpose, err)
+	}
+	if token.UsedAt != nil {
+		return nil, ErrActionTokenUsed
+	}
+	now := time.Now()
+	if now.After(token.ExpiresAt) {
+		return nil, ErrActionTokenExpired
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE action_tokens SET used_at = $2 WHERE id = $1`, token.ID, now); err != nil {
+		return nil, fmt.Errorf("failed to consume %s token: %w", purpose, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	token.UsedAt = &now
+	return token, nil
+}
+
+var (
+	ErrActionTokenNotFound = fmt.Errorf("token not found")
+	ErrActionTokenUsed     = fmt.Errorf("token has already been used")
+	ErrActionTokenExpired  = fmt.Errorf("token has expired")
+)
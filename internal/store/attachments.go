@@ -0,0 +1,217 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// AttachmentStore persists the metadata of uploaded files; their contents live in a
+// blob.BlobStore under BlobKey. Attachments are tied to a message by
+// MessageStore.CreateMessage.
+type AttachmentStore interface {
+	CreateAttachment(ctx context.Context, attachment *models.Attachment) error
+	GetAttachmentByID(ctx context.Context, attachmentID uuid.UUID) (*models.Attachment, error)
+	// GetAttachmentsByIDs returns the attachments that exist among ids, in no particular order.
+	GetAttachmentsByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Attachment, error)
+	// GetMessageAttachments returns the attachments of each of the messages, in upload
+	// order. Messages without attachments are left out.
+	GetMessageAttachments(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]*models.Attachment, error)
+
+	// GetPendingAttachmentIDs lists the attachments awaiting the media processor, oldest first.
+	GetPendingAttachmentIDs(ctx context.Context) ([]uuid.UUID, error)
+	// UpdateAttachmentProcessing stores the processing status, size and image metadata of
+	// a and returns the attachment as now stored, including the message it was sent with
+	// in the meantime.
+	UpdateAttachmentProcessing(ctx context.Context, a *models.Attachment) (*models.Attachment, error)
+}
+
+// PostgresAttachmentStore implements AttachmentStore with PostgreSQL.
+type PostgresAttachmentStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresAttachmentStore returns a Postgres-backed AttachmentStore implementation.
+func NewPostgresAttachmentStore(db *pgxpool.Pool) *PostgresAttachmentStore {
+	return &PostgresAttachmentStore{db: db}
+}
+
+const attachmentColumns = `id, uploader_id, message_id, chat_id, blob_key, filename, content_type, size_bytes, created_at,
+        processing_status, width, height, blurhash, thumbnails, processed_at`
+
+func (s *PostgresAttachmentStore) CreateAttachment(ctx context.Context, a *models.Attachment) error {
+	query := `
+        INSERT INTO attachments (id, uploader_id, blob_key, filename, content_type, size_bytes, created_at, processing_status)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+    `
+	_, err := s.db.Exec(ctx, query, a.ID, a.UploaderID, a.BlobKey, a.Filename, a.ContentType, a.Size, a.CreatedAt, a.ProcessingStatus)
+	if err != nil {
+		return fmt.Errorf("failed to create attachment: %w", err)
+	}
+	return nil
+}
+
+func (s *PostgresAttachmentStore) GetAttachmentByID(ctx context.Context, attachmentID uuid.UUID) (*models.Attachment, error) {
+	a, err := scanAttachment(s.db.QueryRow(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE id = $1`, attachmentID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("failed to get attachment by ID: %w", err)
+	}
+	return a, nil
+}
+
+func (s *PostgresAttachmentStore) GetAttachmentsByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Attachment, error) {
+	if len(ids) == 0 {
+		return make([]*models.Attachment, 0), nil
+	}
+	rows, err := s.db.Query(ctx, `SELECT `+attachmentColumns+` FROM attachments WHERE id = ANY($1)`, ids)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query attachments: %w", err)
+	}
+	return scanAttachments(rows)
+}
+
+func (s *PostgresAttachmentStore) GetMessageAttachments(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]*models.Attachment, error) {
+	byMessage := make(map[uuid.UUID][]*models.Attachment)
+	if len(messageIDs) == 0 {
+		return byMessage, nil
+	}
+	query := `SELECT ` + attachmentColumns + ` FROM attachments
+        WHERE message_id = ANY($1)
+        ORDER BY created_at ASC, id ASC`
+	rows, err := s.db.Query(ctx, query, messageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message attachments: %w", err)
+	}
+	attachments, err := scanAttachments(rows)
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range attachments {
+		byMessage[*a.MessageID] = append(byMessage[*a.MessageID], a)
+	}
+	return byMessage, nil
+}
+
+func (s *PostgresAttachmentStore) GetPendingAttachmentIDs(ctx context.Context) ([]uuid.UUID, error) {
+	query := `
+        SELECT id FROM attachments
+        WHERE processing_status = $1
+        ORDER BY created_at ASC
+    `
+	rows, err := s.db.Query(ctx, query, models.ProcessingPending)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pending attachments: %w", err)
+	}
+	defer rows.Close()
+
+	ids := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan pending attachment row: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating pending attachment rows: %w", err)
+	}
+	return ids, nil
+}
+
+func (s *PostgresAttachmentStore) UpdateAttachmentProcessing(ctx context.Context, a *models.Attachment) (*models.Attachment, error) {
+	thumbnails, err := json.Marshal(storedThumbnails(a.Thumbnails))
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnails of attachment %s: %w", a.ID, err)
+	}
+	var width, height *int
+	var blurhash *string
+	if a.Width > 0 && a.Height > 0 {
+		width, height = &a.Width, &a.Height
+	}
+	if a.Blurhash != "" {
+		blurhash = &a.Blurhash
+	}
+	query := `
+        UPDATE attachments
+        SET processing_status = $2, size_bytes = $3, width = $4, height = $5, blurhash = $6,
+            thumbnails = $7, processed_at = $8
+        WHERE id = $1
+        RETURNING ` + attachmentColumns
+	updated, err := scanAttachment(s.db.QueryRow(ctx, query,
+		a.ID, a.ProcessingStatus, a.Size, width, height, blurhash, thumbnails, a.ProcessedAt))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrAttachmentNotFound
+		}
+		return nil, fmt.Errorf("failed to update processing of attachment %s: %w", a.ID, err)
+	}
+	return updated, nil
+}
+
+// storedThumbnails copies thumbnails without their per-response download links.
+func storedThumbnails(thumbnails []*models.Thumbnail) []*models.Thumbnail {
+	stored := make([]*models.Thumbnail, len(thumbnails))
+	for i, t := range thumbnails {
+		cp := *t
+		cp.URL = ""
+		stored[i] = &cp
+	}
+	return stored
+}
+
+func scanAttachment(row pgx.Row) (*models.Attachment, error) {
+	var a models.Attachment
+	var width, height *int
+	var blurhash *string
+	var thumbnails []byte
+	err := row.Scan(&a.ID, &a.UploaderID, &a.MessageID, &a.ChatID, &a.BlobKey, &a.Filename, &a.ContentType, &a.Size, &a.CreatedAt,
+		&a.ProcessingStatus, &width, &height, &blurhash, &thumbnails, &a.ProcessedAt)
+	if err != nil {
+		return nil, err
+	}
+	if width != nil && height != nil {
+		a.Width, a.Height = *width, *height
+	}
+	if blurhash != nil {
+		a.Blurhash = *blurhash
+	}
+	if err := json.Unmarshal(thumbnails, &a.Thumbnails); err != nil {
+		return nil, fmt.Errorf("failed to decode thumbnails of attachment %s: %w", a.ID, err)
+	}
+	if len(a.Thumbnails) == 0 {
+		a.Thumbnails = nil
+	}
+	return &a, nil
+}
+
+func scanAttachments(rows pgx.Rows) ([]*models.Attachment, error) {
+	defer rows.Close()
+	attachments := make([]*models.Attachment, 0)
+	for rows.Next() {
+		a, err := scanAttachment(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan attachment row: %w", err)
+		}
+		attachments = append(attachments, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating attachment rows: %w", err)
+	}
+	return attachments, nil
+}
+
+var (
+	ErrAttachmentNotFound = fmt.Errorf("attachment not found")
+	// ErrAttachmentUnavailable is returned by CreateMessage when an attachment was not
+	// uploaded by the sender or has already been sent with another message.
+	ErrAttachmentUnavailable = fmt.Errorf("attachment is not available to this message")
+)
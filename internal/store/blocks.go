@@ -0,0 +1,125 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// BlockStore persists the users each user has blocked.
+type BlockStore interface {
+	// BlockUser records that blockerID blocked blockedID; blocking twice is a no-op.
+	BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID, at time.Time) error
+	// UnblockUser lifts a block; lifting one that does not exist is a no-op.
+	UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error
+	// GetBlockedUsers returns the users blockerID has blocked, most recent first.
+	GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]*models.BlockedUser, error)
+	// IsBlockedBetween reports whether either user has blocked the other.
+	IsBlockedBetween(ctx context.Context, userID, otherID uuid.UUID) (bool, error)
+	// GetBlockedPeerIDs returns every user that userID has blocked or been blocked by.
+	GetBlockedPeerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+}
+
+// PostgresBlockStore implements BlockStore with PostgreSQL.
+type PostgresBlockStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresBlockStore returns a Postgres-backed BlockStore implementation.
+func NewPostgresBlockStore(db *pgxpool.Pool) *PostgresBlockStore {
+	return &PostgresBlockStore{db: db}
+}
+
+func (s *PostgresBlockStore) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID, at time.Time) error {
+	query := `
+        INSERT INTO user_blocks (blocker_id, blocked_id, created_at)
+        VALUES ($1, $2, $3)
+        ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+    `
+	if _, err := s.db.Exec(ctx, query, blockerID, blockedID, at); err != nil {
+		return fmt.Errorf("failed to block user %s for user %s: %w", blockedID, blockerID, err)
+	}
+	return nil
+}
+
+func (s *PostgresBlockStore) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	query := `DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`
+	if _, err := s.db.Exec(ctx, query, blockerID, blockedID); err != nil {
+		return fmt.Errorf("failed to unblock user %s for user %s: %w", blockedID, blockerID, err)
+	}
+	return nil
+}
+
+func (s *PostgresBlockStore) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]*models.BlockedUser, error) {
+	query := `
+        SELECT u.id, u.username, u.email, u.email_visibility, u.created_at, u.updated_at, b.created_at
+        FROM user_blocks b
+        JOIN users u ON u.id = b.blocked_id
+        WHERE b.blocker_id = $1
+        ORDER BY b.created_at DESC, u.id
+    `
+	rows, err := s.db.Query(ctx, query, blockerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query users blocked by %s: %w", blockerID, err)
+	}
+	defer rows.Close()
+
+	blocked := make([]*models.BlockedUser, 0)
+	for rows.Next() {
+		var u models.User
+		var blockedAt time.Time
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.EmailVisibility, &u.CreatedAt, &u.UpdatedAt, &blockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked user row: %w", err)
+		}
+		blocked = append(blocked, &models.BlockedUser{User: u.ToPublicUser(), BlockedAt: blockedAt})
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocked user rows: %w", err)
+	}
+	return blocked, nil
+}
+
+func (s *PostgresBlockStore) IsBlockedBetween(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	query := `
+        SELECT EXISTS (
+            SELECT 1 FROM user_blocks
+            WHERE (blocker_id = $1 AND blocked_id = $2) OR (blocker_id = $2 AND blocked_id = $1)
+        )
+    `
+	var blocked bool
+	if err := s.db.QueryRow(ctx, query, userID, otherID).Scan(&blocked); err != nil {
+		return false, fmt.Errorf("failed to check blocks between users %s and %s: %w", userID, otherID, err)
+	}
+	return blocked, nil
+}
+
+func (s *PostgresBlockStore) GetBlockedPeerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+        SELECT blocked_id FROM user_blocks WHERE blocker_id = $1
+        UNION
+        SELECT blocker_id FROM user_blocks WHERE blocked_id = $1
+    `
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query blocked peers of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	peerIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var peerID uuid.UUID
+		if err := rows.Scan(&peerID); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked peer of user %s: %w", userID, err)
+		}
+		peerIDs = append(peerIDs, peerID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocked peer rows of user %s: %w", userID, err)
+	}
+	return peerIDs, nil
+}
@@ -21,9 +21,22 @@ type ChatStore interface {
 	GetChatByID(ctx context.Context, chatID uuid.UUID) (*models.Chat, error)
 	GetChatByParticipantIDs(ctx context.Context, participantIDs []uuid.UUID) (*models.Chat, error)
 	GetUserChats(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Chat, error)
+	GetUserChatsPage(ctx context.Context, userID uuid.UUID, page models.CursorPage) (*models.Page[*models.Chat], error)
 	AddUserToChat(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) error
 	RemoveUserFromChat(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) error
 	GetAllParticipantsInChat(ctx context.Context, chatID uuid.UUID) ([]*models.PublicUser, error)
+	// GetChatPartnerIDs returns every other user who shares at least one chat with userID.
+	GetChatPartnerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error)
+	// SharesChat reports whether the two users are members of at least one common chat.
+	SharesChat(ctx context.Context, userID, otherID uuid.UUID) (bool, error)
+
+	CreateGroupChat(ctx context.Context, name string, creatorID uuid.UUID, memberIDs []uuid.UUID) (*models.Chat, error)
+	GetChatMembers(ctx context.Context, chatID uuid.UUID) ([]*models.ChatMember, error)
+	GetParticipantRole(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (models.ChatRole, error)
+	IsParticipant(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (bool, error)
+	SetParticipantRole(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, role models.ChatRole) error
+	// SetChatMuted mutes or unmutes a chat for one participant.
+	SetChatMuted(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, muted bool) error
 }
 
 // PostgresChatStore implements ChatStore with PostgreSQL.
@@ -67,9 +80,39 @@ func (s *PostgresChatStore) CreateChat(ctx context.Context, participantIDs []uui
 	return createdChat, nil
 }
 
+// CreateGroupChat creates a named group chat with the creator as its first admin.
+func (s *PostgresChatStore) CreateGroupChat(ctx context.Context, name string, creatorID uuid.UUID, memberIDs []uuid.UUID) (*models.Chat, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	chat := &models.Chat{Name: name, IsGroup: true, CreatedBy: &creatorID}
+	chatQuery := `INSERT INTO chats (name, is_group, created_by, created_at) VALUES ($1, TRUE, $2, NOW()) RETURNING id, created_at`
+	err = tx.QueryRow(ctx, chatQuery, name, creatorID).Scan(&chat.ID, &chat.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create group chat entry: %w", err)
+	}
+
+	participantQuery := `INSERT INTO chat_participants (chat_id, user_id, role, created_at) VALUES ($1, $2, $3, NOW()) ON CONFLICT DO NOTHING`
+	if _, err = tx.Exec(ctx, participantQuery, chat.ID, creatorID, models.RoleAdmin); err != nil {
+		return nil, fmt.Errorf("failed to add creator %s to group chat %s: %w", creatorID, chat.ID, err)
+	}
+	for _, userID := range memberIDs {
+		if _, err = tx.Exec(ctx, participantQuery, chat.ID, userID, models.RoleMember); err != nil {
+			return nil, fmt.Errorf("failed to add participant %s to group chat %s: %w", userID, chat.ID, err)
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return chat, nil
+}
+
 func (s *PostgresChatStore) getChatParticipantsInternal(ctx context.Context, chatID uuid.UUID) ([]*models.PublicUser, error) {
 	query := `
-        SELECT u.id, u.username, u.email, u.created_at, u.updated_at
+        SELECT u.id, u.username, CASE WHEN u.email_visibility = 'nobody' THEN '' ELSE u.email END, u.created_at, u.updated_at
         FROM users u
         JOIN chat_participants cp ON u.id = cp.user_id
         WHERE cp.chat_id = $1
@@ -98,10 +141,52 @@ func (s *PostgresChatStore) GetAllParticipantsInChat(ctx context.Context, chatID
 	return s.getChatParticipantsInternal(ctx, chatID)
 }
 
+func (s *PostgresChatStore) GetChatPartnerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+        SELECT DISTINCT other.user_id
+        FROM chat_participants mine
+        JOIN chat_participants other ON other.chat_id = mine.chat_id AND other.user_id != mine.user_id
+        WHERE mine.user_id = $1
+    `
+	rows, err := s.db.Query(ctx, query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query chat partners of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+	partnerIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var partnerID uuid.UUID
+		if err := rows.Scan(&partnerID); err != nil {
+			return nil, fmt.Errorf("failed to scan chat partner of user %s: %w", userID, err)
+		}
+		partnerIDs = append(partnerIDs, partnerID)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat partner rows of user %s: %w", userID, err)
+	}
+	return partnerIDs, nil
+}
+
+func (s *PostgresChatStore) SharesChat(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	query := `
+        SELECT EXISTS (
+            SELECT 1
+            FROM chat_participants mine
+            JOIN chat_participants other ON other.chat_id = mine.chat_id
+            WHERE mine.user_id = $1 AND other.user_id = $2
+        )
+    `
+	var shares bool
+	if err := s.db.QueryRow(ctx, query, userID, otherID).Scan(&shares); err != nil {
+		return false, fmt.Errorf("failed to check shared chats of users %s and %s: %w", userID, otherID, err)
+	}
+	return shares, nil
+}
+
 func (s *PostgresChatStore) GetChatByID(ctx context.Context, chatID uuid.UUID) (*models.Chat, error) {
-	query := `SELECT id, created_at FROM chats WHERE id = $1`
+	query := `SELECT id, name, is_group, created_by, created_at FROM chats WHERE id = $1`
 	chat := &models.Chat{}
-	err := s.db.QueryRow(ctx, query, chatID).Scan(&chat.ID, &chat.CreatedAt)
+	err := s.db.QueryRow(ctx, query, chatID).Scan(&chat.ID, &chat.Name, &chat.IsGroup, &chat.CreatedBy, &chat.CreatedAt)
 	if err != nil {
 		if err == pgx.ErrNoRows {
 			return nil, ErrChatNotFound
@@ -124,7 +209,7 @@ func (s *PostgresChatStore) GetChatByParticipantIDs(ctx context.Context, partici
 	query := `
 		SELECT c.id, c.created_at
 		FROM chats c
-		WHERE EXISTS (
+		WHERE c.is_group = FALSE AND EXISTS (
 			SELECT 1 FROM chat_participants cp1 WHERE cp1.chat_id = c.id AND cp1.user_id = $1
 		) AND EXISTS (
 			SELECT 1 FROM chat_participants cp2 WHERE cp2.chat_id = c.id AND cp2.user_id = $2
@@ -147,9 +232,40 @@ func (s *PostgresChatStore) GetChatByParticipantIDs(ctx context.Context, partici
 }
 
 func (s *PostgresChatStore) GetUserChats(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Chat, error) {
+	return s.queryUserChats(ctx, userID, `
+ORDER BY activity_at DESC, c.id DESC
+LIMIT $2 OFFSET $3`, limit, offset)
+}
+
+// GetUserChatsPage returns a keyset-paginated page of the user's chats, most recently active first.
+func (s *PostgresChatStore) GetUserChatsPage(ctx context.Context, userID uuid.UUID, page models.CursorPage) (*models.Page[*models.Chat], error) {
+	cursor, op, order := page.Before, "<", "DESC"
+	if page.Forward() {
+		cursor, op, order = page.After, ">", "ASC"
+	}
+	var cursorAt *time.Time
+	var cursorID *uuid.UUID
+	if cursor != nil {
+		cursorAt, cursorID = &cursor.Timestamp, &cursor.ID
+	}
+
+	tail := fmt.Sprintf(`
+WHERE $2::timestamptz IS NULL OR (COALESCE(lm.message_timestamp, c.created_at), c.id) %s ($2, $3::uuid)
+ORDER BY activity_at %s, c.id %s
+LIMIT $4`, op, order, order)
+	chats, err := s.queryUserChats(ctx, userID, tail, cursorAt, cursorID, page.Limit+1)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPage(chats, page, (*models.Chat).Cursor), nil
+}
+
+// queryUserChats lists the user's chats with their other participants, last message and
+// unread count. tail filters, orders and limits the rows; its placeholders start at $2.
+func (s *PostgresChatStore) queryUserChats(ctx context.Context, userID uuid.UUID, tail string, args ...any) ([]*models.Chat, error) {
 	query := `
 WITH user_chat_ids AS (
-    SELECT cp.chat_id
+    SELECT cp.chat_id, cp.last_read_message_at, cp.muted
     FROM chat_participants cp
     WHERE cp.user_id = $1
 ),
@@ -159,7 +275,7 @@ chat_participant_details AS (
         jsonb_agg(jsonb_build_object(
             'id', u.id,
             'username', u.username,
-            'email', u.email,
+            'email', CASE WHEN u.email_visibility = 'nobody' THEN NULL ELSE u.email END,
             'createdAt', u.created_at,
             'updatedAt', u.updated_at
         )) FILTER (WHERE u.id != $1) AS other_participants_json
@@ -176,9 +292,11 @@ ranked_messages AS (
         m.content,
         m.status,
         m.created_at AS message_timestamp,
+        m.edited_at,
+        m.deleted_at,
         u_sender.id AS sender_user_id,
         u_sender.username AS sender_username,
-        u_sender.email AS sender_email,
+        CASE WHEN u_sender.email_visibility = 'nobody' THEN '' ELSE u_sender.email END AS sender_email,
         u_sender.created_at AS sender_user_created_at,
         u_sender.updated_at AS sender_user_updated_at,
         ROW_NUMBER() OVER (PARTITION BY m.chat_id ORDER BY m.created_at DESC) as rn
@@ -190,29 +308,44 @@ last_messages AS (
     SELECT *
     FROM ranked_messages
     WHERE rn = 1
+),
+unread_counts AS (
+    SELECT m.chat_id, COUNT(*) AS unread_count
+    FROM messages m
+    JOIN user_chat_ids uci ON uci.chat_id = m.chat_id
+    WHERE m.sender_id != $1
+      AND m.deleted_at IS NULL
+      AND (uci.last_read_message_at IS NULL OR m.created_at > uci.last_read_message_at)
+    GROUP BY m.chat_id
 )
 SELECT
     c.id AS chat_id,
+    c.name AS chat_name,
+    c.is_group AS chat_is_group,
+    c.created_by AS chat_created_by,
     c.created_at AS chat_created_at,
     cpd.other_participants_json,
     lm.message_id,
     lm.content AS last_message_content,
     lm.message_timestamp AS last_message_timestamp,
     lm.status AS last_message_status,
+    lm.edited_at AS last_message_edited_at,
+    lm.deleted_at AS last_message_deleted_at,
     lm.sender_user_id AS last_message_sender_id,
     lm.sender_username AS last_message_sender_username,
     lm.sender_email AS last_message_sender_email,
     lm.sender_user_created_at AS last_message_sender_created_at,
-    lm.sender_user_updated_at AS last_message_sender_updated_at
+    lm.sender_user_updated_at AS last_message_sender_updated_at,
+    COALESCE(uc.unread_count, 0) AS unread_count,
+    uci.muted,
+    COALESCE(lm.message_timestamp, c.created_at) AS activity_at
 FROM chats c
 JOIN user_chat_ids uci ON c.id = uci.chat_id
 LEFT JOIN chat_participant_details cpd ON c.id = cpd.chat_id
 LEFT JOIN last_messages lm ON c.id = lm.chat_id
-ORDER BY lm.message_timestamp DESC NULLS LAST, c.created_at DESC
-LIMIT $2 OFFSET $3;
-    `
+LEFT JOIN unread_counts uc ON c.id = uc.chat_id` + tail
 
-	rows, err := s.db.Query(ctx, query, userID, limit, offset)
+	rows, err := s.db.Query(ctx, query, append([]any{userID}, args...)...)
 	if err != nil {
 		log.Printf("Error querying user chats for userID %s: %v", userID, err)
 		return nil, fmt.Errorf("failed to query user chats: %w", err)
@@ -223,31 +356,47 @@ LIMIT $2 OFFSET $3;
 
 	for rows.Next() {
 		var chatID uuid.UUID
+		var chatName string
+		var chatIsGroup bool
+		var chatCreatedBy *uuid.UUID
 		var chatCreatedAt time.Time
 		var otherParticipantsJSONBytes []byte
 		var lastMessageID sql.NullString
 		var lastMessageContent sql.NullString
 		var lastMessageTimestamp sql.NullTime
 		var lastMessageStatus sql.NullString
+		var lastMessageEditedAt *time.Time
+		var lastMessageDeletedAt *time.Time
 		var lastMessageSenderID sql.NullString
 		var lastMessageSenderUsername sql.NullString
 		var lastMessageSenderEmail sql.NullString
 		var lastMessageSenderCreatedAt sql.NullTime
 		var lastMessageSenderUpdatedAt sql.NullTime
+		var unreadCount int
+		var muted bool
+		var activityAt time.Time
 
 		err := rows.Scan(
 			&chatID,
+			&chatName,
+			&chatIsGroup,
+			&chatCreatedBy,
 			&chatCreatedAt,
 			&otherParticipantsJSONBytes, // Scan as []byte
 			&lastMessageID,
 			&lastMessageContent,
 			&lastMessageTimestamp,
 			&lastMessageStatus,
+			&lastMessageEditedAt,
+			&lastMessageDeletedAt,
 			&lastMessageSenderID,
 			&lastMessageSenderUsername,
 			&lastMessageSenderEmail,
 			&lastMessageSenderCreatedAt,
 			&lastMessageSenderUpdatedAt,
+			&unreadCount,
+			&muted,
+			&activityAt,
 		)
 		if err != nil {
 			log.Printf("Error scanning user chat row: %v", err)
@@ -255,8 +404,13 @@ LIMIT $2 OFFSET $3;
 		}
 
 		chat := &models.Chat{
-			ID:        chatID,
-			CreatedAt: chatCreatedAt,
+			ID:          chatID,
+			Name:        chatName,
+			IsGroup:     chatIsGroup,
+			CreatedBy:   chatCreatedBy,
+			CreatedAt:   chatCreatedAt,
+			UnreadCount: unreadCount,
+			Muted:       muted,
 		}
 
 		if otherParticipantsJSONBytes != nil {
@@ -287,6 +441,9 @@ LIMIT $2 OFFSET $3;
 					Content:   lastMessageContent.String,
 					Timestamp: lastMessageTimestamp.Time,
 					Status:    models.MessageStatus(lastMessageStatus.String),
+					EditedAt:  lastMessageEditedAt,
+					Deleted:   lastMessageDeletedAt != nil,
+					DeletedAt: lastMessageDeletedAt,
 					Sender: &models.PublicUser{
 						ID:        senderUUID,
 						Username:  lastMessageSenderUsername.String,
@@ -298,7 +455,6 @@ LIMIT $2 OFFSET $3;
 			}
 		}
 
-		chat.UnreadCount = 0
 		chatsSlice = append(chatsSlice, chat)
 	}
 	if err = rows.Err(); err != nil {
@@ -318,7 +474,7 @@ func (s *PostgresChatStore) AddUserToChat(ctx context.Context, chatID uuid.UUID,
 	query := `INSERT INTO chat_participants (chat_id, user_id, created_at) VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING`
 	_, err := s.db.Exec(ctx, query, chatID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to add user %s to chat %s: %w", userID, err)
+		return fmt.Errorf("failed to add user %s to chat %s: %w", userID, chatID, err)
 	}
 	return nil
 }
@@ -327,11 +483,93 @@ func (s *PostgresChatStore) RemoveUserFromChat(ctx context.Context, chatID uuid.
 	query := `DELETE FROM chat_participants WHERE chat_id = $1 AND user_id = $2`
 	_, err := s.db.Exec(ctx, query, chatID, userID)
 	if err != nil {
-		return fmt.Errorf("failed to remove user %s from chat %s: %w", userID, err)
+		return fmt.Errorf("failed to remove user %s from chat %s: %w", userID, chatID, err)
+	}
+	return nil
+}
+
+// GetChatMembers returns every participant of a chat with their role, oldest first.
+func (s *PostgresChatStore) GetChatMembers(ctx context.Context, chatID uuid.UUID) ([]*models.ChatMember, error) {
+	query := `
+        SELECT u.id, u.username, CASE WHEN u.email_visibility = 'nobody' THEN '' ELSE u.email END, u.created_at, u.updated_at, cp.role, cp.created_at
+        FROM chat_participants cp
+        JOIN users u ON u.id = cp.user_id
+        WHERE cp.chat_id = $1
+        ORDER BY cp.created_at ASC, u.id ASC
+    `
+	rows, err := s.db.Query(ctx, query, chatID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query members for chat %s: %w", chatID, err)
+	}
+	defer rows.Close()
+
+	var members []*models.ChatMember
+	for rows.Next() {
+		var u models.PublicUser
+		var m models.ChatMember
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt, &u.UpdatedAt, &m.Role, &m.JoinedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan member for chat %s: %w", chatID, err)
+		}
+		m.User = &u
+		members = append(members, &m)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating member rows for chat %s: %w", chatID, err)
+	}
+	return members, nil
+}
+
+// GetParticipantRole returns the user's role in the chat, or ErrNotParticipant.
+func (s *PostgresChatStore) GetParticipantRole(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (models.ChatRole, error) {
+	query := `SELECT role FROM chat_participants WHERE chat_id = $1 AND user_id = $2`
+	var role models.ChatRole
+	err := s.db.QueryRow(ctx, query, chatID, userID).Scan(&role)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return "", ErrNotParticipant
+		}
+		return "", fmt.Errorf("failed to get role of user %s in chat %s: %w", userID, chatID, err)
+	}
+	return role, nil
+}
+
+// IsParticipant reports whether the user is a member of the chat.
+func (s *PostgresChatStore) IsParticipant(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM chat_participants WHERE chat_id = $1 AND user_id = $2)`
+	var exists bool
+	if err := s.db.QueryRow(ctx, query, chatID, userID).Scan(&exists); err != nil {
+		return false, fmt.Errorf("failed to check membership of user %s in chat %s: %w", userID, chatID, err)
+	}
+	return exists, nil
+}
+
+// SetParticipantRole changes an existing participant's role.
+func (s *PostgresChatStore) SetParticipantRole(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, role models.ChatRole) error {
+	query := `UPDATE chat_participants SET role = $1 WHERE chat_id = $2 AND user_id = $3`
+	result, err := s.db.Exec(ctx, query, role, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set role of user %s in chat %s: %w", userID, chatID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotParticipant
+	}
+	return nil
+}
+
+// SetChatMuted mutes or unmutes a chat for one participant.
+func (s *PostgresChatStore) SetChatMuted(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, muted bool) error {
+	query := `UPDATE chat_participants SET muted = $1 WHERE chat_id = $2 AND user_id = $3`
+	result, err := s.db.Exec(ctx, query, muted, chatID, userID)
+	if err != nil {
+		return fmt.Errorf("failed to set mute of chat %s for user %s: %w", chatID, userID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrNotParticipant
 	}
 	return nil
 }
 
 var (
-	ErrChatNotFound = fmt.Errorf("chat not found")
+	ErrChatNotFound   = fmt.Errorf("chat not found")
+	ErrNotParticipant = fmt.Errorf("user is not a participant of this chat")
 )
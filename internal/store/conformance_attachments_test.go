@@ -0,0 +1,157 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func createAttachment(t *testing.T, s stores, uploaderID uuid.UUID, at time.Time, status models.ProcessingStatus) *models.Attachment {
+	t.Helper()
+	a := &models.Attachment{
+		ID:               uuid.New(),
+		UploaderID:       uploaderID,
+		Filename:         "photo.jpg",
+		ContentType:      "image/jpeg",
+		Size:             1024,
+		CreatedAt:        at,
+		ProcessingStatus: status,
+	}
+	a.BlobKey = "attachments/" + a.ID.String()
+	if err := s.attachments.CreateAttachment(context.Background(), a); err != nil {
+		t.Fatalf("CreateAttachment: %v", err)
+	}
+	return a
+}
+
+func testMessageAttachments(t *testing.T, s stores) {
+	ctx := context.Background()
+	a, b, chatID := newDirectChat(t, s)
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	first := createAttachment(t, s, a, base, models.ProcessingNone)
+	second := createAttachment(t, s, a, base.Add(time.Second), models.ProcessingNone)
+	theirs := createAttachment(t, s, b, base, models.ProcessingNone)
+
+	send := func(attachments ...*models.Attachment) (*models.Message, error) {
+		msg := &models.Message{
+			ID:          uuid.New(),
+			ChatID:      chatID,
+			SenderID:    a,
+			Content:     "with attachments",
+			Timestamp:   base.Add(time.Minute),
+			Status:      models.StatusSent,
+			Attachments: attachments,
+		}
+		return msg, s.messages.CreateMessage(ctx, msg)
+	}
+	msg, err := send(second, first)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	byMessage, err := s.attachments.GetMessageAttachments(ctx, []uuid.UUID{msg.ID, uuid.New()})
+	if err != nil {
+		t.Fatalf("GetMessageAttachments: %v", err)
+	}
+	if len(byMessage) != 1 {
+		t.Errorf("GetMessageAttachments lists %d messages, want only the one with attachments", len(byMessage))
+	}
+	got := byMessage[msg.ID]
+	if len(got) != 2 || got[0].ID != first.ID || got[1].ID != second.ID {
+		t.Fatalf("attachments of the message = %v, want %s then %s in upload order", got, first.ID, second.ID)
+	}
+	for _, att := range got {
+		if att.MessageID == nil || *att.MessageID != msg.ID || att.ChatID == nil || *att.ChatID != chatID {
+			t.Errorf("attachment %s tied to message %v in chat %v, want %s in %s", att.ID, att.MessageID, att.ChatID, msg.ID, chatID)
+		}
+	}
+
+	for _, tt := range []struct {
+		name       string
+		attachment *models.Attachment
+	}{
+		{"already sent", first},
+		{"uploaded by someone else", theirs},
+		{"unknown", &models.Attachment{ID: uuid.New()}},
+	} {
+		// The sender's fresh upload rides along to check that nothing is stored on failure.
+		fresh := createAttachment(t, s, a, base, models.ProcessingNone)
+		rejected, err := send(fresh, tt.attachment)
+		if !errors.Is(err, store.ErrAttachmentUnavailable) {
+			t.Errorf("%s: CreateMessage got %v, want ErrAttachmentUnavailable", tt.name, err)
+			continue
+		}
+		if _, err := s.messages.GetMessageByID(ctx, rejected.ID); !errors.Is(err, store.ErrMessageNotFound) {
+			t.Errorf("%s: rejected message was stored: %v", tt.name, err)
+		}
+		if stored, err := s.attachments.GetAttachmentByID(ctx, fresh.ID); err != nil || stored.MessageID != nil {
+			t.Errorf("%s: fresh upload after the rejected send: %+v, %v; want it still unsent", tt.name, stored, err)
+		}
+	}
+
+	found, err := s.attachments.GetAttachmentsByIDs(ctx, []uuid.UUID{theirs.ID, uuid.New()})
+	if err != nil || len(found) != 1 || found[0].ID != theirs.ID {
+		t.Errorf("GetAttachmentsByIDs = %v, %v; want only %s", found, err, theirs.ID)
+	}
+	if _, err := s.attachments.GetAttachmentByID(ctx, uuid.New()); !errors.Is(err, store.ErrAttachmentNotFound) {
+		t.Errorf("GetAttachmentByID of an unknown ID: got %v, want ErrAttachmentNotFound", err)
+	}
+}
+
+func testAttachmentProcessing(t *testing.T, s stores) {
+	ctx := context.Background()
+	a, _, chatID := newDirectChat(t, s)
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	image := createAttachment(t, s, a, base, models.ProcessingPending)
+	pending, err := s.attachments.GetPendingAttachmentIDs(ctx)
+	if err != nil || !slices.Contains(pending, image.ID) {
+		t.Fatalf("GetPendingAttachmentIDs = %v, %v; want it to list %s", pending, err, image.ID)
+	}
+
+	// The image is sent while the processor works on it.
+	msg := &models.Message{ID: uuid.New(), ChatID: chatID, SenderID: a, Timestamp: base, Status: models.StatusSent, Attachments: []*models.Attachment{image}}
+	if err := s.messages.CreateMessage(ctx, msg); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	processedAt := base.Add(time.Second)
+	updated, err := s.attachments.UpdateAttachmentProcessing(ctx, &models.Attachment{
+		ID:               image.ID,
+		ProcessingStatus: models.ProcessingReady,
+		Size:             900,
+		Width:            640,
+		Height:           480,
+		Blurhash:         "LEHV6nWB2yk8pyo0adR*.7kCMdnj",
+		Thumbnails:       []*models.Thumbnail{{MaxEdge: 320, Width: 320, Height: 240, Size: 100, URL: "https://signed.example"}},
+		ProcessedAt:      &processedAt,
+	})
+	if err != nil {
+		t.Fatalf("UpdateAttachmentProcessing: %v", err)
+	}
+	if updated.MessageID == nil || *updated.MessageID != msg.ID {
+		t.Errorf("updated attachment is tied to %v, want the message %s it was sent with", updated.MessageID, msg.ID)
+	}
+	stored, err := s.attachments.GetAttachmentByID(ctx, image.ID)
+	if err != nil {
+		t.Fatalf("GetAttachmentByID: %v", err)
+	}
+	if stored.ProcessingStatus != models.ProcessingReady || stored.Size != 900 || stored.Width != 640 || stored.Height != 480 ||
+		stored.Blurhash == "" || stored.ProcessedAt == nil || !stored.ProcessedAt.Equal(processedAt) {
+		t.Errorf("stored attachment = %+v, want the processed metadata", stored)
+	}
+	if len(stored.Thumbnails) != 1 || stored.Thumbnails[0].MaxEdge != 320 || stored.Thumbnails[0].URL != "" {
+		t.Errorf("stored thumbnails = %v, want the 320 one without its download link", stored.Thumbnails)
+	}
+	pending, err = s.attachments.GetPendingAttachmentIDs(ctx)
+	if err != nil || slices.Contains(pending, image.ID) {
+		t.Errorf("GetPendingAttachmentIDs = %v, %v; want %s gone once processed", pending, err, image.ID)
+	}
+	if _, err := s.attachments.UpdateAttachmentProcessing(ctx, &models.Attachment{ID: uuid.New()}); !errors.Is(err, store.ErrAttachmentNotFound) {
+		t.Errorf("UpdateAttachmentProcessing of an unknown ID: got %v, want ErrAttachmentNotFound", err)
+	}
+}
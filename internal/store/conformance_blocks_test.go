@@ -0,0 +1,110 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func testBlocks(t *testing.T, s stores) {
+	ctx := context.Background()
+	a, b, c := createUser(t, s), createUser(t, s), createUser(t, s)
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	for _, block := range []struct {
+		blocked uuid.UUID
+		at      time.Time
+	}{{b.ID, base}, {b.ID, base.Add(time.Minute)}, {c.ID, base.Add(time.Second)}} {
+		if err := s.blocks.BlockUser(ctx, a.ID, block.blocked, block.at); err != nil {
+			t.Fatalf("BlockUser: %v", err)
+		}
+	}
+
+	blocked, err := s.blocks.GetBlockedUsers(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("GetBlockedUsers: %v", err)
+	}
+	if len(blocked) != 2 || blocked[0].User.ID != c.ID || blocked[1].User.ID != b.ID {
+		t.Fatalf("GetBlockedUsers = %v, want %s then %s", blocked, c.ID, b.ID)
+	}
+	// Blocking again is a no-op, so the first block's time stands.
+	if !blocked[1].BlockedAt.Equal(base) {
+		t.Errorf("block of %s dated %v, want %v", b.ID, blocked[1].BlockedAt, base)
+	}
+
+	for _, tt := range []struct {
+		user, other uuid.UUID
+		want        bool
+	}{{a.ID, b.ID, true}, {b.ID, a.ID, true}, {b.ID, c.ID, false}} {
+		got, err := s.blocks.IsBlockedBetween(ctx, tt.user, tt.other)
+		if err != nil || got != tt.want {
+			t.Errorf("IsBlockedBetween(%s, %s) = %v, %v; want %v", tt.user, tt.other, got, err, tt.want)
+		}
+	}
+	peers, err := s.blocks.GetBlockedPeerIDs(ctx, a.ID)
+	if err != nil {
+		t.Fatalf("GetBlockedPeerIDs: %v", err)
+	}
+	sortIDs(peers)
+	want := []uuid.UUID{b.ID, c.ID}
+	sortIDs(want)
+	assertIDs(t, "peers of the blocker", peers, want)
+	peers, err = s.blocks.GetBlockedPeerIDs(ctx, b.ID)
+	if err != nil {
+		t.Fatalf("GetBlockedPeerIDs: %v", err)
+	}
+	assertIDs(t, "peers of the blocked", peers, []uuid.UUID{a.ID})
+
+	for i := 0; i < 2; i++ {
+		if err := s.blocks.UnblockUser(ctx, a.ID, b.ID); err != nil {
+			t.Fatalf("UnblockUser #%d: %v", i+1, err)
+		}
+	}
+	if got, err := s.blocks.IsBlockedBetween(ctx, b.ID, a.ID); err != nil || got {
+		t.Errorf("IsBlockedBetween after unblocking = %v, %v; want false", got, err)
+	}
+	blocked, err = s.blocks.GetBlockedUsers(ctx, a.ID)
+	if err != nil || len(blocked) != 1 || blocked[0].User.ID != c.ID {
+		t.Errorf("GetBlockedUsers after unblocking = %v, %v; want only %s", blocked, err, c.ID)
+	}
+}
+
+func testChatMute(t *testing.T, s stores) {
+	ctx := context.Background()
+	a, b, chatID := newDirectChat(t, s)
+	createMessage(t, s, chatID, b, time.Now().UTC().Truncate(time.Microsecond))
+
+	check := func(what string, muted bool, total int) {
+		t.Helper()
+		page, err := s.chats.GetUserChatsPage(ctx, a, models.CursorPage{Limit: 10})
+		if err != nil {
+			t.Fatalf("GetUserChatsPage: %v", err)
+		}
+		if len(page.Items) != 1 || page.Items[0].Muted != muted || page.Items[0].UnreadCount != 1 {
+			t.Errorf("%s: chat list %+v, want one chat with muted %v and 1 unread", what, page.Items, muted)
+		}
+		got, err := s.messages.GetTotalUnreadCount(ctx, a)
+		if err != nil || got != total {
+			t.Errorf("%s: GetTotalUnreadCount = %d, %v; want %d", what, got, err, total)
+		}
+	}
+	check("before muting", false, 1)
+	if err := s.chats.SetChatMuted(ctx, chatID, a, true); err != nil {
+		t.Fatalf("SetChatMuted: %v", err)
+	}
+	// A muted chat keeps its own unread count but stays out of the total.
+	check("muted", true, 0)
+	if err := s.chats.SetChatMuted(ctx, chatID, a, false); err != nil {
+		t.Fatalf("SetChatMuted: %v", err)
+	}
+	check("unmuted", false, 1)
+
+	if err := s.chats.SetChatMuted(ctx, chatID, uuid.New(), true); !errors.Is(err, store.ErrNotParticipant) {
+		t.Errorf("SetChatMuted by an outsider: got %v, want ErrNotParticipant", err)
+	}
+}
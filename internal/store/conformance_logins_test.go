@@ -0,0 +1,69 @@
+package store_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+func testLoginThrottles(t *testing.T, s stores) {
+	ctx := context.Background()
+	account, ip := "account:"+uuid.NewString()+"@example.com", "ip:"+uuid.NewString()
+	const window = 5 * time.Minute
+	base := time.Now().UTC().Truncate(time.Microsecond)
+
+	record := func(at time.Time, keys []string, want int) {
+		t.Helper()
+		attempt := &models.FailedLogin{
+			ID:          uuid.New(),
+			Email:       "someone@example.com",
+			IPAddress:   "192.0.2.1",
+			Reason:      models.FailedLoginWrongPassword,
+			AttemptedAt: at,
+		}
+		throttles, err := s.logins.RecordFailedLogin(ctx, attempt, keys, window)
+		if err != nil {
+			t.Fatalf("RecordFailedLogin: %v", err)
+		}
+		if len(throttles) != len(keys) {
+			t.Fatalf("RecordFailedLogin returned %d throttles, want %d", len(throttles), len(keys))
+		}
+		for i, th := range throttles {
+			if th.Key != keys[i] || th.Failures != want || !th.LastFailureAt.Equal(at) {
+				t.Errorf("throttle %+v, want %s at %d failures, last at %v", *th, keys[i], want, at)
+			}
+		}
+	}
+	record(base, []string{account, ip}, 1)
+	record(base.Add(window-time.Second), []string{account, ip}, 2)
+	// A gap longer than the window starts the count over.
+	record(base.Add(2*window), []string{account}, 1)
+	// Locked attempts are audited without touching any throttle.
+	record(base.Add(2*window), nil, 0)
+
+	throttles, err := s.logins.GetLoginThrottles(ctx, []string{account, ip, "ip:unknown-" + uuid.NewString()})
+	if err != nil {
+		t.Fatalf("GetLoginThrottles: %v", err)
+	}
+	failures := make(map[string]int)
+	for _, th := range throttles {
+		failures[th.Key] = th.Failures
+	}
+	if len(throttles) != 2 || failures[account] != 1 || failures[ip] != 2 {
+		t.Errorf("GetLoginThrottles = %v, want %s at 1 and %s at 2", failures, account, ip)
+	}
+
+	cleared, err := s.logins.ClearLoginThrottles(ctx, []string{account, "account:unknown-" + uuid.NewString()})
+	if err != nil || cleared != 1 {
+		t.Errorf("ClearLoginThrottles = %d, %v; want 1", cleared, err)
+	}
+	throttles, err = s.logins.GetLoginThrottles(ctx, []string{account, ip})
+	if err != nil || len(throttles) != 1 || throttles[0].Key != ip {
+		t.Errorf("GetLoginThrottles after clearing the account = %v, %v; want only %s", throttles, err, ip)
+	}
+	record(base.Add(2*window+time.Second), []string{account}, 1)
+}
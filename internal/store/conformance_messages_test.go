@@ -0,0 +1,352 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// newDirectChat returns a direct chat between two new users.
+func newDirectChat(t *testing.T, s stores) (a, b uuid.UUID, chatID uuid.UUID) {
+	t.Helper()
+	ua, ub := createUser(t, s), createUser(t, s)
+	chat, err := s.chats.CreateChat(context.Background(), []uuid.UUID{ua.ID, ub.ID})
+	if err != nil {
+		t.Fatalf("CreateChat: %v", err)
+	}
+	return ua.ID, ub.ID, chat.ID
+}
+
+func testMessageEditsAndDeletes(t *testing.T, s stores) {
+	ctx := context.Background()
+	a, _, chatID := newDirectChat(t, s)
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	msg := createMessage(t, s, chatID, a, base)
+	original := msg.Content
+
+	edited, err := s.messages.EditMessage(ctx, msg.ID, "second", base.Add(time.Minute))
+	if err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+	if edited.Content != "second" || edited.EditedAt == nil || !edited.EditedAt.Equal(base.Add(time.Minute)) {
+		t.Errorf("after edit: content %q, editedAt %v; want %q at %v", edited.Content, edited.EditedAt, "second", base.Add(time.Minute))
+	}
+	// Saving unchanged content is not a revision.
+	if _, err := s.messages.EditMessage(ctx, msg.ID, "second", base.Add(2*time.Minute)); err != nil {
+		t.Fatalf("EditMessage with same content: %v", err)
+	}
+	if _, err := s.messages.EditMessage(ctx, msg.ID, "third", base.Add(3*time.Minute)); err != nil {
+		t.Fatalf("EditMessage: %v", err)
+	}
+
+	edits, err := s.messages.GetMessageEdits(ctx, msg.ID)
+	if err != nil {
+		t.Fatalf("GetMessageEdits: %v", err)
+	}
+	var history []string
+	for _, e := range edits {
+		history = append(history, e.PreviousContent)
+	}
+	if len(history) != 2 || history[0] != original || history[1] != "second" {
+		t.Errorf("edit history = %q, want [%q %q], oldest first", history, original, "second")
+	}
+
+	deleted, err := s.messages.DeleteMessage(ctx, msg.ID, base.Add(4*time.Minute))
+	if err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+	if !deleted.Deleted || deleted.Content != "" || deleted.DeletedAt == nil {
+		t.Errorf("after delete: deleted %v, content %q, deletedAt %v; want a tombstone", deleted.Deleted, deleted.Content, deleted.DeletedAt)
+	}
+	if edits, err := s.messages.GetMessageEdits(ctx, msg.ID); err != nil || len(edits) != 0 {
+		t.Errorf("edit history after delete: got %d edits, %v; want none", len(edits), err)
+	}
+	if _, err := s.messages.EditMessage(ctx, msg.ID, "revived", base.Add(5*time.Minute)); !errors.Is(err, store.ErrMessageDeleted) {
+		t.Errorf("EditMessage of deleted message: got %v, want ErrMessageDeleted", err)
+	}
+	if _, err := s.messages.DeleteMessage(ctx, msg.ID, base.Add(5*time.Minute)); !errors.Is(err, store.ErrMessageDeleted) {
+		t.Errorf("DeleteMessage twice: got %v, want ErrMessageDeleted", err)
+	}
+	if _, err := s.messages.EditMessage(ctx, uuid.New(), "x", base); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("EditMessage of unknown message: got %v, want ErrMessageNotFound", err)
+	}
+	if _, err := s.messages.DeleteMessage(ctx, uuid.New(), base); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("DeleteMessage of unknown message: got %v, want ErrMessageNotFound", err)
+	}
+}
+
+func testReceiptPointers(t *testing.T, s stores) {
+	ctx := context.Background()
+	sender, b, c := createUser(t, s), createUser(t, s), createUser(t, s)
+	group, err := s.chats.CreateGroupChat(ctx, "receipts", sender.ID, []uuid.UUID{b.ID, c.ID})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	first := createMessage(t, s, group.ID, sender.ID, base)
+	second := createMessage(t, s, group.ID, sender.ID, base.Add(time.Second))
+
+	mark := func(userID, messageID uuid.UUID, status models.MessageStatus, want bool) {
+		t.Helper()
+		advanced, err := s.messages.MarkMessagesUpTo(ctx, group.ID, userID, messageID, status)
+		if err != nil {
+			t.Fatalf("MarkMessagesUpTo: %v", err)
+		}
+		if advanced != want {
+			t.Errorf("MarkMessagesUpTo(%s) advanced = %v, want %v", status, advanced, want)
+		}
+	}
+	receipts := func(messageID uuid.UUID) map[uuid.UUID]models.MessageStatus {
+		t.Helper()
+		list, err := s.messages.GetMessageReceipts(ctx, messageID)
+		if err != nil {
+			t.Fatalf("GetMessageReceipts: %v", err)
+		}
+		byUser := make(map[uuid.UUID]models.MessageStatus)
+		for _, r := range list {
+			byUser[r.User.ID] = r.Status
+		}
+		return byUser
+	}
+	status := func(messageID uuid.UUID) models.MessageStatus {
+		t.Helper()
+		msg, err := s.messages.GetMessageByID(ctx, messageID)
+		if err != nil {
+			t.Fatalf("GetMessageByID: %v", err)
+		}
+		return msg.Status
+	}
+
+	// Acknowledging the second message acknowledges the first as well.
+	mark(b.ID, second.ID, models.StatusDelivered, true)
+	if got := receipts(first.ID); len(got) != 2 || got[b.ID] != models.StatusDelivered || got[c.ID] != models.StatusSent {
+		t.Errorf("receipts after b received: %v, want b delivered and c sent", got)
+	}
+	if got := status(first.ID); got != models.StatusSent {
+		t.Errorf("status with one recipient left: %s, want sent", got)
+	}
+
+	mark(c.ID, second.ID, models.StatusRead, true)
+	if got := receipts(second.ID); got[b.ID] != models.StatusDelivered || got[c.ID] != models.StatusRead {
+		t.Errorf("receipts after c read: %v, want b delivered and c read", got)
+	}
+	if got := status(first.ID); got != models.StatusDelivered {
+		t.Errorf("status once everyone received it: %s, want delivered", got)
+	}
+
+	// Pointers never move backwards.
+	mark(b.ID, first.ID, models.StatusDelivered, false)
+	mark(c.ID, first.ID, models.StatusRead, false)
+
+	mark(b.ID, first.ID, models.StatusRead, true)
+	if got := status(first.ID); got != models.StatusRead {
+		t.Errorf("status of the message everyone read: %s, want read", got)
+	}
+	if got := status(second.ID); got != models.StatusDelivered {
+		t.Errorf("status of the message b has not read: %s, want delivered", got)
+	}
+	if got := receipts(second.ID); got[b.ID] != models.StatusDelivered {
+		t.Errorf("b's receipt of the newer message: %s, want delivered", got[b.ID])
+	}
+
+	if _, err := s.messages.MarkMessagesUpTo(ctx, group.ID, b.ID, uuid.New(), models.StatusRead); !errors.Is(err, store.ErrMessageNotFound) {
+		t.Errorf("MarkMessagesUpTo of unknown message: got %v, want ErrMessageNotFound", err)
+	}
+}
+
+func testUnreadCounts(t *testing.T, s stores) {
+	ctx := context.Background()
+	a, b, chatID := newDirectChat(t, s)
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	createMessage(t, s, chatID, a, base)
+	// The two newest messages share a timestamp; the ID decides which one is last.
+	tied := []*models.Message{
+		createMessage(t, s, chatID, a, base.Add(time.Second)),
+		createMessage(t, s, chatID, a, base.Add(time.Second)),
+	}
+	createMessage(t, s, chatID, b, base.Add(500*time.Millisecond))
+	gone := createMessage(t, s, chatID, a, base.Add(200*time.Millisecond))
+	if _, err := s.messages.DeleteMessage(ctx, gone.ID, base.Add(2*time.Second)); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+
+	unread := func(what string, want int) {
+		t.Helper()
+		got, err := s.messages.GetUnreadMessageCountForUserInChat(ctx, chatID, b)
+		if err != nil {
+			t.Fatalf("GetUnreadMessageCountForUserInChat: %v", err)
+		}
+		total, err := s.messages.GetTotalUnreadCount(ctx, b)
+		if err != nil {
+			t.Fatalf("GetTotalUnreadCount: %v", err)
+		}
+		page, err := s.chats.GetUserChatsPage(ctx, b, models.CursorPage{Limit: 10})
+		if err != nil {
+			t.Fatalf("GetUserChatsPage: %v", err)
+		}
+		listed := -1
+		for _, chat := range page.Items {
+			if chat.ID == chatID {
+				listed = chat.UnreadCount
+			}
+		}
+		if got != want || total != want || listed != want {
+			t.Errorf("%s: unread in chat %d, total %d, in chat list %d; want %d", what, got, total, listed, want)
+		}
+	}
+	// Neither the reader's own message nor the deleted one counts.
+	unread("before reading", 3)
+
+	latestID, advanced, err := s.messages.MarkChatRead(ctx, chatID, b)
+	if err != nil || !advanced {
+		t.Fatalf("MarkChatRead: got %v, %v; want the pointer to advance", advanced, err)
+	}
+	if want := sortedNewestFirst(tied)[0]; latestID != want {
+		t.Errorf("MarkChatRead picked %s, want %s, the greater ID of the tie", latestID, want)
+	}
+	unread("after reading", 0)
+
+	createMessage(t, s, chatID, a, base.Add(3*time.Second))
+	unread("after a new message", 1)
+
+	if id, advanced, err := s.messages.MarkChatRead(ctx, uuid.New(), b); err != nil || advanced || id != uuid.Nil {
+		t.Errorf("MarkChatRead of a chat without messages: got %s, %v, %v; want nil ID", id, advanced, err)
+	}
+}
+
+func testRepliesAndCounts(t *testing.T, s stores) {
+	ctx := context.Background()
+	a, b, chatID := newDirectChat(t, s)
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	root := createMessage(t, s, chatID, a, base)
+	lonely := createMessage(t, s, chatID, a, base.Add(time.Millisecond))
+
+	reply := func(sender uuid.UUID, to *models.Message, at time.Time) *models.Message {
+		t.Helper()
+		msg := &models.Message{
+			ID:        uuid.New(),
+			ChatID:    chatID,
+			SenderID:  sender,
+			Content:   "reply at " + at.Format(time.RFC3339Nano),
+			Timestamp: at,
+			Status:    models.StatusSent,
+			ReplyToID: &to.ID,
+		}
+		if err := s.messages.CreateMessage(ctx, msg); err != nil {
+			t.Fatalf("CreateMessage reply: %v", err)
+		}
+		return msg
+	}
+	var replies []*models.Message
+	for i := 1; i <= 3; i++ {
+		replies = append(replies, reply(b, root, base.Add(time.Duration(i)*time.Second)))
+	}
+	// A reply to a reply belongs to the nested thread, not the root's.
+	reply(a, replies[0], base.Add(4*time.Second))
+	gone := reply(a, root, base.Add(5*time.Second))
+	if _, err := s.messages.DeleteMessage(ctx, gone.ID, base.Add(6*time.Second)); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+
+	counts, err := s.messages.GetReplyCounts(ctx, []uuid.UUID{root.ID, replies[0].ID, lonely.ID})
+	if err != nil {
+		t.Fatalf("GetReplyCounts: %v", err)
+	}
+	if counts[root.ID] != 3 || counts[replies[0].ID] != 1 {
+		t.Errorf("reply counts = %v, want 3 for the root and 1 for the first reply", counts)
+	}
+	if _, ok := counts[lonely.ID]; ok {
+		t.Errorf("GetReplyCounts lists %s, which has no replies", lonely.ID)
+	}
+
+	want := sortedNewestFirst(replies)
+	first, err := s.messages.GetRepliesPage(ctx, root.ID, models.CursorPage{Limit: 2})
+	if err != nil {
+		t.Fatalf("GetRepliesPage: %v", err)
+	}
+	assertIDs(t, "first page of replies", pageIDs(first), want[:2])
+	cursor, err := models.ParseCursor(first.NextCursor)
+	if err != nil || !first.HasMore {
+		t.Fatalf("first page of replies: hasMore %v, ParseCursor(%q): %v", first.HasMore, first.NextCursor, err)
+	}
+	second, err := s.messages.GetRepliesPage(ctx, root.ID, models.CursorPage{Limit: 2, Before: cursor})
+	if err != nil {
+		t.Fatalf("GetRepliesPage: %v", err)
+	}
+	// The deleted reply is left out here too, so the thread matches its count.
+	assertIDs(t, "second page of replies", pageIDs(second), want[2:])
+	if second.HasMore {
+		t.Error("last page of replies reports more")
+	}
+}
+
+func testReactions(t *testing.T, s stores) {
+	ctx := context.Background()
+	a, b, chatID := newDirectChat(t, s)
+	base := time.Now().UTC().Truncate(time.Microsecond)
+	msg := createMessage(t, s, chatID, a, base)
+	quiet := createMessage(t, s, chatID, b, base.Add(time.Millisecond))
+
+	add := func(user uuid.UUID, emoji string, at time.Time, wantNew bool) {
+		t.Helper()
+		added, err := s.messages.AddReaction(ctx, msg.ID, user, emoji, at)
+		if err != nil || added != wantNew {
+			t.Fatalf("AddReaction(%s): got %v, %v; want %v", emoji, added, err, wantNew)
+		}
+	}
+	add(a, "👍", base.Add(time.Second), true)
+	add(a, "👍", base.Add(2*time.Second), false)
+	add(b, "❤️", base.Add(3*time.Second), true)
+	add(b, "👍", base.Add(4*time.Second), true)
+
+	type count struct {
+		emoji string
+		n     int
+		mine  bool
+	}
+	assertCounts := func(what string, viewer uuid.UUID, want []count) {
+		t.Helper()
+		counts, err := s.messages.GetReactionCounts(ctx, []uuid.UUID{msg.ID, quiet.ID}, viewer)
+		if err != nil {
+			t.Fatalf("GetReactionCounts: %v", err)
+		}
+		if _, ok := counts[quiet.ID]; ok {
+			t.Errorf("%s: GetReactionCounts lists %s, which has no reactions", what, quiet.ID)
+		}
+		got := counts[msg.ID]
+		if len(got) != len(want) {
+			t.Fatalf("%s: got %d emoji, want %d", what, len(got), len(want))
+		}
+		for i, w := range want {
+			if got[i].Emoji != w.emoji || got[i].Count != w.n || got[i].ReactedByMe != w.mine {
+				t.Errorf("%s: reaction %d = %+v, want %+v", what, i, *got[i], w)
+			}
+		}
+	}
+	// Most used first; ReactedByMe is the viewer's own.
+	assertCounts("as a", a, []count{{"👍", 2, true}, {"❤️", 1, false}})
+	assertCounts("as b", b, []count{{"👍", 2, true}, {"❤️", 1, true}})
+
+	for i, want := range []bool{true, false} {
+		removed, err := s.messages.RemoveReaction(ctx, msg.ID, b, "👍")
+		if err != nil || removed != want {
+			t.Fatalf("RemoveReaction #%d: got %v, %v; want %v", i+1, removed, err, want)
+		}
+	}
+	// On a tie the emoji used first leads.
+	assertCounts("after removing", b, []count{{"👍", 1, false}, {"❤️", 1, true}})
+
+	if _, err := s.messages.DeleteMessage(ctx, msg.ID, base.Add(time.Minute)); err != nil {
+		t.Fatalf("DeleteMessage: %v", err)
+	}
+	counts, err := s.messages.GetReactionCounts(ctx, []uuid.UUID{msg.ID}, a)
+	if err != nil || len(counts[msg.ID]) != 0 {
+		t.Errorf("reactions of a deleted message: got %v, %v; want none", counts[msg.ID], err)
+	}
+}
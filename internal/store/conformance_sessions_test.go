@@ -0,0 +1,126 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// newRefreshToken returns an unsaved refresh token with a unique hash.
+func newRefreshToken(sessionID uuid.UUID, expiresAt time.Time) *models.RefreshToken {
+	id := uuid.New()
+	return &models.RefreshToken{
+		ID:        id,
+		SessionID: sessionID,
+		TokenHash: "hash-" + id.String(),
+		CreatedAt: time.Now().UTC(),
+		ExpiresAt: expiresAt,
+	}
+}
+
+func createSession(t *testing.T, s stores, userID uuid.UUID, expiresAt time.Time) (*models.Session, *models.RefreshToken) {
+	t.Helper()
+	now := time.Now().UTC().Truncate(time.Microsecond)
+	session := &models.Session{ID: uuid.New(), UserID: userID, UserAgent: "conformance", IPAddress: "192.0.2.1", CreatedAt: now, LastUsedAt: now}
+	token := newRefreshToken(session.ID, expiresAt)
+	if err := s.sessions.CreateSession(context.Background(), session, token); err != nil {
+		t.Fatalf("CreateSession: %v", err)
+	}
+	return session, token
+}
+
+func testRefreshTokenRotation(t *testing.T, s stores) {
+	ctx := context.Background()
+	u := createUser(t, s)
+	expiresAt := time.Now().Add(time.Hour)
+	session, first := createSession(t, s, u.ID, expiresAt)
+
+	second := newRefreshToken(uuid.Nil, expiresAt)
+	rotated, err := s.sessions.RotateRefreshToken(ctx, first.TokenHash, second)
+	if err != nil {
+		t.Fatalf("RotateRefreshToken: %v", err)
+	}
+	if rotated.ID != session.ID || rotated.UserID != u.ID || second.SessionID != session.ID {
+		t.Errorf("rotation returned session %s of user %s, next token in %s; want all in %s of %s", rotated.ID, rotated.UserID, second.SessionID, session.ID, u.ID)
+	}
+
+	// Replaying the consumed token means it leaked: the whole session goes.
+	replayed, err := s.sessions.RotateRefreshToken(ctx, first.TokenHash, newRefreshToken(uuid.Nil, expiresAt))
+	if !errors.Is(err, store.ErrRefreshTokenReused) {
+		t.Fatalf("replaying a used token: got %v, want ErrRefreshTokenReused", err)
+	}
+	if replayed == nil || replayed.ID != session.ID || replayed.IsActive() {
+		t.Errorf("replay returned session %+v, want %s revoked", replayed, session.ID)
+	}
+	stored, err := s.sessions.GetSession(ctx, session.ID)
+	if err != nil || stored.IsActive() {
+		t.Errorf("GetSession after replay = %+v, %v; want it revoked", stored, err)
+	}
+	if _, err := s.sessions.RotateRefreshToken(ctx, second.TokenHash, newRefreshToken(uuid.Nil, expiresAt)); !errors.Is(err, store.ErrSessionRevoked) {
+		t.Errorf("rotating the current token of the revoked session: got %v, want ErrSessionRevoked", err)
+	}
+
+	if _, err := s.sessions.RotateRefreshToken(ctx, "hash-unknown-"+uuid.NewString(), newRefreshToken(uuid.Nil, expiresAt)); !errors.Is(err, store.ErrRefreshTokenNotFound) {
+		t.Errorf("rotating an unknown token: got %v, want ErrRefreshTokenNotFound", err)
+	}
+
+	stale, expired := createSession(t, s, u.ID, time.Now().Add(-time.Minute))
+	if _, err := s.sessions.RotateRefreshToken(ctx, expired.TokenHash, newRefreshToken(uuid.Nil, expiresAt)); !errors.Is(err, store.ErrRefreshTokenExpired) {
+		t.Errorf("rotating an expired token: got %v, want ErrRefreshTokenExpired", err)
+	}
+	if stored, err := s.sessions.GetSession(ctx, stale.ID); err != nil || !stored.IsActive() {
+		t.Errorf("session of an expired token = %+v, %v; want it still active", stored, err)
+	}
+}
+
+func testSessionRevocation(t *testing.T, s stores) {
+	ctx := context.Background()
+	u, other := createUser(t, s), createUser(t, s)
+	expiresAt := time.Now().Add(time.Hour)
+	kept, _ := createSession(t, s, u.ID, expiresAt)
+	second, _ := createSession(t, s, u.ID, expiresAt)
+	third, _ := createSession(t, s, u.ID, expiresAt)
+	untouched, _ := createSession(t, s, other.ID, expiresAt)
+
+	revoked, err := s.sessions.RevokeUserSessions(ctx, u.ID, &kept.ID)
+	if err != nil {
+		t.Fatalf("RevokeUserSessions: %v", err)
+	}
+	want := []uuid.UUID{second.ID, third.ID}
+	sortIDs(revoked)
+	sortIDs(want)
+	assertIDs(t, "revoked sessions", revoked, want)
+	if again, err := s.sessions.RevokeUserSessions(ctx, u.ID, &kept.ID); err != nil || len(again) != 0 {
+		t.Errorf("revoking again = %v, %v; want nothing left to revoke", again, err)
+	}
+	for _, tt := range []struct {
+		session *models.Session
+		active  bool
+	}{{kept, true}, {second, false}, {untouched, true}} {
+		stored, err := s.sessions.GetSession(ctx, tt.session.ID)
+		if err != nil || stored.IsActive() != tt.active {
+			t.Errorf("session %s: %+v, %v; want active %v", tt.session.ID, stored, err, tt.active)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := s.sessions.RevokeSession(ctx, kept.ID); err != nil {
+			t.Fatalf("RevokeSession #%d: %v", i+1, err)
+		}
+	}
+	if revoked, err := s.sessions.RevokeUserSessions(ctx, u.ID, nil); err != nil || len(revoked) != 0 {
+		t.Errorf("RevokeUserSessions with every session revoked = %v, %v; want none", revoked, err)
+	}
+	if err := s.sessions.RevokeSession(ctx, uuid.New()); !errors.Is(err, store.ErrSessionNotFound) {
+		t.Errorf("RevokeSession of an unknown session: got %v, want ErrSessionNotFound", err)
+	}
+	if _, err := s.sessions.GetSession(ctx, uuid.New()); !errors.Is(err, store.ErrSessionNotFound) {
+		t.Errorf("GetSession of an unknown session: got %v, want ErrSessionNotFound", err)
+	}
+}
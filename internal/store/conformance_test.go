@@ -0,0 +1,423 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"slices"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/migrate"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDatabaseEnv names the DSN of a disposable Postgres database. The Postgres half of
+// the suite is skipped when it is unset; the schema is migrated up before the run.
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// stores is the set of stores a conformance run exercises. They must share one backing
+// database so that chats and messages can refer to the users created in it.
+type stores struct {
+	users       store.UserStore
+	chats       store.ChatStore
+	messages    store.MessageStore
+	tokens      store.ActionTokenStore
+	blocks      store.BlockStore
+	attachments store.AttachmentStore
+	sessions    store.SessionStore
+	logins      store.LoginAttemptStore
+}
+
+// conformanceCase is one behaviour checked against every backend, on fresh stores.
+type conformanceCase struct {
+	name string
+	run  func(t *testing.T, s stores)
+}
+
+// conformanceCases lists the cases kept in the per-area conformance_*_test.go files.
+var conformanceCases = []conformanceCase{
+	{"MessageEditsAndDeletes", testMessageEditsAndDeletes},
+	{"ReceiptPointers", testReceiptPointers},
+	{"UnreadCounts", testUnreadCounts},
+	{"RepliesAndCounts", testRepliesAndCounts},
+	{"Reactions", testReactions},
+	{"MessageAttachments", testMessageAttachments},
+	{"AttachmentProcessing", testAttachmentProcessing},
+	{"SearchRanking", testSearchRanking},
+	{"EmailProjection", testEmailProjection},
+	{"ActionTokens", testActionTokens},
+	{"PasswordChangeInvalidatesResetTokens", testPasswordChangeInvalidatesResetTokens},
+	{"SearchSkipsBlockedUsers", testSearchSkipsBlockedUsers},
+	{"Blocks", testBlocks},
+	{"RefreshTokenRotation", testRefreshTokenRotation},
+	{"SessionRevocation", testSessionRevocation},
+	{"LoginThrottles", testLoginThrottles},
+	{"ChatMute", testChatMute},
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	runConformance(t, func(t *testing.T) stores {
+		db := store.NewMemoryDB()
+		return stores{
+			users:       store.NewMemoryUserStore(db),
+			chats:       store.NewMemoryChatStore(db),
+			messages:    store.NewMemoryMessageStore(db),
+			tokens:      store.NewMemoryActionTokenStore(db),
+			blocks:      store.NewMemoryBlockStore(db),
+			attachments: store.NewMemoryAttachmentStore(db),
+			sessions:    store.NewMemorySessionStore(db),
+			logins:      store.NewMemoryLoginAttemptStore(db),
+		}
+	})
+}
+
+func TestPostgresStoreConformance(t *testing.T) {
+	pool := openTestDatabase(t)
+	runConformance(t, func(t *testing.T) stores {
+		return stores{
+			users:       store.NewPostgresUserStore(pool),
+			chats:       store.NewPostgresChatStore(pool),
+			messages:    store.NewPostgresMessageStore(pool),
+			tokens:      store.NewPostgresActionTokenStore(pool),
+			blocks:      store.NewPostgresBlockStore(pool),
+			attachments: store.NewPostgresAttachmentStore(pool),
+			sessions:    store.NewPostgresSessionStore(pool),
+			logins:      store.NewPostgresLoginAttemptStore(pool),
+		}
+	})
+}
+
+func openTestDatabase(t *testing.T) *pgxpool.Pool {
+	t.Helper()
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	migrator, err := migrate.NewMigrator(pool)
+	if err != nil {
+		t.Fatalf("load migrations: %v", err)
+	}
+	if _, err := migrator.Up(ctx); err != nil {
+		t.Fatalf("migrate test database: %v", err)
+	}
+	return pool
+}
+
+// runConformance checks the behaviour handlers rely on from every backend. Each case
+// works on users of its own, so a Postgres database may be reused across runs.
+func runConformance(t *testing.T, newStores func(t *testing.T) stores) {
+	t.Run("UserSentinelErrors", func(t *testing.T) {
+		s := newStores(t)
+		ctx := context.Background()
+		u := createUser(t, s)
+
+		dup := newUser()
+		dup.Email = u.Email
+		if err := s.users.CreateUser(ctx, dup); !errors.Is(err, store.ErrEmailExists) {
+			t.Errorf("CreateUser with taken email: got %v, want ErrEmailExists", err)
+		}
+		dup = newUser()
+		dup.Username = u.Username
+		if err := s.users.CreateUser(ctx, dup); !errors.Is(err, store.ErrUsernameExists) {
+			t.Errorf("CreateUser with taken username: got %v, want ErrUsernameExists", err)
+		}
+
+		got, err := s.users.GetUserByEmail(ctx, u.Email)
+		if err != nil || got.ID != u.ID {
+			t.Errorf("GetUserByEmail: got %v, %v; want user %s", got, err, u.ID)
+		}
+		if _, err := s.users.GetUserByEmail(ctx, "missing-"+uuid.NewString()+"@example.com"); !errors.Is(err, store.ErrUserNotFound) {
+			t.Errorf("GetUserByEmail of unknown email: got %v, want ErrUserNotFound", err)
+		}
+		if _, err := s.users.GetUserByID(ctx, uuid.NewString()); !errors.Is(err, store.ErrUserNotFound) {
+			t.Errorf("GetUserByID of unknown ID: got %v, want ErrUserNotFound", err)
+		}
+	})
+
+	t.Run("EmailChangeInvalidatesActionTokens", func(t *testing.T) {
+		s := newStores(t)
+		ctx := context.Background()
+		u := createUser(t, s)
+		issue := func(purpose string) string {
+			now := time.Now()
+			token := &models.ActionToken{
+				ID:        uuid.New(),
+				UserID:    u.ID,
+				Purpose:   purpose,
+				TokenHash: uuid.NewString(),
+				CreatedAt: now,
+				ExpiresAt: now.Add(time.Hour),
+			}
+			if err := s.tokens.CreateActionToken(ctx, token); err != nil {
+				t.Fatalf("CreateActionToken: %v", err)
+			}
+			return token.TokenHash
+		}
+
+		// Keeping the address leaves outstanding tokens alone.
+		reset := issue(models.ActionResetPassword)
+		if err := s.users.UpdateUserProfile(ctx, u.ID, u.Username+"x", u.Email); err != nil {
+			t.Fatalf("UpdateUserProfile: %v", err)
+		}
+		if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionResetPassword, reset); err != nil {
+			t.Errorf("consume reset token after renaming: got %v, want success", err)
+		}
+
+		reset, verify := issue(models.ActionResetPassword), issue(models.ActionVerifyEmail)
+		if err := s.users.UpdateUserProfile(ctx, u.ID, u.Username, "new-"+u.Email); err != nil {
+			t.Fatalf("UpdateUserProfile: %v", err)
+		}
+		if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionResetPassword, reset); !errors.Is(err, store.ErrActionTokenUsed) {
+			t.Errorf("consume reset token after email change: got %v, want ErrActionTokenUsed", err)
+		}
+		if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionVerifyEmail, verify); !errors.Is(err, store.ErrActionTokenUsed) {
+			t.Errorf("consume verification token after email change: got %v, want ErrActionTokenUsed", err)
+		}
+	})
+
+	t.Run("ChatSentinelErrors", func(t *testing.T) {
+		s := newStores(t)
+		ctx := context.Background()
+		a, b := createUser(t, s), createUser(t, s)
+		chat, err := s.chats.CreateChat(ctx, []uuid.UUID{a.ID, b.ID})
+		if err != nil {
+			t.Fatalf("CreateChat: %v", err)
+		}
+
+		if _, err := s.chats.GetChatByID(ctx, uuid.New()); !errors.Is(err, store.ErrChatNotFound) {
+			t.Errorf("GetChatByID of unknown chat: got %v, want ErrChatNotFound", err)
+		}
+		if _, err := s.chats.GetParticipantRole(ctx, chat.ID, uuid.New()); !errors.Is(err, store.ErrNotParticipant) {
+			t.Errorf("GetParticipantRole of non-member: got %v, want ErrNotParticipant", err)
+		}
+		if ok, err := s.chats.IsParticipant(ctx, chat.ID, a.ID); err != nil || !ok {
+			t.Errorf("IsParticipant of member: got %v, %v; want true", ok, err)
+		}
+		if _, err := s.messages.GetMessageByID(ctx, uuid.New()); !errors.Is(err, store.ErrMessageNotFound) {
+			t.Errorf("GetMessageByID of unknown message: got %v, want ErrMessageNotFound", err)
+		}
+	})
+
+	t.Run("DirectChatLookup", func(t *testing.T) {
+		s := newStores(t)
+		ctx := context.Background()
+		a, b, c := createUser(t, s), createUser(t, s), createUser(t, s)
+
+		if _, err := s.chats.GetChatByParticipantIDs(ctx, []uuid.UUID{a.ID, b.ID}); !errors.Is(err, store.ErrChatNotFound) {
+			t.Fatalf("lookup before any chat exists: got %v, want ErrChatNotFound", err)
+		}
+		// Neither a group nor a three-person chat counts as the pair's direct chat.
+		if _, err := s.chats.CreateGroupChat(ctx, "pair", a.ID, []uuid.UUID{b.ID}); err != nil {
+			t.Fatalf("CreateGroupChat: %v", err)
+		}
+		if _, err := s.chats.CreateChat(ctx, []uuid.UUID{a.ID, b.ID, c.ID}); err != nil {
+			t.Fatalf("CreateChat of three: %v", err)
+		}
+		if _, err := s.chats.GetChatByParticipantIDs(ctx, []uuid.UUID{a.ID, b.ID}); !errors.Is(err, store.ErrChatNotFound) {
+			t.Fatalf("lookup with only group chats: got %v, want ErrChatNotFound", err)
+		}
+
+		direct, err := s.chats.CreateChat(ctx, []uuid.UUID{a.ID, b.ID})
+		if err != nil {
+			t.Fatalf("CreateChat: %v", err)
+		}
+		for _, pair := range [][]uuid.UUID{{a.ID, b.ID}, {b.ID, a.ID}} {
+			got, err := s.chats.GetChatByParticipantIDs(ctx, pair)
+			if err != nil || got.ID != direct.ID {
+				t.Errorf("GetChatByParticipantIDs(%v): got %v, %v; want chat %s", pair, got, err, direct.ID)
+			}
+		}
+		if _, err := s.chats.GetChatByParticipantIDs(ctx, []uuid.UUID{a.ID, c.ID}); !errors.Is(err, store.ErrChatNotFound) {
+			t.Errorf("lookup of a pair without a chat: got %v, want ErrChatNotFound", err)
+		}
+		if _, err := s.chats.GetChatByParticipantIDs(ctx, []uuid.UUID{a.ID}); err == nil {
+			t.Errorf("lookup with one participant: got nil error")
+		}
+	})
+
+	t.Run("MessagePagination", func(t *testing.T) {
+		s := newStores(t)
+		ctx := context.Background()
+		a, b := createUser(t, s), createUser(t, s)
+		chat, err := s.chats.CreateChat(ctx, []uuid.UUID{a.ID, b.ID})
+		if err != nil {
+			t.Fatalf("CreateChat: %v", err)
+		}
+		// Two messages share a timestamp so that the ID breaks the tie.
+		base := time.Now().UTC().Truncate(time.Millisecond)
+		offsets := []time.Duration{0, time.Second, time.Second, 2 * time.Second, 3 * time.Second}
+		sent := make([]*models.Message, len(offsets))
+		for i, offset := range offsets {
+			sent[i] = createMessage(t, s, chat.ID, a.ID, base.Add(offset))
+		}
+		newestFirst := sortedNewestFirst(sent)
+
+		var walked []uuid.UUID
+		page := models.CursorPage{Limit: 2}
+		for {
+			got, err := s.messages.GetMessagesPage(ctx, chat.ID, page)
+			if err != nil {
+				t.Fatalf("GetMessagesPage: %v", err)
+			}
+			for _, m := range got.Items {
+				walked = append(walked, m.ID)
+			}
+			if !got.HasMore {
+				break
+			}
+			cursor, err := models.ParseCursor(got.NextCursor)
+			if err != nil {
+				t.Fatalf("ParseCursor(%q): %v", got.NextCursor, err)
+			}
+			page.Before = cursor
+		}
+		assertIDs(t, "backward walk", walked, newestFirst)
+
+		// Walking forward from the oldest message returns the newer ones, newest first.
+		oldest := newestFirst[len(newestFirst)-1]
+		got, err := s.messages.GetMessagesPage(ctx, chat.ID, models.CursorPage{Limit: 2, After: &models.Cursor{Timestamp: base, ID: oldest}})
+		if err != nil {
+			t.Fatalf("GetMessagesPage forward: %v", err)
+		}
+		if !got.HasMore {
+			t.Errorf("forward page: HasMore = false, want true")
+		}
+		assertIDs(t, "forward page", pageIDs(got), []uuid.UUID{newestFirst[len(newestFirst)-3], newestFirst[len(newestFirst)-2]})
+	})
+
+	t.Run("ChatListOrder", func(t *testing.T) {
+		s := newStores(t)
+		ctx := context.Background()
+		a, b, c := createUser(t, s), createUser(t, s), createUser(t, s)
+		withB, err := s.chats.CreateChat(ctx, []uuid.UUID{a.ID, b.ID})
+		if err != nil {
+			t.Fatalf("CreateChat: %v", err)
+		}
+		withC, err := s.chats.CreateChat(ctx, []uuid.UUID{a.ID, c.ID})
+		if err != nil {
+			t.Fatalf("CreateChat: %v", err)
+		}
+		// The older chat becomes the most recently active one.
+		createMessage(t, s, withC.ID, c.ID, time.Now().UTC().Add(time.Minute))
+		createMessage(t, s, withB.ID, b.ID, time.Now().UTC().Add(2*time.Minute))
+
+		first, err := s.chats.GetUserChatsPage(ctx, a.ID, models.CursorPage{Limit: 1})
+		if err != nil {
+			t.Fatalf("GetUserChatsPage: %v", err)
+		}
+		if !first.HasMore {
+			t.Errorf("first chat page: HasMore = false, want true")
+		}
+		cursor, err := models.ParseCursor(first.NextCursor)
+		if err != nil {
+			t.Fatalf("ParseCursor(%q): %v", first.NextCursor, err)
+		}
+		second, err := s.chats.GetUserChatsPage(ctx, a.ID, models.CursorPage{Limit: 1, Before: cursor})
+		if err != nil {
+			t.Fatalf("GetUserChatsPage: %v", err)
+		}
+		if second.HasMore {
+			t.Errorf("second chat page: HasMore = true, want false")
+		}
+		var walked []uuid.UUID
+		for _, chat := range append(first.Items, second.Items...) {
+			walked = append(walked, chat.ID)
+		}
+		assertIDs(t, "chat list", walked, []uuid.UUID{withB.ID, withC.ID})
+	})
+
+	for _, c := range conformanceCases {
+		t.Run(c.name, func(t *testing.T) { c.run(t, newStores(t)) })
+	}
+}
+
+func newUser() *models.User {
+	id := uuid.New()
+	now := time.Now().UTC()
+	return &models.User{
+		ID:              id,
+		Username:        "user_" + id.String()[:8],
+		Email:           "user_" + id.String() + "@example.com",
+		HashedPassword:  "not-a-real-hash",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		EmailVisibility: models.VisibilityContacts,
+	}
+}
+
+func createUser(t *testing.T, s stores) *models.User {
+	t.Helper()
+	u := newUser()
+	if err := s.users.CreateUser(context.Background(), u); err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	return u
+}
+
+func createMessage(t *testing.T, s stores, chatID, senderID uuid.UUID, at time.Time) *models.Message {
+	t.Helper()
+	msg := &models.Message{
+		ID:        uuid.New(),
+		ChatID:    chatID,
+		SenderID:  senderID,
+		Content:   "message at " + at.Format(time.RFC3339Nano),
+		Timestamp: at,
+		Status:    models.StatusSent,
+	}
+	if err := s.messages.CreateMessage(context.Background(), msg); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	return msg
+}
+
+// sortedNewestFirst returns the message IDs in the (timestamp, id) order pages use.
+func sortedNewestFirst(messages []*models.Message) []uuid.UUID {
+	sorted := slices.Clone(messages)
+	slices.SortFunc(sorted, func(a, b *models.Message) int {
+		if b.Cursor().Less(a.Cursor()) {
+			return -1
+		}
+		return 1
+	})
+	ids := make([]uuid.UUID, len(sorted))
+	for i, m := range sorted {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+// sortIDs puts IDs returned in no particular order into a fixed one for comparison.
+func sortIDs(ids []uuid.UUID) {
+	slices.SortFunc(ids, func(a, b uuid.UUID) int { return slices.Compare(a[:], b[:]) })
+}
+
+func pageIDs(page *models.Page[*models.Message]) []uuid.UUID {
+	ids := make([]uuid.UUID, len(page.Items))
+	for i, m := range page.Items {
+		ids[i] = m.ID
+	}
+	return ids
+}
+
+func assertIDs(t *testing.T, what string, got, want []uuid.UUID) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("%s: got %d items %v, want %d %v", what, len(got), got, len(want), want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Fatalf("%s: item %d is %s, want %s (got %v, want %v)", what, i, got[i], want[i], got, want)
+		}
+	}
+}
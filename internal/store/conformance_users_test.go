@@ -0,0 +1,260 @@
+package store_test
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+func testSearchSkipsBlockedUsers(t *testing.T, s stores) {
+	ctx := context.Background()
+	blocker, blocked, other := createUser(t, s), createUser(t, s), createUser(t, s)
+	if err := s.blocks.BlockUser(ctx, blocker.ID, blocked.ID, time.Now()); err != nil {
+		t.Fatalf("BlockUser: %v", err)
+	}
+
+	for _, tt := range []struct {
+		name     string
+		searcher uuid.UUID
+		target   *models.User
+		found    bool
+	}{
+		{"blocker searches blocked", blocker.ID, blocked, false},
+		{"blocked searches blocker", blocked.ID, blocker, false},
+		{"blocker searches someone else", blocker.ID, other, true},
+	} {
+		users, err := s.users.SearchUsers(ctx, models.UserSearch{Query: tt.target.Username, SearcherID: tt.searcher, Limit: 50})
+		if err != nil {
+			t.Fatalf("%s: SearchUsers: %v", tt.name, err)
+		}
+		found := false
+		for _, u := range users {
+			found = found || u.ID == tt.target.ID
+		}
+		if found != tt.found {
+			t.Errorf("%s: found = %v, want %v", tt.name, found, tt.found)
+		}
+	}
+}
+
+func testSearchRanking(t *testing.T, s stores) {
+	ctx := context.Background()
+	searcher := createUser(t, s)
+	stem := "rank" + strings.ReplaceAll(uuid.NewString(), "-", "")[:8]
+	named := func(username string) *models.User {
+		t.Helper()
+		u := newUser()
+		u.Username = username
+		if err := s.users.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser %s: %v", username, err)
+		}
+		return u
+	}
+	exact := named(stem)
+	// The stranger sorts before the partner by name, so only the partner rank can lift it.
+	strangerPrefix := named(stem + "_a")
+	partnerPrefix := named(stem + "_b")
+	similar := named("x" + stem)
+	named("zz" + strings.ReplaceAll(uuid.NewString(), "-", "")[:8])
+	if _, err := s.chats.CreateChat(ctx, []uuid.UUID{searcher.ID, partnerPrefix.ID}); err != nil {
+		t.Fatalf("CreateChat: %v", err)
+	}
+
+	want := []uuid.UUID{exact.ID, partnerPrefix.ID, strangerPrefix.ID, similar.ID}
+	for _, tt := range []struct {
+		name          string
+		query         string
+		limit, offset int
+		want          []uuid.UUID
+	}{
+		{"all matches", stem, 50, 0, want},
+		{"query case and spaces are ignored", "  " + strings.ToUpper(stem) + " ", 50, 0, want},
+		{"second page", stem, 2, 2, want[2:]},
+	} {
+		users, err := s.users.SearchUsers(ctx, models.UserSearch{Query: tt.query, SearcherID: searcher.ID, Limit: tt.limit, Offset: tt.offset})
+		if err != nil {
+			t.Fatalf("%s: SearchUsers: %v", tt.name, err)
+		}
+		got := make([]uuid.UUID, len(users))
+		for i, u := range users {
+			got[i] = u.ID
+		}
+		assertIDs(t, tt.name, got, tt.want)
+	}
+}
+
+func testEmailProjection(t *testing.T, s stores) {
+	ctx := context.Background()
+	stranger, contact := createUser(t, s), createUser(t, s)
+	withVisibility := func(v models.Visibility) *models.User {
+		t.Helper()
+		u := newUser()
+		u.EmailVisibility = v
+		if err := s.users.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		return u
+	}
+	nobody := withVisibility(models.VisibilityNobody)
+	contacts := withVisibility(models.VisibilityContacts)
+	everyone := withVisibility(models.VisibilityEveryone)
+	targets := []*models.User{nobody, contacts, everyone}
+	chat, err := s.chats.CreateGroupChat(ctx, "email projection", contact.ID, []uuid.UUID{nobody.ID, contacts.ID, everyone.ID})
+	if err != nil {
+		t.Fatalf("CreateGroupChat: %v", err)
+	}
+
+	// shown is the email each viewer should get for each target, or "" when hidden.
+	shown := map[uuid.UUID]map[uuid.UUID]string{
+		stranger.ID: {nobody.ID: "", contacts.ID: "", everyone.ID: everyone.Email},
+		contact.ID:  {nobody.ID: "", contacts.ID: contacts.Email, everyone.ID: everyone.Email},
+	}
+	for viewer, emails := range shown {
+		for _, target := range targets {
+			byName, err := s.users.SearchUsers(ctx, models.UserSearch{Query: target.Username, SearcherID: viewer, Limit: 50})
+			if err != nil {
+				t.Fatalf("SearchUsers: %v", err)
+			}
+			if len(byName) == 0 || byName[0].ID != target.ID {
+				t.Fatalf("search for %s by username did not rank them first: %v", target.EmailVisibility, byName)
+			}
+			if byName[0].Email != emails[target.ID] {
+				t.Errorf("viewer %s searching %s: email %q, want %q", viewer, target.EmailVisibility, byName[0].Email, emails[target.ID])
+			}
+			byEmail, err := s.users.SearchUsers(ctx, models.UserSearch{Query: target.Email, SearcherID: viewer, Limit: 50})
+			if err != nil {
+				t.Fatalf("SearchUsers: %v", err)
+			}
+			found := len(byEmail) > 0 && byEmail[0].ID == target.ID
+			if want := emails[target.ID] != ""; found != want {
+				t.Errorf("viewer %s searching %s by email: found = %v, want %v", viewer, target.EmailVisibility, found, want)
+			}
+		}
+	}
+
+	// Everyone in a chat is a contact of the others there.
+	inChat := shown[contact.ID]
+	members, err := s.chats.GetChatMembers(ctx, chat.ID)
+	if err != nil {
+		t.Fatalf("GetChatMembers: %v", err)
+	}
+	for _, m := range members {
+		if want, ok := inChat[m.User.ID]; ok && m.User.Email != want {
+			t.Errorf("chat member email %q, want %q", m.User.Email, want)
+		}
+	}
+	at := time.Now().UTC().Truncate(time.Microsecond)
+	for _, target := range targets {
+		msg := createMessage(t, s, chat.ID, target.ID, at)
+		got, err := s.messages.GetMessageByID(ctx, msg.ID)
+		if err != nil {
+			t.Fatalf("GetMessageByID: %v", err)
+		}
+		if got.Sender == nil || got.Sender.Email != inChat[target.ID] {
+			t.Errorf("sender %s: got %+v, want email %q", target.EmailVisibility, got.Sender, inChat[target.ID])
+		}
+	}
+	receipts, err := s.messages.GetMessageReceipts(ctx, createMessage(t, s, chat.ID, contact.ID, at.Add(time.Second)).ID)
+	if err != nil {
+		t.Fatalf("GetMessageReceipts: %v", err)
+	}
+	if len(receipts) != len(targets) {
+		t.Fatalf("got %d receipts, want %d", len(receipts), len(targets))
+	}
+	for _, r := range receipts {
+		if r.User.Email != inChat[r.User.ID] {
+			t.Errorf("receipt email %q, want %q", r.User.Email, inChat[r.User.ID])
+		}
+	}
+
+	if err := s.users.UpdateUserSettings(ctx, nobody.ID, models.UserSettings{EmailVisibility: models.VisibilityEveryone}); err != nil {
+		t.Fatalf("UpdateUserSettings: %v", err)
+	}
+	users, err := s.users.SearchUsers(ctx, models.UserSearch{Query: nobody.Email, SearcherID: stranger.ID, Limit: 50})
+	if err != nil {
+		t.Fatalf("SearchUsers: %v", err)
+	}
+	if len(users) == 0 || users[0].ID != nobody.ID || users[0].Email != nobody.Email {
+		t.Errorf("after opening up the email, search by it got %v, want %s with its email", users, nobody.ID)
+	}
+}
+
+func testActionTokens(t *testing.T, s stores) {
+	ctx := context.Background()
+	u := createUser(t, s)
+	now := time.Now().UTC()
+	issue := func(purpose string, expiresAt time.Time) string {
+		t.Helper()
+		hash := "hash-" + uuid.NewString()
+		token := &models.ActionToken{ID: uuid.New(), UserID: u.ID, Purpose: purpose, TokenHash: hash, CreatedAt: now, ExpiresAt: expiresAt}
+		if err := s.tokens.CreateActionToken(ctx, token); err != nil {
+			t.Fatalf("CreateActionToken: %v", err)
+		}
+		return hash
+	}
+
+	verify := issue(models.ActionVerifyEmail, now.Add(time.Hour))
+	if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionResetPassword, verify); !errors.Is(err, store.ErrActionTokenNotFound) {
+		t.Errorf("consuming for the wrong purpose: got %v, want ErrActionTokenNotFound", err)
+	}
+	got, err := s.tokens.ConsumeActionToken(ctx, models.ActionVerifyEmail, verify)
+	if err != nil || got.UserID != u.ID || got.UsedAt == nil {
+		t.Fatalf("ConsumeActionToken = %+v, %v; want the token of %s, used", got, err, u.ID)
+	}
+	if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionVerifyEmail, verify); !errors.Is(err, store.ErrActionTokenUsed) {
+		t.Errorf("consuming twice: got %v, want ErrActionTokenUsed", err)
+	}
+
+	expired := issue(models.ActionResetPassword, now.Add(-time.Second))
+	if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionResetPassword, expired); !errors.Is(err, store.ErrActionTokenExpired) {
+		t.Errorf("consuming an expired token: got %v, want ErrActionTokenExpired", err)
+	}
+	// A new token replaces the user's earlier unused one of the same purpose.
+	earlier := issue(models.ActionResetPassword, now.Add(time.Hour))
+	latest := issue(models.ActionResetPassword, now.Add(time.Hour))
+	if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionResetPassword, earlier); !errors.Is(err, store.ErrActionTokenUsed) {
+		t.Errorf("consuming a superseded token: got %v, want ErrActionTokenUsed", err)
+	}
+	if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionResetPassword, latest); err != nil {
+		t.Errorf("consuming the latest token: %v", err)
+	}
+}
+
+func testPasswordChangeInvalidatesResetTokens(t *testing.T, s stores) {
+	ctx := context.Background()
+	u := createUser(t, s)
+	now := time.Now().UTC()
+	hashes := make(map[string]string)
+	for _, purpose := range []string{models.ActionResetPassword, models.ActionVerifyEmail} {
+		hashes[purpose] = "hash-" + uuid.NewString()
+		token := &models.ActionToken{ID: uuid.New(), UserID: u.ID, Purpose: purpose, TokenHash: hashes[purpose], CreatedAt: now, ExpiresAt: now.Add(time.Hour)}
+		if err := s.tokens.CreateActionToken(ctx, token); err != nil {
+			t.Fatalf("CreateActionToken: %v", err)
+		}
+	}
+
+	if err := s.users.UpdatePassword(ctx, u.ID, "another-hash"); err != nil {
+		t.Fatalf("UpdatePassword: %v", err)
+	}
+	got, err := s.users.GetUserByID(ctx, u.ID.String())
+	if err != nil || got.HashedPassword != "another-hash" {
+		t.Errorf("GetUserByID after UpdatePassword = %+v, %v; want the new hash", got, err)
+	}
+	if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionResetPassword, hashes[models.ActionResetPassword]); !errors.Is(err, store.ErrActionTokenUsed) {
+		t.Errorf("consuming a reset token issued before the change: got %v, want ErrActionTokenUsed", err)
+	}
+	// Verifying the address has nothing to do with the password.
+	if _, err := s.tokens.ConsumeActionToken(ctx, models.ActionVerifyEmail, hashes[models.ActionVerifyEmail]); err != nil {
+		t.Errorf("consuming a verification token after the change: %v", err)
+	}
+	if err := s.users.UpdatePassword(ctx, uuid.New(), "hash"); !errors.Is(err, store.ErrUserNotFound) {
+		t.Errorf("UpdatePassword of an unknown user: got %v, want ErrUserNotFound", err)
+	}
+}
@@ -0,0 +1,238 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// EventStore persists the per-chat log of realtime events used for offline sync.
+type EventStore interface {
+	// AppendChatEvent records an event and returns it with its assigned seq.
+	AppendChatEvent(ctx context.Context, chatID uuid.UUID, messageID *uuid.UUID, eventType string, payload interface{}) (*models.ChatEvent, error)
+	GetChatEvent(ctx context.Context, seq int64) (*models.ChatEvent, error)
+	// GetChatEventsAfter returns up to limit events of a chat with seq > afterSeq, in seq order.
+	GetChatEventsAfter(ctx context.Context, chatID uuid.UUID, afterSeq int64, limit int) ([]*models.ChatEvent, error)
+	// GetUserEventsAfter returns up to limit events with seq > afterSeq from every chat the
+	// user currently belongs to, in seq order.
+	GetUserEventsAfter(ctx context.Context, userID uuid.UUID, afterSeq int64, limit int) ([]*models.ChatEvent, error)
+	// GetMessageEventSeq returns the seq of the first event recorded for a message, i.e. its creation.
+	GetMessageEventSeq(ctx context.Context, messageID uuid.UUID) (int64, error)
+	// RedactMessageEvents rewrites the recorded events that carry a message's content, its
+	// own events and the quotes of it in replies, to the message's current state so that
+	// sync does not replay deleted or superseded text.
+	RedactMessageEvents(ctx context.Context, msg *models.Message) error
+}
+
+// PostgresEventStore implements EventStore with PostgreSQL.
+type PostgresEventStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresEventStore returns a Postgres-backed EventStore implementation.
+func NewPostgresEventStore(db *pgxpool.Pool) *PostgresEventStore {
+	return &PostgresEventStore{db: db}
+}
+
+func (s *PostgresEventStore) AppendChatEvent(ctx context.Context, chatID uuid.UUID, messageID *uuid.UUID, eventType string, payload interface{}) (*models.ChatEvent, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event payload: %w", eventType, err)
+	}
+
+	query := `
+        INSERT INTO chat_events (chat_id, message_id, event_type, payload)
+        VALUES ($1, $2, $3, $4)
+        RETURNING seq, created_at
+    `
+	event := &models.ChatEvent{ChatID: chatID, MessageID: messageID, Type: eventType, Payload: raw}
+	if err := s.db.QueryRow(ctx, query, chatID, messageID, eventType, raw).Scan(&event.Seq, &event.CreatedAt); err != nil {
+		return nil, fmt.Errorf("failed to append %s event to chat %s: %w", eventType, chatID, err)
+	}
+	return event, nil
+}
+
+func (s *PostgresEventStore) GetChatEvent(ctx context.Context, seq int64) (*models.ChatEvent, error) {
+	query := `
+        SELECT seq, chat_id, message_id, event_type, payload, created_at
+        FROM chat_events
+        WHERE seq = $1
+    `
+	var event models.ChatEvent
+	err := s.db.QueryRow(ctx, query, seq).Scan(&event.Seq, &event.ChatID, &event.MessageID, &event.Type, &event.Payload, &event.CreatedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrEventNotFound
+		}
+		return nil, fmt.Errorf("failed to get chat event %d: %w", seq, err)
+	}
+	return &event, nil
+}
+
+func (s *PostgresEventStore) GetChatEventsAfter(ctx context.Context, chatID uuid.UUID, afterSeq int64, limit int) ([]*models.ChatEvent, error) {
+	query := `
+        SELECT seq, chat_id, message_id, event_type, payload, created_at
+        FROM chat_events
+        WHERE chat_id = $1 AND seq > $2
+        ORDER BY seq ASC
+        LIMIT $3
+    `
+	rows, err := s.db.Query(ctx, query, chatID, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events of chat %s: %w", chatID, err)
+	}
+	return scanChatEvents(rows)
+}
+
+func (s *PostgresEventStore) GetUserEventsAfter(ctx context.Context, userID uuid.UUID, afterSeq int64, limit int) ([]*models.ChatEvent, error) {
+	query := `
+        SELECT e.seq, e.chat_id, e.message_id, e.event_type, e.payload, e.created_at
+        FROM chat_events e
+        JOIN chat_participants cp ON cp.chat_id = e.chat_id AND cp.user_id = $1
+        WHERE e.seq > $2
+        ORDER BY e.seq ASC
+        LIMIT $3
+    `
+	rows, err := s.db.Query(ctx, query, userID, afterSeq, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query events for user %s: %w", userID, err)
+	}
+	return scanChatEvents(rows)
+}
+
+func (s *PostgresEventStore) GetMessageEventSeq(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	query := `SELECT seq FROM chat_events WHERE message_id = $1 ORDER BY seq ASC LIMIT 1`
+	var seq int64
+	if err := s.db.QueryRow(ctx, query, messageID).Scan(&seq); err != nil {
+		if err == pgx.ErrNoRows {
+			return 0, ErrEventNotFound
+		}
+		return 0, fmt.Errorf("failed to look up event of message %s: %w", messageID, err)
+	}
+	return seq, nil
+}
+
+func (s *PostgresEventStore) RedactMessageEvents(ctx context.Context, msg *models.Message) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+        SELECT seq, message_id, payload
+        FROM chat_events
+        WHERE message_id = $1
+           OR (chat_id = $2 AND payload->'replyTo'->>'id' = $3)
+        FOR UPDATE
+    `
+	rows, err := tx.Query(ctx, query, msg.ID, msg.ChatID, msg.ID.String())
+	if err != nil {
+		return fmt.Errorf("failed to query events of message %s: %w", msg.ID, err)
+	}
+	redacted := make(map[int64]json.RawMessage)
+	for rows.Next() {
+		var seq int64
+		var messageID *uuid.UUID
+		var payload json.RawMessage
+		if err := rows.Scan(&seq, &messageID, &payload); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan event of message %s: %w", msg.ID, err)
+		}
+		own := messageID != nil && *messageID == msg.ID
+		if updated, changed := redactEventPayload(payload, own, msg); changed {
+			redacted[seq] = updated
+		}
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("error iterating events of message %s: %w", msg.ID, err)
+	}
+
+	for seq, payload := range redacted {
+		if _, err := tx.Exec(ctx, `UPDATE chat_events SET payload = $1 WHERE seq = $2`, payload, seq); err != nil {
+			return fmt.Errorf("failed to redact chat event %d: %w", seq, err)
+		}
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// redactEventPayload brings the copies of msg in an event payload up to date: the message
+// itself when own is set and the payload carries its content, and the quote of it in a
+// reply. A deleted message keeps neither content nor attachments. It reports whether the
+// payload changed; payloads that are not JSON objects are left alone.
+func redactEventPayload(payload json.RawMessage, own bool, msg *models.Message) (json.RawMessage, bool) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(payload, &fields); err != nil {
+		return payload, false
+	}
+	deleted := msg.DeletedAt != nil
+	changed := false
+	set := func(m map[string]json.RawMessage, key string, value any) {
+		raw, _ := json.Marshal(value)
+		m[key] = raw
+		changed = true
+	}
+
+	if _, hasContent := fields["content"]; own && hasContent {
+		set(fields, "content", msg.Content)
+		if msg.EditedAt != nil {
+			set(fields, "editedAt", msg.EditedAt)
+		}
+		if deleted {
+			set(fields, "deleted", true)
+			set(fields, "deletedAt", msg.DeletedAt)
+			delete(fields, "attachments")
+		}
+	}
+
+	var quote map[string]json.RawMessage
+	if raw, ok := fields["replyTo"]; ok && json.Unmarshal(raw, &quote) == nil {
+		var quotedID uuid.UUID
+		if json.Unmarshal(quote["id"], &quotedID) == nil && quotedID == msg.ID {
+			preview := models.NewQuotedMessage(msg.ID, msg.SenderID, "", msg.Content, msg.Timestamp, deleted)
+			set(quote, "content", preview.Content)
+			set(quote, "deleted", deleted)
+			set(fields, "replyTo", quote)
+		}
+	}
+
+	if !changed {
+		return payload, false
+	}
+	updated, err := json.Marshal(fields)
+	if err != nil {
+		return payload, false
+	}
+	return updated, true
+}
+
+func scanChatEvents(rows pgx.Rows) ([]*models.ChatEvent, error) {
+	defer rows.Close()
+
+	events := make([]*models.ChatEvent, 0)
+	for rows.Next() {
+		var event models.ChatEvent
+		if err := rows.Scan(&event.Seq, &event.ChatID, &event.MessageID, &event.Type, &event.Payload, &event.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan chat event row: %w", err)
+		}
+		events = append(events, &event)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating chat event rows: %w", err)
+	}
+	return events, nil
+}
+
+var (
+	ErrEventNotFound = fmt.Errorf("chat event not found")
+)
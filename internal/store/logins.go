@@ -0,0 +1,108 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// LoginAttemptStore tracks failed logins for brute-force protection and auditing.
+type LoginAttemptStore interface {
+	// RecordFailedLogin stores the audit record and adds a failure to each throttle key,
+	// restarting counters whose last failure is older than window. It returns the
+	// updated throttles.
+	RecordFailedLogin(ctx context.Context, attempt *models.FailedLogin, keys []string, window time.Duration) ([]*models.LoginThrottle, error)
+	// GetLoginThrottles returns the throttles that exist among keys.
+	GetLoginThrottles(ctx context.Context, keys []string) ([]*models.LoginThrottle, error)
+	// ClearLoginThrottles resets the given keys and returns how many had failures.
+	ClearLoginThrottles(ctx context.Context, keys []string) (int, error)
+}
+
+// PostgresLoginAttemptStore implements LoginAttemptStore with PostgreSQL.
+type PostgresLoginAttemptStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresLoginAttemptStore returns a Postgres-backed LoginAttemptStore implementation.
+func NewPostgresLoginAttemptStore(db *pgxpool.Pool) *PostgresLoginAttemptStore {
+	return &PostgresLoginAttemptStore{db: db}
+}
+
+func (s *PostgresLoginAttemptStore) RecordFailedLogin(ctx context.Context, attempt *models.FailedLogin, keys []string, window time.Duration) ([]*models.LoginThrottle, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	auditQuery := `
+        INSERT INTO failed_logins (id, email, user_id, ip_address, user_agent, reason, attempted_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	_, err = tx.Exec(ctx, auditQuery, attempt.ID, attempt.Email, attempt.UserID, attempt.IPAddress, attempt.UserAgent, attempt.Reason, attempt.AttemptedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record failed login for %s: %w", attempt.Email, err)
+	}
+
+	throttleQuery := `
+        INSERT INTO login_throttles (key, failures, last_failure_at)
+        VALUES ($1, 1, $2)
+        ON CONFLICT (key) DO UPDATE SET
+            failures = CASE WHEN login_throttles.last_failure_at < $3 THEN 1 ELSE login_throttles.failures + 1 END,
+            last_failure_at = EXCLUDED.last_failure_at
+        RETURNING key, failures, last_failure_at
+    `
+	throttles := make([]*models.LoginThrottle, 0, len(keys))
+	for _, key := range keys {
+		t := &models.LoginThrottle{}
+		err := tx.QueryRow(ctx, throttleQuery, key, attempt.AttemptedAt, attempt.AttemptedAt.Add(-window)).Scan(&t.Key, &t.Failures, &t.LastFailureAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update login throttle %s: %w", key, err)
+		}
+		throttles = append(throttles, t)
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return throttles, nil
+}
+
+func (s *PostgresLoginAttemptStore) GetLoginThrottles(ctx context.Context, keys []string) ([]*models.LoginThrottle, error) {
+	query := `SELECT key, failures, last_failure_at FROM login_throttles WHERE key = ANY($1)`
+	rows, err := s.db.Query(ctx, query, keys)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query login throttles: %w", err)
+	}
+	return scanLoginThrottles(rows)
+}
+
+func (s *PostgresLoginAttemptStore) ClearLoginThrottles(ctx context.Context, keys []string) (int, error) {
+	result, err := s.db.Exec(ctx, `DELETE FROM login_throttles WHERE key = ANY($1)`, keys)
+	if err != nil {
+		return 0, fmt.Errorf("failed to clear login throttles: %w", err)
+	}
+	return int(result.RowsAffected()), nil
+}
+
+func scanLoginThrottles(rows pgx.Rows) ([]*models.LoginThrottle, error) {
+	defer rows.Close()
+
+	throttles := make([]*models.LoginThrottle, 0)
+	for rows.Next() {
+		t := &models.LoginThrottle{}
+		if err := rows.Scan(&t.Key, &t.Failures, &t.LastFailureAt); err != nil {
+			return nil, fmt.Errorf("failed to scan login throttle row: %w", err)
+		}
+		throttles = append(throttles, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating login throttle rows: %w", err)
+	}
+	return throttles, nil
+}
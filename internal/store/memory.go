@@ -0,0 +1,140 @@
+package store
+
+import (
+	"sync"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemoryDB is the shared, mutex-guarded state behind the in-memory stores.
+// A single MemoryDB should be passed to every NewMemory*Store constructor so
+// that joins (participants, message senders) see the same data.
+type MemoryDB struct {
+	mu sync.RWMutex
+
+	users        map[uuid.UUID]*models.User
+	chats        map[uuid.UUID]*models.Chat
+	participants map[uuid.UUID]map[uuid.UUID]*memoryMember // chatID -> userID -> membership
+	messages     map[uuid.UUID]*models.Message
+	chatMessages map[uuid.UUID][]uuid.UUID // chatID -> message IDs in insertion order
+	messageEdits map[uuid.UUID][]*models.MessageEdit
+	reactions    map[uuid.UUID][]*memoryReaction // messageID -> reactions, oldest first
+	attachments  map[uuid.UUID]*models.Attachment
+	chatEvents   []*models.ChatEvent // seq N is chatEvents[N-1]
+
+	sessions      map[uuid.UUID]*models.Session
+	refreshTokens map[string]*models.RefreshToken // token hash -> token
+	actionTokens  map[string]*models.ActionToken  // token hash -> token
+
+	failedLogins   []*models.FailedLogin
+	loginThrottles map[string]*models.LoginThrottle
+
+	blocks map[uuid.UUID]map[uuid.UUID]time.Time // blockerID -> blockedID -> blocked at
+}
+
+type memoryMember struct {
+	role      models.ChatRole
+	joinedAt  time.Time
+	muted     bool
+	delivered *memoryReceiptPointer
+	read      *memoryReceiptPointer
+}
+
+// memoryReaction is one user's emoji reaction to a message.
+type memoryReaction struct {
+	userID uuid.UUID
+	emoji  string
+	at     time.Time
+}
+
+// memoryReceiptPointer marks the newest message a member has received or read.
+type memoryReceiptPointer struct {
+	messageID uuid.UUID
+	messageAt time.Time
+	updatedAt time.Time
+}
+
+// reached reports whether the pointer is at or past a message sent at t.
+func (p *memoryReceiptPointer) reached(t time.Time) bool {
+	return p != nil && !p.messageAt.Before(t)
+}
+
+// unreadCountLocked counts messages from others in a chat that the user has not read yet.
+// Callers must hold mu.
+func (db *MemoryDB) unreadCountLocked(chatID uuid.UUID, userID uuid.UUID) int {
+	member, ok := db.participants[chatID][userID]
+	if !ok {
+		return 0
+	}
+	count := 0
+	for _, msgID := range db.chatMessages[chatID] {
+		msg := db.messages[msgID]
+		if msg.SenderID != userID && msg.DeletedAt == nil && !member.read.reached(msg.Timestamp) {
+			count++
+		}
+	}
+	return count
+}
+
+// NewMemoryDB returns an empty in-memory database.
+func NewMemoryDB() *MemoryDB {
+	return &MemoryDB{
+		users:        make(map[uuid.UUID]*models.User),
+		chats:        make(map[uuid.UUID]*models.Chat),
+		participants: make(map[uuid.UUID]map[uuid.UUID]*memoryMember),
+		messages:     make(map[uuid.UUID]*models.Message),
+		chatMessages: make(map[uuid.UUID][]uuid.UUID),
+		messageEdits: make(map[uuid.UUID][]*models.MessageEdit),
+		reactions:    make(map[uuid.UUID][]*memoryReaction),
+		attachments:  make(map[uuid.UUID]*models.Attachment),
+
+		sessions:      make(map[uuid.UUID]*models.Session),
+		refreshTokens: make(map[string]*models.RefreshToken),
+		actionTokens:  make(map[string]*models.ActionToken),
+
+		loginThrottles: make(map[string]*models.LoginThrottle),
+
+		blocks: make(map[uuid.UUID]map[uuid.UUID]time.Time),
+	}
+}
+
+// publicUserLocked returns a stored user as seen by the people they share a chat with.
+// Callers must hold mu.
+func (db *MemoryDB) publicUserLocked(userID uuid.UUID) *models.PublicUser {
+	if u, ok := db.users[userID]; ok {
+		return u.ProfileFor(models.RelationshipContact)
+	}
+	return nil
+}
+
+// messageWithSenderLocked copies a stored message and attaches its sender and a preview of
+// the message it replies to. Callers must hold mu.
+func (db *MemoryDB) messageWithSenderLocked(msg *models.Message) *models.Message {
+	cp := *msg
+	cp.Sender = db.publicUserLocked(msg.SenderID)
+	cp.Deleted = msg.DeletedAt != nil
+	if msg.ReplyToID != nil {
+		if parent, ok := db.messages[*msg.ReplyToID]; ok {
+			var username string
+			if u, ok := db.users[parent.SenderID]; ok {
+				username = u.Username
+			}
+			cp.ReplyTo = models.NewQuotedMessage(parent.ID, parent.SenderID, username, parent.Content, parent.Timestamp, parent.DeletedAt != nil)
+		}
+	}
+	return &cp
+}
+
+var (
+	_ UserStore         = (*MemoryUserStore)(nil)
+	_ ChatStore         = (*MemoryChatStore)(nil)
+	_ MessageStore      = (*MemoryMessageStore)(nil)
+	_ SessionStore      = (*MemorySessionStore)(nil)
+	_ LoginAttemptStore = (*MemoryLoginAttemptStore)(nil)
+	_ ActionTokenStore  = (*MemoryActionTokenStore)(nil)
+	_ BlockStore        = (*MemoryBlockStore)(nil)
+	_ AttachmentStore   = (*MemoryAttachmentStore)(nil)
+)
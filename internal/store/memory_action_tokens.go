@@ -0,0 +1,56 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"blinkchat-backend/internal/models"
+)
+
+// MemoryActionTokenStore implements ActionTokenStore on top of a MemoryDB.
+type MemoryActionTokenStore struct {
+	db *MemoryDB
+}
+
+// NewMemoryActionTokenStore returns an in-memory ActionTokenStore implementation.
+func NewMemoryActionTokenStore(db *MemoryDB) *MemoryActionTokenStore {
+	return &MemoryActionTokenStore{db: db}
+}
+
+func (s *MemoryActionTokenStore) CreateActionToken(ctx context.Context, token *models.ActionToken) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.users[token.UserID]; !ok {
+		return ErrUserNotFound
+	}
+	for _, existing := range s.db.actionTokens {
+		if existing.UserID == token.UserID && existing.Purpose == token.Purpose && existing.UsedAt == nil {
+			usedAt := token.CreatedAt
+			existing.UsedAt = &usedAt
+		}
+	}
+	cp := *token
+	s.db.actionTokens[token.TokenHash] = &cp
+	return nil
+}
+
+func (s *MemoryActionTokenStore) ConsumeActionToken(ctx context.Context, purpose string, tokenHash string) (*models.ActionToken, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	token, ok := s.db.actionTokens[tokenHash]
+	if !ok || token.Purpose != purpose {
+		return nil, ErrActionTokenNotFound
+	}
+	if token.UsedAt != nil {
+		return nil, ErrActionTokenUsed
+	}
+	now := time.Now()
+	if now.After(token.ExpiresAt) {
+		return nil, ErrActionTokenExpired
+	}
+	token.UsedAt = &now
+	cp := *token
+	return &cp, nil
+}
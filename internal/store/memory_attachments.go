@@ -0,0 +1,129 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemoryAttachmentStore implements AttachmentStore on top of a MemoryDB.
+type MemoryAttachmentStore struct {
+	db *MemoryDB
+}
+
+// NewMemoryAttachmentStore returns an in-memory AttachmentStore implementation.
+func NewMemoryAttachmentStore(db *MemoryDB) *MemoryAttachmentStore {
+	return &MemoryAttachmentStore{db: db}
+}
+
+func (s *MemoryAttachmentStore) CreateAttachment(ctx context.Context, a *models.Attachment) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, exists := s.db.attachments[a.ID]; exists {
+		return fmt.Errorf("failed to create attachment: duplicate attachment ID %s", a.ID)
+	}
+	s.db.attachments[a.ID] = copyAttachment(a)
+	return nil
+}
+
+func (s *MemoryAttachmentStore) GetAttachmentByID(ctx context.Context, attachmentID uuid.UUID) (*models.Attachment, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	a, ok := s.db.attachments[attachmentID]
+	if !ok {
+		return nil, ErrAttachmentNotFound
+	}
+	return copyAttachment(a), nil
+}
+
+func (s *MemoryAttachmentStore) GetAttachmentsByIDs(ctx context.Context, ids []uuid.UUID) ([]*models.Attachment, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	attachments := make([]*models.Attachment, 0, len(ids))
+	for _, id := range ids {
+		if a, ok := s.db.attachments[id]; ok {
+			attachments = append(attachments, copyAttachment(a))
+		}
+	}
+	return attachments, nil
+}
+
+func (s *MemoryAttachmentStore) GetMessageAttachments(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID][]*models.Attachment, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	wanted := make(map[uuid.UUID]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		wanted[id] = true
+	}
+	byMessage := make(map[uuid.UUID][]*models.Attachment)
+	for _, a := range s.db.attachments {
+		if a.MessageID != nil && wanted[*a.MessageID] {
+			byMessage[*a.MessageID] = append(byMessage[*a.MessageID], copyAttachment(a))
+		}
+	}
+	for _, attachments := range byMessage {
+		sort.Slice(attachments, func(i, j int) bool {
+			if !attachments[i].CreatedAt.Equal(attachments[j].CreatedAt) {
+				return attachments[i].CreatedAt.Before(attachments[j].CreatedAt)
+			}
+			return attachments[i].ID.String() < attachments[j].ID.String()
+		})
+	}
+	return byMessage, nil
+}
+
+func (s *MemoryAttachmentStore) GetPendingAttachmentIDs(ctx context.Context) ([]uuid.UUID, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	pending := make([]*models.Attachment, 0)
+	for _, a := range s.db.attachments {
+		if a.ProcessingStatus == models.ProcessingPending {
+			pending = append(pending, a)
+		}
+	}
+	sort.Slice(pending, func(i, j int) bool { return pending[i].CreatedAt.Before(pending[j].CreatedAt) })
+	ids := make([]uuid.UUID, len(pending))
+	for i, a := range pending {
+		ids[i] = a.ID
+	}
+	return ids, nil
+}
+
+func (s *MemoryAttachmentStore) UpdateAttachmentProcessing(ctx context.Context, a *models.Attachment) (*models.Attachment, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored, ok := s.db.attachments[a.ID]
+	if !ok {
+		return nil, ErrAttachmentNotFound
+	}
+	stored.ProcessingStatus = a.ProcessingStatus
+	stored.Size = a.Size
+	stored.Width, stored.Height = a.Width, a.Height
+	stored.Blurhash = a.Blurhash
+	stored.Thumbnails = storedThumbnails(a.Thumbnails)
+	if len(stored.Thumbnails) == 0 {
+		stored.Thumbnails = nil
+	}
+	stored.ProcessedAt = a.ProcessedAt
+	return copyAttachment(stored), nil
+}
+
+// copyAttachment copies an attachment, its thumbnails included, without download links.
+func copyAttachment(a *models.Attachment) *models.Attachment {
+	cp := *a
+	cp.URL, cp.URLExpiresAt = "", nil
+	if a.Thumbnails != nil {
+		cp.Thumbnails = storedThumbnails(a.Thumbnails)
+	}
+	return &cp
+}
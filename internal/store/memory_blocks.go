@@ -0,0 +1,88 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemoryBlockStore implements BlockStore on top of a MemoryDB.
+type MemoryBlockStore struct {
+	db *MemoryDB
+}
+
+// NewMemoryBlockStore returns an in-memory BlockStore implementation.
+func NewMemoryBlockStore(db *MemoryDB) *MemoryBlockStore {
+	return &MemoryBlockStore{db: db}
+}
+
+func (s *MemoryBlockStore) BlockUser(ctx context.Context, blockerID, blockedID uuid.UUID, at time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if s.db.blocks[blockerID] == nil {
+		s.db.blocks[blockerID] = make(map[uuid.UUID]time.Time)
+	}
+	if _, exists := s.db.blocks[blockerID][blockedID]; !exists {
+		s.db.blocks[blockerID][blockedID] = at
+	}
+	return nil
+}
+
+func (s *MemoryBlockStore) UnblockUser(ctx context.Context, blockerID, blockedID uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	delete(s.db.blocks[blockerID], blockedID)
+	return nil
+}
+
+func (s *MemoryBlockStore) GetBlockedUsers(ctx context.Context, blockerID uuid.UUID) ([]*models.BlockedUser, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	blocked := make([]*models.BlockedUser, 0, len(s.db.blocks[blockerID]))
+	for blockedID, at := range s.db.blocks[blockerID] {
+		if u, ok := s.db.users[blockedID]; ok {
+			blocked = append(blocked, &models.BlockedUser{User: u.ToPublicUser(), BlockedAt: at})
+		}
+	}
+	sort.Slice(blocked, func(i, j int) bool {
+		if !blocked[i].BlockedAt.Equal(blocked[j].BlockedAt) {
+			return blocked[i].BlockedAt.After(blocked[j].BlockedAt)
+		}
+		return blocked[i].User.ID.String() < blocked[j].User.ID.String()
+	})
+	return blocked, nil
+}
+
+func (s *MemoryBlockStore) IsBlockedBetween(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	_, blocked := s.db.blocks[userID][otherID]
+	_, blockedBy := s.db.blocks[otherID][userID]
+	return blocked || blockedBy, nil
+}
+
+func (s *MemoryBlockStore) GetBlockedPeerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	peerIDs := make([]uuid.UUID, 0)
+	for blockedID := range s.db.blocks[userID] {
+		peerIDs = append(peerIDs, blockedID)
+	}
+	for blockerID, blocked := range s.db.blocks {
+		if _, ok := blocked[userID]; ok {
+			if _, alsoBlocked := s.db.blocks[userID][blockerID]; !alsoBlocked {
+				peerIDs = append(peerIDs, blockerID)
+			}
+		}
+	}
+	return peerIDs, nil
+}
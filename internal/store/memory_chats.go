@@ -0,0 +1,347 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemoryChatStore implements ChatStore on top of a MemoryDB.
+type MemoryChatStore struct {
+	db *MemoryDB
+}
+
+// NewMemoryChatStore returns an in-memory ChatStore implementation.
+func NewMemoryChatStore(db *MemoryDB) *MemoryChatStore {
+	return &MemoryChatStore{db: db}
+}
+
+func (s *MemoryChatStore) CreateChat(ctx context.Context, participantIDs []uuid.UUID) (*models.Chat, error) {
+	if len(participantIDs) == 0 {
+		return nil, fmt.Errorf("at least one participant is required to create a chat")
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now()
+	chat := &models.Chat{ID: uuid.New(), CreatedAt: now}
+	members := make(map[uuid.UUID]*memoryMember, len(participantIDs))
+	for _, userID := range participantIDs {
+		if _, dup := members[userID]; dup {
+			return nil, fmt.Errorf("failed to add participant %s to chat %s: duplicate participant", userID, chat.ID)
+		}
+		members[userID] = &memoryMember{role: models.RoleMember, joinedAt: now}
+	}
+
+	s.db.chats[chat.ID] = chat
+	s.db.participants[chat.ID] = members
+	return &models.Chat{ID: chat.ID, CreatedAt: chat.CreatedAt}, nil
+}
+
+// CreateGroupChat creates a named group chat with the creator as its first admin.
+func (s *MemoryChatStore) CreateGroupChat(ctx context.Context, name string, creatorID uuid.UUID, memberIDs []uuid.UUID) (*models.Chat, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now()
+	chat := &models.Chat{ID: uuid.New(), Name: name, IsGroup: true, CreatedBy: &creatorID, CreatedAt: now}
+	members := map[uuid.UUID]*memoryMember{
+		creatorID: {role: models.RoleAdmin, joinedAt: now},
+	}
+	for _, userID := range memberIDs {
+		if _, exists := members[userID]; !exists {
+			members[userID] = &memoryMember{role: models.RoleMember, joinedAt: now}
+		}
+	}
+
+	s.db.chats[chat.ID] = chat
+	s.db.participants[chat.ID] = members
+	cp := *chat
+	return &cp, nil
+}
+
+// sortedMemberIDsLocked returns chat member IDs ordered by join time. Callers must hold mu.
+func (db *MemoryDB) sortedMemberIDsLocked(chatID uuid.UUID) []uuid.UUID {
+	members := db.participants[chatID]
+	ids := make([]uuid.UUID, 0, len(members))
+	for userID := range members {
+		ids = append(ids, userID)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		ti, tj := members[ids[i]].joinedAt, members[ids[j]].joinedAt
+		if ti.Equal(tj) {
+			return ids[i].String() < ids[j].String()
+		}
+		return ti.Before(tj)
+	})
+	return ids
+}
+
+// participantsLocked returns chat members ordered by join time. Callers must hold mu.
+func (s *MemoryChatStore) participantsLocked(chatID uuid.UUID, exclude *uuid.UUID) []*models.PublicUser {
+	var participants []*models.PublicUser
+	for _, userID := range s.db.sortedMemberIDsLocked(chatID) {
+		if exclude != nil && userID == *exclude {
+			continue
+		}
+		if p := s.db.publicUserLocked(userID); p != nil {
+			participants = append(participants, p)
+		}
+	}
+	return participants
+}
+
+// GetChatMembers returns every participant of a chat with their role, oldest first.
+func (s *MemoryChatStore) GetChatMembers(ctx context.Context, chatID uuid.UUID) ([]*models.ChatMember, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	var members []*models.ChatMember
+	for _, userID := range s.db.sortedMemberIDsLocked(chatID) {
+		p := s.db.publicUserLocked(userID)
+		if p == nil {
+			continue
+		}
+		m := s.db.participants[chatID][userID]
+		members = append(members, &models.ChatMember{User: p, Role: m.role, JoinedAt: m.joinedAt})
+	}
+	return members, nil
+}
+
+// GetParticipantRole returns the user's role in the chat, or ErrNotParticipant.
+func (s *MemoryChatStore) GetParticipantRole(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (models.ChatRole, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	m, ok := s.db.participants[chatID][userID]
+	if !ok {
+		return "", ErrNotParticipant
+	}
+	return m.role, nil
+}
+
+// IsParticipant reports whether the user is a member of the chat.
+func (s *MemoryChatStore) IsParticipant(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	_, ok := s.db.participants[chatID][userID]
+	return ok, nil
+}
+
+// SetParticipantRole changes an existing participant's role.
+func (s *MemoryChatStore) SetParticipantRole(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, role models.ChatRole) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	m, ok := s.db.participants[chatID][userID]
+	if !ok {
+		return ErrNotParticipant
+	}
+	m.role = role
+	return nil
+}
+
+// SetChatMuted mutes or unmutes a chat for one participant.
+func (s *MemoryChatStore) SetChatMuted(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, muted bool) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	m, ok := s.db.participants[chatID][userID]
+	if !ok {
+		return ErrNotParticipant
+	}
+	m.muted = muted
+	return nil
+}
+
+func (s *MemoryChatStore) GetAllParticipantsInChat(ctx context.Context, chatID uuid.UUID) ([]*models.PublicUser, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+	return s.participantsLocked(chatID, nil), nil
+}
+
+func (s *MemoryChatStore) GetChatPartnerIDs(ctx context.Context, userID uuid.UUID) ([]uuid.UUID, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	seen := make(map[uuid.UUID]bool)
+	partnerIDs := make([]uuid.UUID, 0)
+	for _, members := range s.db.participants {
+		if _, ok := members[userID]; !ok {
+			continue
+		}
+		for memberID := range members {
+			if memberID != userID && !seen[memberID] {
+				seen[memberID] = true
+				partnerIDs = append(partnerIDs, memberID)
+			}
+		}
+	}
+	return partnerIDs, nil
+}
+
+func (s *MemoryChatStore) SharesChat(ctx context.Context, userID, otherID uuid.UUID) (bool, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, members := range s.db.participants {
+		_, mine := members[userID]
+		_, theirs := members[otherID]
+		if mine && theirs {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func (s *MemoryChatStore) GetChatByID(ctx context.Context, chatID uuid.UUID) (*models.Chat, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	stored, ok := s.db.chats[chatID]
+	if !ok {
+		return nil, ErrChatNotFound
+	}
+	chat := *stored
+	chat.OtherParticipants = s.participantsLocked(chatID, nil)
+	return &chat, nil
+}
+
+func (s *MemoryChatStore) GetChatByParticipantIDs(ctx context.Context, participantIDs []uuid.UUID) (*models.Chat, error) {
+	if len(participantIDs) != 2 {
+		return nil, fmt.Errorf("GetChatByParticipantIDs expects exactly two participant IDs for 1:1 chat lookup")
+	}
+	userA, userB := participantIDs[0], participantIDs[1]
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for chatID, members := range s.db.participants {
+		if len(members) != 2 || s.db.chats[chatID].IsGroup {
+			continue
+		}
+		_, hasA := members[userA]
+		_, hasB := members[userB]
+		if hasA && hasB {
+			stored := s.db.chats[chatID]
+			return &models.Chat{ID: stored.ID, CreatedAt: stored.CreatedAt}, nil
+		}
+	}
+	return nil, ErrChatNotFound
+}
+
+func (s *MemoryChatStore) GetUserChats(ctx context.Context, userID uuid.UUID, limit, offset int) ([]*models.Chat, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return paginate(s.userChatsLocked(userID), limit, offset), nil
+}
+
+// GetUserChatsPage returns a keyset-paginated page of the user's chats, most recently active first.
+func (s *MemoryChatStore) GetUserChatsPage(ctx context.Context, userID uuid.UUID, page models.CursorPage) (*models.Page[*models.Chat], error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return keysetPage(s.userChatsLocked(userID), page, (*models.Chat).Cursor), nil
+}
+
+// userChatsLocked returns the user's chats ordered by last activity, newest first. Callers must hold mu.
+func (s *MemoryChatStore) userChatsLocked(userID uuid.UUID) []*models.Chat {
+	var chatsSlice []*models.Chat
+	for chatID, members := range s.db.participants {
+		if _, ok := members[userID]; !ok {
+			continue
+		}
+		stored := *s.db.chats[chatID]
+		chat := &stored
+		chat.OtherParticipants = s.participantsLocked(chatID, &userID)
+		if chat.OtherParticipants == nil {
+			chat.OtherParticipants = []*models.PublicUser{}
+		}
+		if last := s.lastMessageLocked(chatID); last != nil {
+			chat.LastMessage = s.db.messageWithSenderLocked(last)
+		}
+		chat.UnreadCount = s.db.unreadCountLocked(chatID, userID)
+		chat.Muted = members[userID].muted
+		chatsSlice = append(chatsSlice, chat)
+	}
+
+	sort.Slice(chatsSlice, func(i, j int) bool {
+		return chatsSlice[j].Cursor().Less(chatsSlice[i].Cursor())
+	})
+	return chatsSlice
+}
+
+// lastMessageLocked returns the newest message in a chat. Callers must hold mu.
+func (s *MemoryChatStore) lastMessageLocked(chatID uuid.UUID) *models.Message {
+	var last *models.Message
+	for _, msgID := range s.db.chatMessages[chatID] {
+		msg := s.db.messages[msgID]
+		if last == nil || msg.Timestamp.After(last.Timestamp) {
+			last = msg
+		}
+	}
+	return last
+}
+
+func (s *MemoryChatStore) AddUserToChat(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	members, ok := s.db.participants[chatID]
+	if !ok {
+		return fmt.Errorf("failed to add user %s to chat %s: %w", userID, chatID, ErrChatNotFound)
+	}
+	if _, exists := members[userID]; !exists {
+		members[userID] = &memoryMember{role: models.RoleMember, joinedAt: time.Now()}
+	}
+	return nil
+}
+
+func (s *MemoryChatStore) RemoveUserFromChat(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if members, ok := s.db.participants[chatID]; ok {
+		delete(members, userID)
+	}
+	return nil
+}
+
+// paginate applies LIMIT/OFFSET semantics to an already ordered slice.
+func paginate[T any](items []T, limit, offset int) []T {
+	if offset >= len(items) {
+		return nil
+	}
+	items = items[offset:]
+	if limit >= 0 && limit < len(items) {
+		items = items[:limit]
+	}
+	return items
+}
+
+// keysetPage applies cursor semantics to a slice ordered newest first.
+func keysetPage[T any](items []T, page models.CursorPage, cursorOf func(T) models.Cursor) *models.Page[T] {
+	var walk []T
+	if page.Forward() {
+		for i := len(items) - 1; i >= 0; i-- {
+			if page.After.Less(cursorOf(items[i])) {
+				walk = append(walk, items[i])
+			}
+		}
+	} else {
+		for _, item := range items {
+			if page.Before == nil || cursorOf(item).Less(*page.Before) {
+				walk = append(walk, item)
+			}
+		}
+	}
+	return models.NewPage(paginate(walk, page.Limit+1, 0), page, cursorOf)
+}
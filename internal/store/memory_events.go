@@ -0,0 +1,120 @@
+package store
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemoryEventStore implements EventStore on top of a MemoryDB.
+type MemoryEventStore struct {
+	db *MemoryDB
+}
+
+// NewMemoryEventStore returns an in-memory EventStore implementation.
+func NewMemoryEventStore(db *MemoryDB) *MemoryEventStore {
+	return &MemoryEventStore{db: db}
+}
+
+func (s *MemoryEventStore) AppendChatEvent(ctx context.Context, chatID uuid.UUID, messageID *uuid.UUID, eventType string, payload interface{}) (*models.ChatEvent, error) {
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode %s event payload: %w", eventType, err)
+	}
+
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.chats[chatID]; !ok {
+		return nil, fmt.Errorf("failed to append %s event: %w", eventType, ErrChatNotFound)
+	}
+	event := &models.ChatEvent{
+		Seq:       int64(len(s.db.chatEvents)) + 1,
+		ChatID:    chatID,
+		MessageID: messageID,
+		Type:      eventType,
+		Payload:   raw,
+		CreatedAt: time.Now(),
+	}
+	s.db.chatEvents = append(s.db.chatEvents, event)
+	cp := *event
+	return &cp, nil
+}
+
+func (s *MemoryEventStore) GetChatEvent(ctx context.Context, seq int64) (*models.ChatEvent, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	if seq < 1 || seq > int64(len(s.db.chatEvents)) {
+		return nil, ErrEventNotFound
+	}
+	cp := *s.db.chatEvents[seq-1]
+	return &cp, nil
+}
+
+func (s *MemoryEventStore) GetChatEventsAfter(ctx context.Context, chatID uuid.UUID, afterSeq int64, limit int) ([]*models.ChatEvent, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.eventsAfterLocked(afterSeq, limit, func(e *models.ChatEvent) bool {
+		return e.ChatID == chatID
+	}), nil
+}
+
+func (s *MemoryEventStore) GetUserEventsAfter(ctx context.Context, userID uuid.UUID, afterSeq int64, limit int) ([]*models.ChatEvent, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.eventsAfterLocked(afterSeq, limit, func(e *models.ChatEvent) bool {
+		_, ok := s.db.participants[e.ChatID][userID]
+		return ok
+	}), nil
+}
+
+func (s *MemoryEventStore) GetMessageEventSeq(ctx context.Context, messageID uuid.UUID) (int64, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, e := range s.db.chatEvents {
+		if e.MessageID != nil && *e.MessageID == messageID {
+			return e.Seq, nil
+		}
+	}
+	return 0, ErrEventNotFound
+}
+
+func (s *MemoryEventStore) RedactMessageEvents(ctx context.Context, msg *models.Message) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, e := range s.db.chatEvents {
+		own := e.MessageID != nil && *e.MessageID == msg.ID
+		if !own && e.ChatID != msg.ChatID {
+			continue
+		}
+		if updated, changed := redactEventPayload(e.Payload, own, msg); changed {
+			e.Payload = updated
+		}
+	}
+	return nil
+}
+
+// eventsAfterLocked scans the log from afterSeq. Seqs are 1-based slice positions. Callers must hold mu.
+func (s *MemoryEventStore) eventsAfterLocked(afterSeq int64, limit int, match func(*models.ChatEvent) bool) []*models.ChatEvent {
+	events := make([]*models.ChatEvent, 0)
+	if afterSeq < 0 {
+		afterSeq = 0
+	}
+	for i := afterSeq; i < int64(len(s.db.chatEvents)) && len(events) < limit; i++ {
+		if e := s.db.chatEvents[i]; match(e) {
+			cp := *e
+			events = append(events, &cp)
+		}
+	}
+	return events
+}
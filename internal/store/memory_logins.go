@@ -0,0 +1,71 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"blinkchat-backend/internal/models"
+)
+
+// MemoryLoginAttemptStore implements LoginAttemptStore on top of a MemoryDB.
+type MemoryLoginAttemptStore struct {
+	db *MemoryDB
+}
+
+// NewMemoryLoginAttemptStore returns an in-memory LoginAttemptStore implementation.
+func NewMemoryLoginAttemptStore(db *MemoryDB) *MemoryLoginAttemptStore {
+	return &MemoryLoginAttemptStore{db: db}
+}
+
+func (s *MemoryLoginAttemptStore) RecordFailedLogin(ctx context.Context, attempt *models.FailedLogin, keys []string, window time.Duration) ([]*models.LoginThrottle, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	cp := *attempt
+	s.db.failedLogins = append(s.db.failedLogins, &cp)
+
+	throttles := make([]*models.LoginThrottle, 0, len(keys))
+	for _, key := range keys {
+		t, ok := s.db.loginThrottles[key]
+		if !ok {
+			t = &models.LoginThrottle{Key: key}
+			s.db.loginThrottles[key] = t
+		}
+		if t.LastFailureAt.Before(attempt.AttemptedAt.Add(-window)) {
+			t.Failures = 0
+		}
+		t.Failures++
+		t.LastFailureAt = attempt.AttemptedAt
+		tcp := *t
+		throttles = append(throttles, &tcp)
+	}
+	return throttles, nil
+}
+
+func (s *MemoryLoginAttemptStore) GetLoginThrottles(ctx context.Context, keys []string) ([]*models.LoginThrottle, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	throttles := make([]*models.LoginThrottle, 0, len(keys))
+	for _, key := range keys {
+		if t, ok := s.db.loginThrottles[key]; ok {
+			cp := *t
+			throttles = append(throttles, &cp)
+		}
+	}
+	return throttles, nil
+}
+
+func (s *MemoryLoginAttemptStore) ClearLoginThrottles(ctx context.Context, keys []string) (int, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	cleared := 0
+	for _, key := range keys {
+		if _, ok := s.db.loginThrottles[key]; ok {
+			delete(s.db.loginThrottles, key)
+			cleared++
+		}
+	}
+	return cleared, nil
+}
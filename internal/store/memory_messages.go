@@ -0,0 +1,382 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemoryMessageStore implements MessageStore on top of a MemoryDB.
+type MemoryMessageStore struct {
+	db *MemoryDB
+}
+
+// NewMemoryMessageStore returns an in-memory MessageStore implementation.
+func NewMemoryMessageStore(db *MemoryDB) *MemoryMessageStore {
+	return &MemoryMessageStore{db: db}
+}
+
+func (s *MemoryMessageStore) CreateMessage(ctx context.Context, message *models.Message) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, exists := s.db.messages[message.ID]; exists {
+		return fmt.Errorf("failed to create message: duplicate message ID %s", message.ID)
+	}
+	if _, ok := s.db.chats[message.ChatID]; !ok {
+		return fmt.Errorf("failed to create message: %w", ErrChatNotFound)
+	}
+	for _, a := range message.Attachments {
+		stored, ok := s.db.attachments[a.ID]
+		if !ok || stored.UploaderID != message.SenderID || stored.MessageID != nil {
+			return ErrAttachmentUnavailable
+		}
+	}
+
+	cp := *message
+	cp.Sender = nil
+	cp.ReplyTo = nil
+	cp.Attachments = nil
+	s.db.messages[message.ID] = &cp
+	s.db.chatMessages[message.ChatID] = append(s.db.chatMessages[message.ChatID], message.ID)
+	for _, a := range message.Attachments {
+		messageID, chatID := message.ID, message.ChatID
+		stored := s.db.attachments[a.ID]
+		stored.MessageID, stored.ChatID = &messageID, &chatID
+		a.MessageID, a.ChatID = &messageID, &chatID
+	}
+	return nil
+}
+
+func (s *MemoryMessageStore) GetMessagesByChatID(ctx context.Context, chatID uuid.UUID, limit, offset int) ([]*models.Message, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	page := paginate(s.chatMessagesLocked(chatID), limit, offset)
+	if page == nil {
+		page = make([]*models.Message, 0)
+	}
+	return page, nil
+}
+
+// GetMessagesPage returns a keyset-paginated page of a chat's messages, newest first.
+func (s *MemoryMessageStore) GetMessagesPage(ctx context.Context, chatID uuid.UUID, page models.CursorPage) (*models.Page[*models.Message], error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return keysetPage(s.chatMessagesLocked(chatID), page, (*models.Message).Cursor), nil
+}
+
+// chatMessagesLocked returns a chat's messages with their senders, newest first. Callers must hold mu.
+func (s *MemoryMessageStore) chatMessagesLocked(chatID uuid.UUID) []*models.Message {
+	messages := make([]*models.Message, 0, len(s.db.chatMessages[chatID]))
+	for _, msgID := range s.db.chatMessages[chatID] {
+		messages = append(messages, s.db.messageWithSenderLocked(s.db.messages[msgID]))
+	}
+	sort.SliceStable(messages, func(i, j int) bool {
+		return messages[j].Cursor().Less(messages[i].Cursor())
+	})
+	return messages
+}
+
+// GetRepliesPage returns a keyset-paginated page of the direct replies to a message that
+// have not been deleted, newest first.
+func (s *MemoryMessageStore) GetRepliesPage(ctx context.Context, messageID uuid.UUID, page models.CursorPage) (*models.Page[*models.Message], error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	parent, ok := s.db.messages[messageID]
+	if !ok {
+		return models.NewPage([]*models.Message{}, page, (*models.Message).Cursor), nil
+	}
+	var replies []*models.Message
+	for _, msg := range s.chatMessagesLocked(parent.ChatID) {
+		if msg.ReplyToID != nil && *msg.ReplyToID == messageID && msg.DeletedAt == nil {
+			replies = append(replies, msg)
+		}
+	}
+	return keysetPage(replies, page, (*models.Message).Cursor), nil
+}
+
+// GetReplyCounts counts the direct replies to each of the messages that have not been deleted.
+func (s *MemoryMessageStore) GetReplyCounts(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	wanted := make(map[uuid.UUID]bool, len(messageIDs))
+	for _, id := range messageIDs {
+		wanted[id] = true
+	}
+	counts := make(map[uuid.UUID]int)
+	for _, msg := range s.db.messages {
+		if msg.ReplyToID != nil && wanted[*msg.ReplyToID] && msg.DeletedAt == nil {
+			counts[*msg.ReplyToID]++
+		}
+	}
+	return counts, nil
+}
+
+func (s *MemoryMessageStore) GetMessageByID(ctx context.Context, messageID uuid.UUID) (*models.Message, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	msg, ok := s.db.messages[messageID]
+	if !ok {
+		return nil, ErrMessageNotFound
+	}
+	return s.db.messageWithSenderLocked(msg), nil
+}
+
+func (s *MemoryMessageStore) GetUnreadMessageCountForUserInChat(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	return s.db.unreadCountLocked(chatID, userID), nil
+}
+
+// GetTotalUnreadCount sums the user's unread messages across all of their chats that
+// they have not muted.
+func (s *MemoryMessageStore) GetTotalUnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	total := 0
+	for chatID, members := range s.db.participants {
+		if m, ok := members[userID]; ok && !m.muted {
+			total += s.db.unreadCountLocked(chatID, userID)
+		}
+	}
+	return total, nil
+}
+
+// MarkChatRead marks every message currently in the chat as read by the user.
+func (s *MemoryMessageStore) MarkChatRead(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (uuid.UUID, bool, error) {
+	s.db.mu.RLock()
+	var latest *models.Message
+	for _, msgID := range s.db.chatMessages[chatID] {
+		if msg := s.db.messages[msgID]; latest == nil || latest.Cursor().Less(msg.Cursor()) {
+			latest = msg
+		}
+	}
+	s.db.mu.RUnlock()
+	if latest == nil {
+		return uuid.Nil, false, nil
+	}
+
+	advanced, err := s.MarkMessagesUpTo(ctx, chatID, userID, latest.ID, models.StatusRead)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return latest.ID, advanced, nil
+}
+
+// MarkMessagesUpTo advances the user's receipt pointers and refreshes the aggregate status of
+// the chat's messages.
+func (s *MemoryMessageStore) MarkMessagesUpTo(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, messageID uuid.UUID, status models.MessageStatus) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	msg, ok := s.db.messages[messageID]
+	if !ok || msg.ChatID != chatID {
+		return false, ErrMessageNotFound
+	}
+	member, ok := s.db.participants[chatID][userID]
+	if !ok {
+		return false, ErrNotParticipant
+	}
+
+	now := time.Now()
+	advanced := false
+	if !member.delivered.reached(msg.Timestamp) {
+		member.delivered = &memoryReceiptPointer{messageID: msg.ID, messageAt: msg.Timestamp, updatedAt: now}
+		advanced = true
+	}
+	if status == models.StatusRead && !member.read.reached(msg.Timestamp) {
+		member.read = &memoryReceiptPointer{messageID: msg.ID, messageAt: msg.Timestamp, updatedAt: now}
+		advanced = true
+	}
+	if advanced {
+		s.refreshAggregateStatusLocked(chatID, msg.Timestamp)
+	}
+	return advanced, nil
+}
+
+// refreshAggregateStatusLocked promotes messages sent up to upTo whose every recipient has
+// received (or read) them.
+func (s *MemoryMessageStore) refreshAggregateStatusLocked(chatID uuid.UUID, upTo time.Time) {
+	members := s.db.participants[chatID]
+	for _, msgID := range s.db.chatMessages[chatID] {
+		msg := s.db.messages[msgID]
+		if msg.Timestamp.After(upTo) || msg.Status == models.StatusRead {
+			continue
+		}
+		delivered, read := true, true
+		for userID, member := range members {
+			if userID == msg.SenderID {
+				continue
+			}
+			delivered = delivered && member.delivered.reached(msg.Timestamp)
+			read = read && member.read.reached(msg.Timestamp)
+		}
+		switch {
+		case read:
+			msg.Status = models.StatusRead
+		case delivered:
+			msg.Status = models.StatusDelivered
+		}
+	}
+}
+
+// GetMessageReceipts reports the delivery and read state of a message for each recipient.
+func (s *MemoryMessageStore) GetMessageReceipts(ctx context.Context, messageID uuid.UUID) ([]*models.MessageReceipt, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	msg, ok := s.db.messages[messageID]
+	if !ok {
+		return nil, ErrMessageNotFound
+	}
+	receipts := make([]*models.MessageReceipt, 0)
+	for _, userID := range s.db.sortedMemberIDsLocked(msg.ChatID) {
+		if userID == msg.SenderID {
+			continue
+		}
+		member := s.db.participants[msg.ChatID][userID]
+		var deliveredAt, readAt *time.Time
+		if member.delivered != nil {
+			deliveredAt = &member.delivered.updatedAt
+		}
+		if member.read != nil {
+			readAt = &member.read.updatedAt
+		}
+		receipts = append(receipts, newMessageReceipt(s.db.publicUserLocked(userID),
+			member.delivered.reached(msg.Timestamp), deliveredAt,
+			member.read.reached(msg.Timestamp), readAt))
+	}
+	return receipts, nil
+}
+
+// EditMessage replaces a message's content, recording the previous content in its edit history.
+func (s *MemoryMessageStore) EditMessage(ctx context.Context, messageID uuid.UUID, content string, editedAt time.Time) (*models.Message, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	msg, ok := s.db.messages[messageID]
+	if !ok {
+		return nil, ErrMessageNotFound
+	}
+	if msg.DeletedAt != nil {
+		return nil, ErrMessageDeleted
+	}
+	if msg.Content != content {
+		s.db.messageEdits[messageID] = append(s.db.messageEdits[messageID], &models.MessageEdit{
+			ID:              uuid.New(),
+			MessageID:       messageID,
+			PreviousContent: msg.Content,
+			EditedAt:        editedAt,
+		})
+		msg.Content = content
+		msg.EditedAt = &editedAt
+	}
+	return s.db.messageWithSenderLocked(msg), nil
+}
+
+// DeleteMessage turns a message into a tombstone: its content and edit history are discarded.
+func (s *MemoryMessageStore) DeleteMessage(ctx context.Context, messageID uuid.UUID, deletedAt time.Time) (*models.Message, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	msg, ok := s.db.messages[messageID]
+	if !ok {
+		return nil, ErrMessageNotFound
+	}
+	if msg.DeletedAt != nil {
+		return nil, ErrMessageDeleted
+	}
+	msg.Content = ""
+	msg.DeletedAt = &deletedAt
+	delete(s.db.messageEdits, messageID)
+	delete(s.db.reactions, messageID)
+	return s.db.messageWithSenderLocked(msg), nil
+}
+
+// GetMessageEdits returns a message's previous revisions, oldest first.
+func (s *MemoryMessageStore) GetMessageEdits(ctx context.Context, messageID uuid.UUID) ([]*models.MessageEdit, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	edits := make([]*models.MessageEdit, 0, len(s.db.messageEdits[messageID]))
+	for _, e := range s.db.messageEdits[messageID] {
+		cp := *e
+		edits = append(edits, &cp)
+	}
+	return edits, nil
+}
+
+// AddReaction records userID's emoji reaction to a message and reports whether it is new.
+func (s *MemoryMessageStore) AddReaction(ctx context.Context, messageID uuid.UUID, userID uuid.UUID, emoji string, at time.Time) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	if _, ok := s.db.messages[messageID]; !ok {
+		return false, ErrMessageNotFound
+	}
+	for _, r := range s.db.reactions[messageID] {
+		if r.userID == userID && r.emoji == emoji {
+			return false, nil
+		}
+	}
+	s.db.reactions[messageID] = append(s.db.reactions[messageID], &memoryReaction{userID: userID, emoji: emoji, at: at})
+	return true, nil
+}
+
+// RemoveReaction deletes userID's emoji reaction and reports whether there was one.
+func (s *MemoryMessageStore) RemoveReaction(ctx context.Context, messageID uuid.UUID, userID uuid.UUID, emoji string) (bool, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	reactions := s.db.reactions[messageID]
+	for i, r := range reactions {
+		if r.userID == userID && r.emoji == emoji {
+			s.db.reactions[messageID] = append(reactions[:i:i], reactions[i+1:]...)
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// GetReactionCounts aggregates the reactions to each of the messages, most used emoji first.
+// Ties go to the emoji that was used first.
+func (s *MemoryMessageStore) GetReactionCounts(ctx context.Context, messageIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID][]*models.ReactionCount, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	counts := make(map[uuid.UUID][]*models.ReactionCount)
+	for _, messageID := range messageIDs {
+		// Reactions are stored oldest first, so the first time an emoji is seen is its
+		// earliest use.
+		byEmoji := make(map[string]*models.ReactionCount)
+		var ordered []*models.ReactionCount
+		for _, r := range s.db.reactions[messageID] {
+			rc, ok := byEmoji[r.emoji]
+			if !ok {
+				rc = &models.ReactionCount{Emoji: r.emoji}
+				byEmoji[r.emoji] = rc
+				ordered = append(ordered, rc)
+			}
+			rc.Count++
+			rc.ReactedByMe = rc.ReactedByMe || r.userID == viewerID
+		}
+		if len(ordered) == 0 {
+			continue
+		}
+		sort.SliceStable(ordered, func(i, j int) bool { return ordered[i].Count > ordered[j].Count })
+		counts[messageID] = ordered
+	}
+	return counts, nil
+}
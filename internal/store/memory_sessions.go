@@ -0,0 +1,123 @@
+package store
+
+import (
+	"context"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemorySessionStore implements SessionStore on top of a MemoryDB.
+type MemorySessionStore struct {
+	db *MemoryDB
+}
+
+// NewMemorySessionStore returns an in-memory SessionStore implementation.
+func NewMemorySessionStore(db *MemoryDB) *MemorySessionStore {
+	return &MemorySessionStore{db: db}
+}
+
+// CreateSession stores a new session together with its first refresh token.
+func (s *MemorySessionStore) CreateSession(ctx context.Context, session *models.Session, token *models.RefreshToken) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	stored := *session
+	stored.LastUsedAt = session.CreatedAt
+	s.db.sessions[session.ID] = &stored
+	storedToken := *token
+	s.db.refreshTokens[token.TokenHash] = &storedToken
+	return nil
+}
+
+// GetSession returns the session with the given ID.
+func (s *MemorySessionStore) GetSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	session, ok := s.db.sessions[sessionID]
+	if !ok {
+		return nil, ErrSessionNotFound
+	}
+	cp := *session
+	return &cp, nil
+}
+
+// RevokeSession marks a session as revoked. Revoking an already revoked session is a no-op.
+func (s *MemorySessionStore) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	session, ok := s.db.sessions[sessionID]
+	if !ok {
+		return ErrSessionNotFound
+	}
+	if session.RevokedAt == nil {
+		now := time.Now()
+		session.RevokedAt = &now
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of a user, optionally keeping one,
+// and returns the IDs of the sessions it revoked.
+func (s *MemorySessionStore) RevokeUserSessions(ctx context.Context, userID uuid.UUID, exceptSessionID *uuid.UUID) ([]uuid.UUID, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	now := time.Now()
+	var revoked []uuid.UUID
+	for id, session := range s.db.sessions {
+		if session.UserID != userID || session.RevokedAt != nil {
+			continue
+		}
+		if exceptSessionID != nil && id == *exceptSessionID {
+			continue
+		}
+		session.RevokedAt = &now
+		revoked = append(revoked, id)
+	}
+	return revoked, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for next within the same session.
+func (s *MemorySessionStore) RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.Session, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	token, ok := s.db.refreshTokens[tokenHash]
+	if !ok {
+		return nil, ErrRefreshTokenNotFound
+	}
+	session, ok := s.db.sessions[token.SessionID]
+	if !ok {
+		return nil, ErrRefreshTokenNotFound
+	}
+
+	if !session.IsActive() {
+		cp := *session
+		return &cp, ErrSessionRevoked
+	}
+
+	now := time.Now()
+	if token.UsedAt != nil {
+		session.RevokedAt = &now
+		cp := *session
+		return &cp, ErrRefreshTokenReused
+	}
+	if now.After(token.ExpiresAt) {
+		cp := *session
+		return &cp, ErrRefreshTokenExpired
+	}
+
+	token.UsedAt = &now
+	next.SessionID = session.ID
+	storedNext := *next
+	s.db.refreshTokens[next.TokenHash] = &storedNext
+	session.LastUsedAt = now
+
+	cp := *session
+	return &cp, nil
+}
@@ -0,0 +1,356 @@
+package store
+
+import (
+	"context"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// MemoryUserStore implements UserStore on top of a MemoryDB.
+type MemoryUserStore struct {
+	db *MemoryDB
+}
+
+// NewMemoryUserStore returns an in-memory UserStore implementation.
+func NewMemoryUserStore(db *MemoryDB) *MemoryUserStore {
+	return &MemoryUserStore{db: db}
+}
+
+// CreateUser persists a new user record.
+func (s *MemoryUserStore) CreateUser(ctx context.Context, user *models.User) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	for _, existing := range s.db.users {
+		if existing.Email == user.Email {
+			return ErrEmailExists
+		}
+		if existing.Username == user.Username {
+			return ErrUsernameExists
+		}
+	}
+
+	cp := *user
+	s.db.users[user.ID] = &cp
+	return nil
+}
+
+// GetUserByEmail returns the user with the given email.
+func (s *MemoryUserStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	for _, u := range s.db.users {
+		if u.Email == email && u.DeletedAt == nil {
+			cp := *u
+			return &cp, nil
+		}
+	}
+	return nil, ErrUserNotFound
+}
+
+// GetUserByID returns the user with the given ID.
+func (s *MemoryUserStore) GetUserByID(ctx context.Context, id string) (*models.User, error) {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return nil, ErrUserNotFound
+	}
+
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	u, ok := s.db.users[userID]
+	if !ok || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+	cp := *u
+	return &cp, nil
+}
+
+// UpdateLastSeen records when the user was last connected.
+func (s *MemoryUserStore) UpdateLastSeen(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	u, ok := s.db.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.LastSeenAt = &at
+	return nil
+}
+
+// UpdateUserSettings replaces the user's privacy settings.
+func (s *MemoryUserStore) UpdateUserSettings(ctx context.Context, userID uuid.UUID, settings models.UserSettings) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	u, ok := s.db.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	u.HideLastSeen = settings.HideLastSeen
+	u.EmailVisibility = settings.EmailVisibility
+	u.UpdatedAt = time.Now()
+	return nil
+}
+
+// MarkEmailVerified records that the user confirmed their email address.
+func (s *MemoryUserStore) MarkEmailVerified(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	u, ok := s.db.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	if u.EmailVerifiedAt == nil {
+		u.EmailVerifiedAt = &at
+	}
+	return nil
+}
+
+// UpdatePassword replaces the user's password hash and invalidates their unused password
+// reset tokens.
+func (s *MemoryUserStore) UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	u, ok := s.db.users[userID]
+	if !ok {
+		return ErrUserNotFound
+	}
+	now := time.Now()
+	u.HashedPassword = hashedPassword
+	u.UpdatedAt = now
+	for _, token := range s.db.actionTokens {
+		if token.UserID == userID && token.Purpose == models.ActionResetPassword && token.UsedAt == nil {
+			usedAt := now
+			token.UsedAt = &usedAt
+		}
+	}
+	return nil
+}
+
+// UpdateUserProfile changes the username and email. A changed email must be verified again,
+// and the user's outstanding action tokens, issued for the old address, stop working.
+func (s *MemoryUserStore) UpdateUserProfile(ctx context.Context, userID uuid.UUID, username, email string) error {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	u, ok := s.db.users[userID]
+	if !ok || u.DeletedAt != nil {
+		return ErrUserNotFound
+	}
+	for id, existing := range s.db.users {
+		if id == userID {
+			continue
+		}
+		if existing.Email == email {
+			return ErrEmailExists
+		}
+		if existing.Username == username {
+			return ErrUsernameExists
+		}
+	}
+
+	now := time.Now()
+	if u.Email != email {
+		u.EmailVerifiedAt = nil
+		for _, token := range s.db.actionTokens {
+			if token.UserID == userID && token.UsedAt == nil {
+				usedAt := now
+				token.UsedAt = &usedAt
+			}
+		}
+	}
+	u.Username = username
+	u.Email = email
+	u.UpdatedAt = now
+	return nil
+}
+
+// DeleteUser anonymises the account and removes it from its group chats.
+func (s *MemoryUserStore) DeleteUser(ctx context.Context, userID uuid.UUID, at time.Time) ([]uuid.UUID, error) {
+	s.db.mu.Lock()
+	defer s.db.mu.Unlock()
+
+	u, ok := s.db.users[userID]
+	if !ok || u.DeletedAt != nil {
+		return nil, ErrUserNotFound
+	}
+
+	groupIDs := make([]uuid.UUID, 0)
+	for chatID, members := range s.db.participants {
+		me, ok := members[userID]
+		if !ok || !s.db.chats[chatID].IsGroup {
+			continue
+		}
+		if me.role == models.RoleAdmin {
+			s.db.handOverAdminLocked(chatID, userID)
+		}
+		delete(members, userID)
+		groupIDs = append(groupIDs, chatID)
+	}
+
+	u.Username = tombstoneUsername(userID)
+	u.Email = tombstoneEmail(userID)
+	u.HashedPassword = ""
+	u.LastSeenAt = nil
+	u.HideLastSeen = true
+	u.EmailVerifiedAt = nil
+	u.EmailVisibility = models.VisibilityNobody
+	u.DeletedAt = &at
+	u.UpdatedAt = at
+
+	for _, session := range s.db.sessions {
+		if session.UserID == userID && session.RevokedAt == nil {
+			session.RevokedAt = &at
+		}
+	}
+	for hash, token := range s.db.actionTokens {
+		if token.UserID == userID {
+			delete(s.db.actionTokens, hash)
+		}
+	}
+	return groupIDs, nil
+}
+
+// handOverAdminLocked promotes the longest-standing other member of a chat when leavingID
+// is its only admin. Callers must hold mu.
+func (db *MemoryDB) handOverAdminLocked(chatID uuid.UUID, leavingID uuid.UUID) {
+	var successor *memoryMember
+	for _, memberID := range db.sortedMemberIDsLocked(chatID) {
+		if memberID == leavingID {
+			continue
+		}
+		m := db.participants[chatID][memberID]
+		if m.role == models.RoleAdmin {
+			return
+		}
+		if successor == nil {
+			successor = m
+		}
+	}
+	if successor != nil {
+		successor.role = models.RoleAdmin
+	}
+}
+
+// SearchUsers returns one page of users matching the search, best matches first.
+func (s *MemoryUserStore) SearchUsers(ctx context.Context, search models.UserSearch) ([]*models.PublicUser, error) {
+	s.db.mu.RLock()
+	defer s.db.mu.RUnlock()
+
+	term := strings.ToLower(strings.TrimSpace(search.Query))
+	partners := make(map[uuid.UUID]bool)
+	for _, members := range s.db.participants {
+		if _, ok := members[search.SearcherID]; ok {
+			for userID := range members {
+				partners[userID] = true
+			}
+		}
+	}
+
+	type match struct {
+		user       *models.User
+		exact      bool
+		partner    bool
+		prefix     bool
+		similarity float64
+	}
+	var matches []match
+	for id, u := range s.db.users {
+		if id == search.SearcherID || u.DeletedAt != nil {
+			continue
+		}
+		if _, blocked := s.db.blocks[search.SearcherID][id]; blocked {
+			continue
+		}
+		if _, blocked := s.db.blocks[id][search.SearcherID]; blocked {
+			continue
+		}
+		rel := models.RelationshipStranger
+		if partners[id] {
+			rel = models.RelationshipContact
+		}
+		username := strings.ToLower(u.Username)
+		emailMatch := strings.ToLower(u.Email) == term && u.EmailVisibility.Allows(rel)
+		m := match{
+			user:       u,
+			exact:      username == term || emailMatch,
+			partner:    partners[id],
+			prefix:     strings.HasPrefix(username, term),
+			similarity: trigramSimilarity(username, term),
+		}
+		if m.exact || m.prefix || m.similarity >= trigramThreshold {
+			matches = append(matches, m)
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		a, b := matches[i], matches[j]
+		switch {
+		case a.exact != b.exact:
+			return a.exact
+		case a.partner != b.partner:
+			return a.partner
+		case a.prefix != b.prefix:
+			return a.prefix
+		case a.similarity != b.similarity:
+			return a.similarity > b.similarity
+		case !strings.EqualFold(a.user.Username, b.user.Username):
+			return strings.ToLower(a.user.Username) < strings.ToLower(b.user.Username)
+		}
+		return a.user.ID.String() < b.user.ID.String()
+	})
+
+	users := make([]*models.PublicUser, 0)
+	for _, m := range paginate(matches, search.Limit, search.Offset) {
+		rel := models.RelationshipStranger
+		if m.partner {
+			rel = models.RelationshipContact
+		}
+		users = append(users, m.user.ProfileFor(rel))
+	}
+	return users, nil
+}
+
+// trigramThreshold mirrors pg_trgm's default similarity_threshold.
+const trigramThreshold = 0.3
+
+// trigramSimilarity approximates pg_trgm's similarity(): the share of distinct trigrams,
+// taken from each space-padded alphanumeric word, that a and b have in common.
+func trigramSimilarity(a, b string) float64 {
+	ta, tb := trigrams(a), trigrams(b)
+	if len(ta) == 0 || len(tb) == 0 {
+		return 0
+	}
+	shared := 0
+	for t := range ta {
+		if tb[t] {
+			shared++
+		}
+	}
+	return float64(shared) / float64(len(ta)+len(tb)-shared)
+}
+
+func trigrams(s string) map[string]bool {
+	set := make(map[string]bool)
+	words := strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	for _, word := range words {
+		padded := []rune("  " + word + " ")
+		for i := 0; i+3 <= len(padded); i++ {
+			set[string(padded[i:i+3])] = true
+		}
+	}
+	return set
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"time"
 
 	"blinkchat-backend/internal/models"
 
@@ -16,9 +17,37 @@ import (
 type MessageStore interface {
 	CreateMessage(ctx context.Context, message *models.Message) error
 	GetMessagesByChatID(ctx context.Context, chatID uuid.UUID, limit, offset int) ([]*models.Message, error)
+	GetMessagesPage(ctx context.Context, chatID uuid.UUID, page models.CursorPage) (*models.Page[*models.Message], error)
 	GetMessageByID(ctx context.Context, messageID uuid.UUID) (*models.Message, error)
-	UpdateMessageStatus(ctx context.Context, messageID uuid.UUID, status models.MessageStatus) error
+	// GetRepliesPage returns a keyset-paginated page of the direct replies to a message that
+	// have not been deleted, newest first, so a thread agrees with its reply count.
+	GetRepliesPage(ctx context.Context, messageID uuid.UUID, page models.CursorPage) (*models.Page[*models.Message], error)
+	// GetReplyCounts counts the direct replies to each of the messages that have not been
+	// deleted. Messages without replies are left out.
+	GetReplyCounts(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID]int, error)
 	GetUnreadMessageCountForUserInChat(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (int, error)
+	GetTotalUnreadCount(ctx context.Context, userID uuid.UUID) (int, error)
+
+	EditMessage(ctx context.Context, messageID uuid.UUID, content string, editedAt time.Time) (*models.Message, error)
+	DeleteMessage(ctx context.Context, messageID uuid.UUID, deletedAt time.Time) (*models.Message, error)
+	GetMessageEdits(ctx context.Context, messageID uuid.UUID) ([]*models.MessageEdit, error)
+
+	// MarkMessagesUpTo advances the user's delivered (and, for StatusRead, read) pointer in
+	// the chat to messageID. Pointers never move backwards; the result reports whether
+	// anything changed.
+	MarkMessagesUpTo(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, messageID uuid.UUID, status models.MessageStatus) (bool, error)
+	GetMessageReceipts(ctx context.Context, messageID uuid.UUID) ([]*models.MessageReceipt, error)
+	// MarkChatRead moves the user's read pointer to the newest message in the chat and
+	// returns that message's ID. The ID is uuid.Nil when the chat has no messages.
+	MarkChatRead(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (uuid.UUID, bool, error)
+
+	// AddReaction records userID's emoji reaction to a message and reports whether it is new.
+	AddReaction(ctx context.Context, messageID uuid.UUID, userID uuid.UUID, emoji string, at time.Time) (bool, error)
+	// RemoveReaction deletes userID's emoji reaction and reports whether there was one.
+	RemoveReaction(ctx context.Context, messageID uuid.UUID, userID uuid.UUID, emoji string) (bool, error)
+	// GetReactionCounts aggregates the reactions to each of the messages, most used emoji
+	// first, flagging the ones viewerID added. Messages without reactions are left out.
+	GetReactionCounts(ctx context.Context, messageIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID][]*models.ReactionCount, error)
 }
 
 // PostgresMessageStore implements MessageStore with PostgreSQL.
@@ -32,141 +61,594 @@ func NewPostgresMessageStore(db *pgxpool.Pool) *PostgresMessageStore {
 	}
 }
 
+// CreateMessage stores a message and ties its Attachments to it. It fails with
+// ErrAttachmentUnavailable, storing nothing, unless every attachment was uploaded by the
+// sender and has not been sent before.
 func (s *PostgresMessageStore) CreateMessage(ctx context.Context, message *models.Message) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
 	query := `
-        INSERT INTO messages (id, chat_id, sender_id, content, status, created_at)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO messages (id, chat_id, sender_id, content, status, created_at, reply_to_id)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
     `
 
-	_, err := s.db.Exec(ctx, query,
+	_, err = tx.Exec(ctx, query,
 		message.ID,
 		message.ChatID,
 		message.SenderID,
 		message.Content,
 		message.Status,
 		message.Timestamp,
+		message.ReplyToID,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to create message: %w", err)
 	}
+
+	if len(message.Attachments) > 0 {
+		ids := make([]uuid.UUID, len(message.Attachments))
+		for i, a := range message.Attachments {
+			ids[i] = a.ID
+		}
+		result, err := tx.Exec(ctx, `
+            UPDATE attachments SET message_id = $1, chat_id = $2
+            WHERE id = ANY($3) AND uploader_id = $4 AND message_id IS NULL`,
+			message.ID, message.ChatID, ids, message.SenderID)
+		if err != nil {
+			return fmt.Errorf("failed to attach files to message: %w", err)
+		}
+		if result.RowsAffected() != int64(len(ids)) {
+			return ErrAttachmentUnavailable
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit message: %w", err)
+	}
+	for _, a := range message.Attachments {
+		a.MessageID, a.ChatID = &message.ID, &message.ChatID
+	}
 	return nil
 }
 
 func (s *PostgresMessageStore) GetMessagesByChatID(ctx context.Context, chatID uuid.UUID, limit, offset int) ([]*models.Message, error) {
-	query := `
-        SELECT
-            m.id, m.chat_id, m.sender_id, m.content, m.status, m.created_at,
-            u.username AS sender_username, u.email AS sender_email, u.created_at AS sender_created_at, u.updated_at AS sender_updated_at
-        FROM messages m
-        JOIN users u ON m.sender_id = u.id
+	query := messageWithSenderSQL + `
         WHERE m.chat_id = $1
-        ORDER BY m.created_at DESC
+        ORDER BY m.created_at DESC, m.id DESC
         LIMIT $2 OFFSET $3
     `
 	rows, err := s.db.Query(ctx, query, chatID, limit, offset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to query messages by chat ID: %w", err)
 	}
-	defer rows.Close()
+	return scanMessagesWithSender(rows)
+}
 
-	messages := make([]*models.Message, 0)
-	for rows.Next() {
-		var msg models.Message
-		var sender models.PublicUser
-
-		err := rows.Scan(
-			&msg.ID,
-			&msg.ChatID,
-			&msg.SenderID,
-			&msg.Content,
-			&msg.Status,
-			&msg.Timestamp,
-			&sender.Username,
-			&sender.Email,
-			&sender.CreatedAt,
-			&sender.UpdatedAt,
-		)
-		if err != nil {
-			log.Printf("Error scanning message row: %v", err)
-			return nil, fmt.Errorf("failed to scan message row: %w", err)
-		}
-		sender.ID = msg.SenderID
-		msg.Sender = &sender
-		messages = append(messages, &msg)
+// GetMessagesPage returns a keyset-paginated page of a chat's messages, newest first.
+func (s *PostgresMessageStore) GetMessagesPage(ctx context.Context, chatID uuid.UUID, page models.CursorPage) (*models.Page[*models.Message], error) {
+	cursor, op, order := page.Before, "<", "DESC"
+	if page.Forward() {
+		cursor, op, order = page.After, ">", "ASC"
 	}
-
-	if err = rows.Err(); err != nil {
-		return nil, fmt.Errorf("error iterating message rows: %w", err)
+	var cursorAt *time.Time
+	var cursorID *uuid.UUID
+	if cursor != nil {
+		cursorAt, cursorID = &cursor.Timestamp, &cursor.ID
 	}
 
-	return messages, nil
+	query := messageWithSenderSQL + fmt.Sprintf(`
+        WHERE m.chat_id = $1
+          AND ($2::timestamptz IS NULL OR (m.created_at, m.id) %s ($2, $3::uuid))
+        ORDER BY m.created_at %s, m.id %s
+        LIMIT $4
+    `, op, order, order)
+	rows, err := s.db.Query(ctx, query, chatID, cursorAt, cursorID, page.Limit+1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message page of chat %s: %w", chatID, err)
+	}
+	messages, err := scanMessagesWithSender(rows)
+	if err != nil {
+		return nil, err
+	}
+	return models.NewPage(messages, page, (*models.Message).Cursor), nil
 }
 
-func (s *PostgresMessageStore) GetMessageByID(ctx context.Context, messageID uuid.UUID) (*models.Message, error) {
-	query := `
+// messageWithSenderSQL selects messages joined with their sender's public fields and a
+// preview of the message they reply to. Callers append the WHERE clause; rows are read
+// with scanMessageWithSender.
+const messageWithSenderSQL = `
         SELECT
-            m.id, m.chat_id, m.sender_id, m.content, m.status, m.created_at,
-            u.username AS sender_username, u.email AS sender_email, u.created_at AS sender_created_at, u.updated_at AS sender_updated_at
+            m.id, m.chat_id, m.sender_id, m.content, m.status, m.created_at, m.edited_at, m.deleted_at, m.reply_to_id,
+            u.username AS sender_username, CASE WHEN u.email_visibility = 'nobody' THEN '' ELSE u.email END AS sender_email, u.created_at AS sender_created_at, u.updated_at AS sender_updated_at,
+            p.sender_id AS parent_sender_id, pu.username AS parent_sender_username, p.content AS parent_content, p.created_at AS parent_created_at, p.deleted_at AS parent_deleted_at
         FROM messages m
         JOIN users u ON m.sender_id = u.id
-        WHERE m.id = $1
-    `
+        LEFT JOIN messages p ON p.id = m.reply_to_id
+        LEFT JOIN users pu ON pu.id = p.sender_id`
+
+// scanMessageWithSender reads one row selected by messageWithSenderSQL.
+func scanMessageWithSender(row pgx.Row) (*models.Message, error) {
 	var msg models.Message
 	var sender models.PublicUser
+	var parentSenderID *uuid.UUID
+	var parentSenderUsername, parentContent *string
+	var parentCreatedAt, parentDeletedAt *time.Time
 
-	err := s.db.QueryRow(ctx, query, messageID).Scan(
+	err := row.Scan(
 		&msg.ID,
 		&msg.ChatID,
 		&msg.SenderID,
 		&msg.Content,
 		&msg.Status,
 		&msg.Timestamp,
+		&msg.EditedAt,
+		&msg.DeletedAt,
+		&msg.ReplyToID,
 		&sender.Username,
 		&sender.Email,
 		&sender.CreatedAt,
 		&sender.UpdatedAt,
+		&parentSenderID,
+		&parentSenderUsername,
+		&parentContent,
+		&parentCreatedAt,
+		&parentDeletedAt,
 	)
 	if err != nil {
-		if err == pgx.ErrNoRows { // Import pgx if not already
-			return nil, ErrMessageNotFound // Custom error
-		}
-		return nil, fmt.Errorf("failed to get message by ID: %w", err)
+		return nil, err
 	}
 	sender.ID = msg.SenderID
 	msg.Sender = &sender
+	msg.Deleted = msg.DeletedAt != nil
+	if msg.ReplyToID != nil && parentSenderID != nil {
+		msg.ReplyTo = models.NewQuotedMessage(*msg.ReplyToID, *parentSenderID, *parentSenderUsername, *parentContent, *parentCreatedAt, parentDeletedAt != nil)
+	}
 	return &msg, nil
 }
 
-func (s *PostgresMessageStore) UpdateMessageStatus(ctx context.Context, messageID uuid.UUID, status models.MessageStatus) error {
-	query := `UPDATE messages SET status = $1, updated_at = NOW() WHERE id = $2`
+// scanMessagesWithSender reads rows selected by messageWithSenderSQL and closes rows.
+func scanMessagesWithSender(rows pgx.Rows) ([]*models.Message, error) {
+	defer rows.Close()
+
+	messages := make([]*models.Message, 0)
+	for rows.Next() {
+		msg, err := scanMessageWithSender(rows)
+		if err != nil {
+			log.Printf("Error scanning message row: %v", err)
+			return nil, fmt.Errorf("failed to scan message row: %w", err)
+		}
+		messages = append(messages, msg)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message rows: %w", err)
+	}
+
+	return messages, nil
+}
+
+func (s *PostgresMessageStore) GetMessageByID(ctx context.Context, messageID uuid.UUID) (*models.Message, error) {
+	msg, err := scanMessageWithSender(s.db.QueryRow(ctx, messageWithSenderSQL+`
+        WHERE m.id = $1`, messageID))
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("failed to get message by ID: %w", err)
+	}
+	return msg, nil
+}
 
-	result, err := s.db.Exec(ctx, query, status, messageID)
+// GetRepliesPage returns a keyset-paginated page of the direct replies to a message that
+// have not been deleted, newest first.
+func (s *PostgresMessageStore) GetRepliesPage(ctx context.Context, messageID uuid.UUID, page models.CursorPage) (*models.Page[*models.Message], error) {
+	cursor, op, order := page.Before, "<", "DESC"
+	if page.Forward() {
+		cursor, op, order = page.After, ">", "ASC"
+	}
+	var cursorAt *time.Time
+	var cursorID *uuid.UUID
+	if cursor != nil {
+		cursorAt, cursorID = &cursor.Timestamp, &cursor.ID
+	}
+
+	query := messageWithSenderSQL + fmt.Sprintf(`
+        WHERE m.reply_to_id = $1 AND m.deleted_at IS NULL
+          AND ($2::timestamptz IS NULL OR (m.created_at, m.id) %s ($2, $3::uuid))
+        ORDER BY m.created_at %s, m.id %s
+        LIMIT $4
+    `, op, order, order)
+	rows, err := s.db.Query(ctx, query, messageID, cursorAt, cursorID, page.Limit+1)
 	if err != nil {
-		return fmt.Errorf("failed to update message status for message %s: %w", messageID, err)
+		return nil, fmt.Errorf("failed to query replies to message %s: %w", messageID, err)
 	}
-	if result.RowsAffected() == 0 {
-		return ErrMessageNotFound
+	messages, err := scanMessagesWithSender(rows)
+	if err != nil {
+		return nil, err
 	}
-	return nil
+	return models.NewPage(messages, page, (*models.Message).Cursor), nil
 }
 
+// GetReplyCounts counts the direct replies to each of the messages that have not been deleted.
+func (s *PostgresMessageStore) GetReplyCounts(ctx context.Context, messageIDs []uuid.UUID) (map[uuid.UUID]int, error) {
+	counts := make(map[uuid.UUID]int)
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+	query := `
+        SELECT reply_to_id, COUNT(*)
+        FROM messages
+        WHERE reply_to_id = ANY($1) AND deleted_at IS NULL
+        GROUP BY reply_to_id
+    `
+	rows, err := s.db.Query(ctx, query, messageIDs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query reply counts: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID uuid.UUID
+		var count int
+		if err := rows.Scan(&messageID, &count); err != nil {
+			return nil, fmt.Errorf("failed to scan reply count row: %w", err)
+		}
+		counts[messageID] = count
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating reply count rows: %w", err)
+	}
+	return counts, nil
+}
+
+// GetUnreadMessageCountForUserInChat counts messages from others that arrived after the user's read pointer.
 func (s *PostgresMessageStore) GetUnreadMessageCountForUserInChat(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (int, error) {
 	query := `
         SELECT COUNT(*)
-        FROM messages
-        WHERE chat_id = $1
-          AND sender_id != $2
-          AND status != $3
+        FROM messages m
+        JOIN chat_participants cp ON cp.chat_id = m.chat_id AND cp.user_id = $2
+        WHERE m.chat_id = $1
+          AND m.sender_id != $2
+          AND m.deleted_at IS NULL
+          AND (cp.last_read_message_at IS NULL OR m.created_at > cp.last_read_message_at)
     `
 	var count int
-	err := s.db.QueryRow(ctx, query, chatID, userID, models.StatusRead).Scan(&count)
+	err := s.db.QueryRow(ctx, query, chatID, userID).Scan(&count)
 	if err != nil {
 		return 0, fmt.Errorf("failed to get unread message count: %w", err)
 	}
 	return count, nil
 }
 
+// GetTotalUnreadCount sums the user's unread messages across all of their chats that
+// they have not muted.
+func (s *PostgresMessageStore) GetTotalUnreadCount(ctx context.Context, userID uuid.UUID) (int, error) {
+	query := `
+        SELECT COUNT(*)
+        FROM chat_participants cp
+        JOIN messages m ON m.chat_id = cp.chat_id
+        WHERE cp.user_id = $1
+          AND NOT cp.muted
+          AND m.sender_id != $1
+          AND m.deleted_at IS NULL
+          AND (cp.last_read_message_at IS NULL OR m.created_at > cp.last_read_message_at)
+    `
+	var count int
+	err := s.db.QueryRow(ctx, query, userID).Scan(&count)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get total unread count for user %s: %w", userID, err)
+	}
+	return count, nil
+}
+
+// MarkChatRead marks every message currently in the chat as read by the user.
+func (s *PostgresMessageStore) MarkChatRead(ctx context.Context, chatID uuid.UUID, userID uuid.UUID) (uuid.UUID, bool, error) {
+	var latestID uuid.UUID
+	query := `SELECT id FROM messages WHERE chat_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`
+	err := s.db.QueryRow(ctx, query, chatID).Scan(&latestID)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return uuid.Nil, false, nil
+		}
+		return uuid.Nil, false, fmt.Errorf("failed to get latest message of chat %s: %w", chatID, err)
+	}
+	advanced, err := s.MarkMessagesUpTo(ctx, chatID, userID, latestID, models.StatusRead)
+	if err != nil {
+		return uuid.Nil, false, err
+	}
+	return latestID, advanced, nil
+}
+
+// MarkMessagesUpTo advances the user's receipt pointers and refreshes the aggregate
+// messages.status, which now means "delivered to / read by every recipient".
+func (s *PostgresMessageStore) MarkMessagesUpTo(ctx context.Context, chatID uuid.UUID, userID uuid.UUID, messageID uuid.UUID, status models.MessageStatus) (bool, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var messageAt time.Time
+	err = tx.QueryRow(ctx, `SELECT created_at FROM messages WHERE id = $1 AND chat_id = $2`, messageID, chatID).Scan(&messageAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return false, ErrMessageNotFound
+		}
+		return false, fmt.Errorf("failed to look up message %s: %w", messageID, err)
+	}
+
+	deliveredQuery := `
+        UPDATE chat_participants
+        SET last_delivered_message_id = $3, last_delivered_message_at = $4, delivered_updated_at = NOW()
+        WHERE chat_id = $1 AND user_id = $2
+          AND (last_delivered_message_at IS NULL OR last_delivered_message_at < $4)
+    `
+	result, err := tx.Exec(ctx, deliveredQuery, chatID, userID, messageID, messageAt)
+	if err != nil {
+		return false, fmt.Errorf("failed to advance delivered pointer: %w", err)
+	}
+	advanced := result.RowsAffected() > 0
+
+	if status == models.StatusRead {
+		readQuery := `
+            UPDATE chat_participants
+            SET last_read_message_id = $3, last_read_message_at = $4, read_updated_at = NOW()
+            WHERE chat_id = $1 AND user_id = $2
+              AND (last_read_message_at IS NULL OR last_read_message_at < $4)
+        `
+		result, err = tx.Exec(ctx, readQuery, chatID, userID, messageID, messageAt)
+		if err != nil {
+			return false, fmt.Errorf("failed to advance read pointer: %w", err)
+		}
+		advanced = advanced || result.RowsAffected() > 0
+	}
+
+	if advanced {
+		aggregateQuery := `
+            UPDATE messages m
+            SET status = CASE
+                    WHEN NOT EXISTS (
+                        SELECT 1 FROM chat_participants cp
+                        WHERE cp.chat_id = m.chat_id AND cp.user_id != m.sender_id
+                          AND (cp.last_read_message_at IS NULL OR cp.last_read_message_at < m.created_at)
+                    ) THEN 'read'
+                    ELSE 'delivered'
+                END,
+                updated_at = NOW()
+            WHERE m.chat_id = $1 AND m.created_at <= $2 AND m.status != 'read'
+              AND NOT EXISTS (
+                  SELECT 1 FROM chat_participants cp
+                  WHERE cp.chat_id = m.chat_id AND cp.user_id != m.sender_id
+                    AND (cp.last_delivered_message_at IS NULL OR cp.last_delivered_message_at < m.created_at)
+              )
+        `
+		if _, err = tx.Exec(ctx, aggregateQuery, chatID, messageAt); err != nil {
+			return false, fmt.Errorf("failed to refresh aggregate message status: %w", err)
+		}
+	}
+
+	if err = tx.Commit(ctx); err != nil {
+		return false, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return advanced, nil
+}
+
+// GetMessageReceipts reports, for each recipient of a message, whether it has been
+// delivered to and read by them. Timestamps are when the recipient's pointer last
+// moved past the message.
+func (s *PostgresMessageStore) GetMessageReceipts(ctx context.Context, messageID uuid.UUID) ([]*models.MessageReceipt, error) {
+	query := `
+        SELECT u.id, u.username, CASE WHEN u.email_visibility = 'nobody' THEN '' ELSE u.email END, u.created_at, u.updated_at,
+               (cp.last_delivered_message_at IS NOT NULL AND cp.last_delivered_message_at >= m.created_at) AS delivered,
+               cp.delivered_updated_at,
+               (cp.last_read_message_at IS NOT NULL AND cp.last_read_message_at >= m.created_at) AS read,
+               cp.read_updated_at
+        FROM messages m
+        JOIN chat_participants cp ON cp.chat_id = m.chat_id AND cp.user_id != m.sender_id
+        JOIN users u ON u.id = cp.user_id
+        WHERE m.id = $1
+        ORDER BY cp.created_at ASC, u.id ASC
+    `
+	rows, err := s.db.Query(ctx, query, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query receipts of message %s: %w", messageID, err)
+	}
+	defer rows.Close()
+
+	receipts := make([]*models.MessageReceipt, 0)
+	for rows.Next() {
+		var u models.PublicUser
+		var delivered, read bool
+		var deliveredAt, readAt *time.Time
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.CreatedAt, &u.UpdatedAt, &delivered, &deliveredAt, &read, &readAt); err != nil {
+			return nil, fmt.Errorf("failed to scan receipt row: %w", err)
+		}
+		receipts = append(receipts, newMessageReceipt(&u, delivered, deliveredAt, read, readAt))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating receipt rows: %w", err)
+	}
+	return receipts, nil
+}
+
+func newMessageReceipt(u *models.PublicUser, delivered bool, deliveredAt *time.Time, read bool, readAt *time.Time) *models.MessageReceipt {
+	receipt := &models.MessageReceipt{User: u, Status: models.StatusSent}
+	if delivered {
+		receipt.Status = models.StatusDelivered
+		receipt.DeliveredAt = deliveredAt
+	}
+	if read {
+		receipt.Status = models.StatusRead
+		receipt.ReadAt = readAt
+	}
+	return receipt
+}
+
+// EditMessage replaces a message's content, recording the previous content in its edit history.
+func (s *PostgresMessageStore) EditMessage(ctx context.Context, messageID uuid.UUID, content string, editedAt time.Time) (*models.Message, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var previousContent string
+	var deletedAt *time.Time
+	err = tx.QueryRow(ctx, `SELECT content, deleted_at FROM messages WHERE id = $1 FOR UPDATE`, messageID).Scan(&previousContent, &deletedAt)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrMessageNotFound
+		}
+		return nil, fmt.Errorf("failed to lock message %s for edit: %w", messageID, err)
+	}
+	if deletedAt != nil {
+		return nil, ErrMessageDeleted
+	}
+
+	if previousContent != content {
+		historyQuery := `INSERT INTO message_edits (message_id, previous_content, edited_at) VALUES ($1, $2, $3)`
+		if _, err = tx.Exec(ctx, historyQuery, messageID, previousContent, editedAt); err != nil {
+			return nil, fmt.Errorf("failed to record edit history for message %s: %w", messageID, err)
+		}
+		updateQuery := `UPDATE messages SET content = $1, edited_at = $2, updated_at = NOW() WHERE id = $3`
+		if _, err = tx.Exec(ctx, updateQuery, content, editedAt, messageID); err != nil {
+			return nil, fmt.Errorf("failed to edit message %s: %w", messageID, err)
+		}
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetMessageByID(ctx, messageID)
+}
+
+// DeleteMessage turns a message into a tombstone: its content and edit history are
+// discarded but the row is kept so clients can render "message deleted".
+func (s *PostgresMessageStore) DeleteMessage(ctx context.Context, messageID uuid.UUID, deletedAt time.Time) (*models.Message, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE messages SET content = '', deleted_at = $1, updated_at = NOW() WHERE id = $2 AND deleted_at IS NULL`
+	result, err := tx.Exec(ctx, query, deletedAt, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to delete message %s: %w", messageID, err)
+	}
+	if result.RowsAffected() == 0 {
+		var exists bool
+		if err := tx.QueryRow(ctx, `SELECT EXISTS (SELECT 1 FROM messages WHERE id = $1)`, messageID).Scan(&exists); err != nil {
+			return nil, fmt.Errorf("failed to check message %s: %w", messageID, err)
+		}
+		if !exists {
+			return nil, ErrMessageNotFound
+		}
+		return nil, ErrMessageDeleted
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM message_edits WHERE message_id = $1`, messageID); err != nil {
+		return nil, fmt.Errorf("failed to discard edit history of message %s: %w", messageID, err)
+	}
+	if _, err = tx.Exec(ctx, `DELETE FROM message_reactions WHERE message_id = $1`, messageID); err != nil {
+		return nil, fmt.Errorf("failed to discard reactions to message %s: %w", messageID, err)
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return s.GetMessageByID(ctx, messageID)
+}
+
+// GetMessageEdits returns a message's previous revisions, oldest first.
+func (s *PostgresMessageStore) GetMessageEdits(ctx context.Context, messageID uuid.UUID) ([]*models.MessageEdit, error) {
+	query := `
+        SELECT id, message_id, previous_content, edited_at
+        FROM message_edits
+        WHERE message_id = $1
+        ORDER BY edited_at ASC
+    `
+	rows, err := s.db.Query(ctx, query, messageID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query edit history of message %s: %w", messageID, err)
+	}
+	defer rows.Close()
+
+	edits := make([]*models.MessageEdit, 0)
+	for rows.Next() {
+		var e models.MessageEdit
+		if err := rows.Scan(&e.ID, &e.MessageID, &e.PreviousContent, &e.EditedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan edit history row: %w", err)
+		}
+		edits = append(edits, &e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating edit history rows: %w", err)
+	}
+	return edits, nil
+}
+
+// AddReaction records userID's emoji reaction to a message and reports whether it is new.
+func (s *PostgresMessageStore) AddReaction(ctx context.Context, messageID uuid.UUID, userID uuid.UUID, emoji string, at time.Time) (bool, error) {
+	query := `
+        INSERT INTO message_reactions (message_id, user_id, emoji, created_at)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (message_id, user_id, emoji) DO NOTHING
+    `
+	result, err := s.db.Exec(ctx, query, messageID, userID, emoji, at)
+	if err != nil {
+		return false, fmt.Errorf("failed to add reaction to message %s: %w", messageID, err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// RemoveReaction deletes userID's emoji reaction and reports whether there was one.
+func (s *PostgresMessageStore) RemoveReaction(ctx context.Context, messageID uuid.UUID, userID uuid.UUID, emoji string) (bool, error) {
+	query := `DELETE FROM message_reactions WHERE message_id = $1 AND user_id = $2 AND emoji = $3`
+	result, err := s.db.Exec(ctx, query, messageID, userID, emoji)
+	if err != nil {
+		return false, fmt.Errorf("failed to remove reaction from message %s: %w", messageID, err)
+	}
+	return result.RowsAffected() > 0, nil
+}
+
+// GetReactionCounts aggregates the reactions to each of the messages, most used emoji first.
+// Ties go to the emoji that was used first.
+func (s *PostgresMessageStore) GetReactionCounts(ctx context.Context, messageIDs []uuid.UUID, viewerID uuid.UUID) (map[uuid.UUID][]*models.ReactionCount, error) {
+	counts := make(map[uuid.UUID][]*models.ReactionCount)
+	if len(messageIDs) == 0 {
+		return counts, nil
+	}
+	query := `
+        SELECT message_id, emoji, COUNT(*), BOOL_OR(user_id = $2)
+        FROM message_reactions
+        WHERE message_id = ANY($1)
+        GROUP BY message_id, emoji
+        ORDER BY message_id, COUNT(*) DESC, MIN(created_at), emoji
+    `
+	rows, err := s.db.Query(ctx, query, messageIDs, viewerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query message reactions: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var messageID uuid.UUID
+		var rc models.ReactionCount
+		if err := rows.Scan(&messageID, &rc.Emoji, &rc.Count, &rc.ReactedByMe); err != nil {
+			return nil, fmt.Errorf("failed to scan message reaction row: %w", err)
+		}
+		counts[messageID] = append(counts[messageID], &rc)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating message reaction rows: %w", err)
+	}
+	return counts, nil
+}
+
 var (
 	ErrMessageNotFound = fmt.Errorf("message not found")
+	ErrMessageDeleted  = fmt.Errorf("message has been deleted")
 )
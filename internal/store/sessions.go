@@ -0,0 +1,214 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// SessionStore persists login sessions and their rotating refresh tokens.
+type SessionStore interface {
+	CreateSession(ctx context.Context, session *models.Session, token *models.RefreshToken) error
+	GetSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error)
+	RevokeSession(ctx context.Context, sessionID uuid.UUID) error
+	RevokeUserSessions(ctx context.Context, userID uuid.UUID, exceptSessionID *uuid.UUID) ([]uuid.UUID, error)
+	// RotateRefreshToken consumes the token with the given hash and stores next in the
+	// same session. Presenting an already-used token revokes the whole session and
+	// returns it together with ErrRefreshTokenReused.
+	RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.Session, error)
+}
+
+// PostgresSessionStore implements SessionStore with PostgreSQL.
+type PostgresSessionStore struct {
+	db *pgxpool.Pool
+}
+
+// NewPostgresSessionStore returns a Postgres-backed SessionStore implementation.
+func NewPostgresSessionStore(db *pgxpool.Pool) *PostgresSessionStore {
+	return &PostgresSessionStore{db: db}
+}
+
+// CreateSession stores a new session together with its first refresh token.
+func (s *PostgresSessionStore) CreateSession(ctx context.Context, session *models.Session, token *models.RefreshToken) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	sessionQuery := `
+        INSERT INTO sessions (id, user_id, user_agent, ip_address, created_at, last_used_at)
+        VALUES ($1, $2, $3, $4, $5, $5)
+    `
+	if _, err = tx.Exec(ctx, sessionQuery, session.ID, session.UserID, session.UserAgent, session.IPAddress, session.CreatedAt); err != nil {
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+	if err = insertRefreshToken(ctx, tx, token); err != nil {
+		return err
+	}
+	if err = tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+func insertRefreshToken(ctx context.Context, tx pgx.Tx, token *models.RefreshToken) error {
+	query := `
+        INSERT INTO refresh_tokens (id, session_id, token_hash, created_at, expires_at)
+        VALUES ($1, $2, $3, $4, $5)
+    `
+	_, err := tx.Exec(ctx, query, token.ID, token.SessionID, token.TokenHash, token.CreatedAt, token.ExpiresAt)
+	if err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+// GetSession returns the session with the given ID.
+func (s *PostgresSessionStore) GetSession(ctx context.Context, sessionID uuid.UUID) (*models.Session, error) {
+	query := `
+        SELECT id, user_id, user_agent, ip_address, created_at, last_used_at, revoked_at
+        FROM sessions
+        WHERE id = $1
+    `
+	session := &models.Session{}
+	err := s.db.QueryRow(ctx, query, sessionID).Scan(
+		&session.ID,
+		&session.UserID,
+		&session.UserAgent,
+		&session.IPAddress,
+		&session.CreatedAt,
+		&session.LastUsedAt,
+		&session.RevokedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrSessionNotFound
+		}
+		return nil, fmt.Errorf("failed to get session %s: %w", sessionID, err)
+	}
+	return session, nil
+}
+
+// RevokeSession marks a session as revoked. Revoking an already revoked session is a no-op.
+func (s *PostgresSessionStore) RevokeSession(ctx context.Context, sessionID uuid.UUID) error {
+	query := `UPDATE sessions SET revoked_at = COALESCE(revoked_at, NOW()) WHERE id = $1`
+	result, err := s.db.Exec(ctx, query, sessionID)
+	if err != nil {
+		return fmt.Errorf("failed to revoke session %s: %w", sessionID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrSessionNotFound
+	}
+	return nil
+}
+
+// RevokeUserSessions revokes every active session of a user, optionally keeping one,
+// and returns the IDs of the sessions it revoked.
+func (s *PostgresSessionStore) RevokeUserSessions(ctx context.Context, userID uuid.UUID, exceptSessionID *uuid.UUID) ([]uuid.UUID, error) {
+	query := `
+        UPDATE sessions SET revoked_at = NOW()
+        WHERE user_id = $1 AND revoked_at IS NULL AND ($2::uuid IS NULL OR id != $2)
+        RETURNING id
+    `
+	rows, err := s.db.Query(ctx, query, userID, exceptSessionID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions of user %s: %w", userID, err)
+	}
+	defer rows.Close()
+
+	var revoked []uuid.UUID
+	for rows.Next() {
+		var id uuid.UUID
+		if err := rows.Scan(&id); err != nil {
+			return nil, fmt.Errorf("failed to scan revoked session ID: %w", err)
+		}
+		revoked = append(revoked, id)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating revoked sessions: %w", err)
+	}
+	return revoked, nil
+}
+
+// RotateRefreshToken exchanges a refresh token for next within the same session.
+func (s *PostgresSessionStore) RotateRefreshToken(ctx context.Context, tokenHash string, next *models.RefreshToken) (*models.Session, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `
+        SELECT rt.id, rt.expires_at, rt.used_at,
+               s.id, s.user_id, s.user_agent, s.ip_address, s.created_at, s.last_used_at, s.revoked_at
+        FROM refresh_tokens rt
+        JOIN sessions s ON s.id = rt.session_id
+        WHERE rt.token_hash = $1
+        FOR UPDATE OF rt, s
+    `
+	var tokenID uuid.UUID
+	var expiresAt time.Time
+	var usedAt *time.Time
+	session := &models.Session{}
+	err = tx.QueryRow(ctx, query, tokenHash).Scan(
+		&tokenID, &expiresAt, &usedAt,
+		&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress,
+		&session.CreatedAt, &session.LastUsedAt, &session.RevokedAt,
+	)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrRefreshTokenNotFound
+		}
+		return nil, fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+
+	if !session.IsActive() {
+		return session, ErrSessionRevoked
+	}
+
+	if usedAt != nil {
+		now := time.Now()
+		if _, err := tx.Exec(ctx, `UPDATE sessions SET revoked_at = $1 WHERE id = $2`, now, session.ID); err != nil {
+			return nil, fmt.Errorf("failed to revoke session %s after refresh token reuse: %w", session.ID, err)
+		}
+		if err := tx.Commit(ctx); err != nil {
+			return nil, fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		session.RevokedAt = &now
+		return session, ErrRefreshTokenReused
+	}
+
+	if time.Now().After(expiresAt) {
+		return session, ErrRefreshTokenExpired
+	}
+
+	if _, err := tx.Exec(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, tokenID); err != nil {
+		return nil, fmt.Errorf("failed to consume refresh token: %w", err)
+	}
+	next.SessionID = session.ID
+	if err := insertRefreshToken(ctx, tx, next); err != nil {
+		return nil, err
+	}
+	if _, err := tx.Exec(ctx, `UPDATE sessions SET last_used_at = NOW() WHERE id = $1`, session.ID); err != nil {
+		return nil, fmt.Errorf("failed to touch session %s: %w", session.ID, err)
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return session, nil
+}
+
+var (
+	ErrSessionNotFound      = fmt.Errorf("session not found")
+	ErrSessionRevoked       = fmt.Errorf("session has been revoked")
+	ErrRefreshTokenNotFound = fmt.Errorf("refresh token not found")
+	ErrRefreshTokenExpired  = fmt.Errorf("refresh token has expired")
+	ErrRefreshTokenReused   = fmt.Errorf("refresh token has already been used")
+)
@@ -3,9 +3,12 @@ package store
 import (
 	"context"
 	"fmt"
+	"strings"
+	"time"
 
 	"blinkchat-backend/internal/models"
 
+	"github.com/google/uuid"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -16,6 +19,25 @@ type UserStore interface {
 	CreateUser(ctx context.Context, user *models.User) error
 	GetUserByEmail(ctx context.Context, email string) (*models.User, error)
 	GetUserByID(ctx context.Context, id string) (*models.User, error)
+	UpdateLastSeen(ctx context.Context, userID uuid.UUID, at time.Time) error
+	UpdateUserSettings(ctx context.Context, userID uuid.UUID, settings models.UserSettings) error
+	MarkEmailVerified(ctx context.Context, userID uuid.UUID, at time.Time) error
+	// UpdatePassword replaces the password hash and invalidates the user's unused password
+	// reset tokens, which were issued for the old password.
+	UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error
+	// UpdateUserProfile changes the username and email. A changed email must be verified
+	// again and invalidates the user's unused action tokens.
+	UpdateUserProfile(ctx context.Context, userID uuid.UUID, username, email string) error
+	// DeleteUser anonymises the account into a tombstone that can no longer log in or be
+	// looked up, and returns the IDs of the group chats it was removed from. Its messages
+	// and direct chats stay behind for the other participants. Groups whose only admin
+	// it was pass admin rights to their longest-standing member; its sessions are revoked.
+	DeleteUser(ctx context.Context, userID uuid.UUID, at time.Time) ([]uuid.UUID, error)
+	// SearchUsers matches usernames case-insensitively by prefix or trigram similarity,
+	// and whole email addresses exactly where the searcher may see them. Users on either
+	// side of a block with the searcher are left out. Exact matches rank first, then people
+	// the searcher already shares a chat with, then prefix matches, then the most similar.
+	SearchUsers(ctx context.Context, search models.UserSearch) ([]*models.PublicUser, error)
 }
 
 // PostgresUserStore stores users in PostgreSQL.
@@ -31,8 +53,8 @@ func NewPostgresUserStore(db *pgxpool.Pool) *PostgresUserStore {
 // CreateUser persists a new user record.
 func (s *PostgresUserStore) CreateUser(ctx context.Context, user *models.User) error {
 	query := `
-        INSERT INTO users (id, username, email, hashed_password, created_at, updated_at)
-        VALUES ($1, $2, $3, $4, $5, $6)
+        INSERT INTO users (id, username, email, hashed_password, created_at, updated_at, email_visibility)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
     `
 
 	_, err := s.db.Exec(ctx, query,
@@ -42,30 +64,40 @@ func (s *PostgresUserStore) CreateUser(ctx context.Context, user *models.User) e
 		user.HashedPassword,
 		user.CreatedAt,
 		user.UpdatedAt,
+		user.EmailVisibility,
 	)
 
 	if err != nil {
-		pgErr, ok := err.(*pgconn.PgError)
-		if ok && pgErr.Code == "23505" {
-			switch pgErr.ConstraintName {
-			case "users_email_key":
-				return ErrEmailExists
-			case "users_username_key":
-				return ErrUsernameExists
-			}
-			return fmt.Errorf("database unique constraint violation: %w, constraint: %s", err, pgErr.ConstraintName)
+		if uniqueErr := userUniqueViolation(err); uniqueErr != nil {
+			return uniqueErr
 		}
 		return fmt.Errorf("failed to create user: %w", err)
 	}
 	return nil
 }
 
+// userUniqueViolation maps unique constraint violations on users to ErrEmailExists and
+// ErrUsernameExists. It returns nil for any other error.
+func userUniqueViolation(err error) error {
+	pgErr, ok := err.(*pgconn.PgError)
+	if !ok || pgErr.Code != "23505" {
+		return nil
+	}
+	switch pgErr.ConstraintName {
+	case "users_email_key":
+		return ErrEmailExists
+	case "users_username_key":
+		return ErrUsernameExists
+	}
+	return fmt.Errorf("database unique constraint violation: %w, constraint: %s", err, pgErr.ConstraintName)
+}
+
 // GetUserByEmail returns the user with the given email.
 func (s *PostgresUserStore) GetUserByEmail(ctx context.Context, email string) (*models.User, error) {
 	query := `
-                SELECT id, username, email, hashed_password, created_at, updated_at
+                SELECT id, username, email, hashed_password, created_at, updated_at, last_seen_at, hide_last_seen, email_verified_at, email_visibility
                 FROM users
-                WHERE email = $1
+                WHERE email = $1 AND deleted_at IS NULL
         `
 	user := &models.User{}
 
@@ -76,6 +108,10 @@ func (s *PostgresUserStore) GetUserByEmail(ctx context.Context, email string) (*
 		&user.HashedPassword,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.LastSeenAt,
+		&user.HideLastSeen,
+		&user.EmailVerifiedAt,
+		&user.EmailVisibility,
 	)
 
 	if err != nil {
@@ -90,9 +126,9 @@ func (s *PostgresUserStore) GetUserByEmail(ctx context.Context, email string) (*
 // GetUserByID returns the user with the given ID.
 func (s *PostgresUserStore) GetUserByID(ctx context.Context, id string) (*models.User, error) {
 	query := `
-                SELECT id, username, email, hashed_password, created_at, updated_at
+                SELECT id, username, email, hashed_password, created_at, updated_at, last_seen_at, hide_last_seen, email_verified_at, email_visibility
                 FROM users
-                WHERE id = $1
+                WHERE id = $1 AND deleted_at IS NULL
         `
 	user := &models.User{}
 
@@ -103,6 +139,10 @@ func (s *PostgresUserStore) GetUserByID(ctx context.Context, id string) (*models
 		&user.HashedPassword,
 		&user.CreatedAt,
 		&user.UpdatedAt,
+		&user.LastSeenAt,
+		&user.HideLastSeen,
+		&user.EmailVerifiedAt,
+		&user.EmailVisibility,
 	)
 
 	if err != nil {
@@ -114,6 +154,280 @@ func (s *PostgresUserStore) GetUserByID(ctx context.Context, id string) (*models
 	return user, nil
 }
 
+// UpdateLastSeen records when the user was last connected.
+func (s *PostgresUserStore) UpdateLastSeen(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	query := `UPDATE users SET last_seen_at = $2 WHERE id = $1`
+	result, err := s.db.Exec(ctx, query, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to update last seen of user %s: %w", userID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdateUserSettings replaces the user's privacy settings.
+func (s *PostgresUserStore) UpdateUserSettings(ctx context.Context, userID uuid.UUID, settings models.UserSettings) error {
+	query := `UPDATE users SET hide_last_seen = $2, email_visibility = $3, updated_at = NOW() WHERE id = $1`
+	result, err := s.db.Exec(ctx, query, userID, settings.HideLastSeen, settings.EmailVisibility)
+	if err != nil {
+		return fmt.Errorf("failed to update settings of user %s: %w", userID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// MarkEmailVerified records that the user confirmed their email address. Verifying an
+// already verified address keeps the original time.
+func (s *PostgresUserStore) MarkEmailVerified(ctx context.Context, userID uuid.UUID, at time.Time) error {
+	query := `UPDATE users SET email_verified_at = COALESCE(email_verified_at, $2) WHERE id = $1`
+	result, err := s.db.Exec(ctx, query, userID, at)
+	if err != nil {
+		return fmt.Errorf("failed to mark email of user %s verified: %w", userID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+	return nil
+}
+
+// UpdatePassword replaces the user's password hash and invalidates their unused password
+// reset tokens.
+func (s *PostgresUserStore) UpdatePassword(ctx context.Context, userID uuid.UUID, hashedPassword string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	query := `UPDATE users SET hashed_password = $2, updated_at = NOW() WHERE id = $1`
+	result, err := tx.Exec(ctx, query, userID, hashedPassword)
+	if err != nil {
+		return fmt.Errorf("failed to update password of user %s: %w", userID, err)
+	}
+	if result.RowsAffected() == 0 {
+		return ErrUserNotFound
+	}
+
+	invalidateQuery := `UPDATE action_tokens SET used_at = NOW() WHERE user_id = $1 AND purpose = $2 AND used_at IS NULL`
+	if _, err := tx.Exec(ctx, invalidateQuery, userID, models.ActionResetPassword); err != nil {
+		return fmt.Errorf("failed to invalidate password reset tokens of user %s: %w", userID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// UpdateUserProfile changes the username and email. A changed email must be verified again,
+// and the user's outstanding action tokens, issued for the old address, stop working.
+func (s *PostgresUserStore) UpdateUserProfile(ctx context.Context, userID uuid.UUID, username, email string) error {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var oldEmail string
+	err = tx.QueryRow(ctx, `SELECT email FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, userID).Scan(&oldEmail)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return ErrUserNotFound
+		}
+		return fmt.Errorf("failed to lock user %s: %w", userID, err)
+	}
+
+	query := `
+        UPDATE users SET
+            username = $2,
+            email = $3,
+            email_verified_at = CASE WHEN email = $3 THEN email_verified_at ELSE NULL END,
+            updated_at = NOW()
+        WHERE id = $1
+    `
+	if _, err := tx.Exec(ctx, query, userID, username, email); err != nil {
+		if uniqueErr := userUniqueViolation(err); uniqueErr != nil {
+			return uniqueErr
+		}
+		return fmt.Errorf("failed to update profile of user %s: %w", userID, err)
+	}
+
+	if oldEmail != email {
+		invalidateQuery := `UPDATE action_tokens SET used_at = NOW() WHERE user_id = $1 AND used_at IS NULL`
+		if _, err := tx.Exec(ctx, invalidateQuery, userID); err != nil {
+			return fmt.Errorf("failed to invalidate action tokens of user %s: %w", userID, err)
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// DeleteUser anonymises the account and removes it from its group chats.
+func (s *PostgresUserStore) DeleteUser(ctx context.Context, userID uuid.UUID, at time.Time) ([]uuid.UUID, error) {
+	tx, err := s.db.Begin(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback(ctx)
+
+	var exists bool
+	err = tx.QueryRow(ctx, `SELECT TRUE FROM users WHERE id = $1 AND deleted_at IS NULL FOR UPDATE`, userID).Scan(&exists)
+	if err != nil {
+		if err == pgx.ErrNoRows {
+			return nil, ErrUserNotFound
+		}
+		return nil, fmt.Errorf("failed to lock user %s: %w", userID, err)
+	}
+
+	promoteQuery := `
+        UPDATE chat_participants SET role = 'admin'
+        WHERE (chat_id, user_id) IN (
+            SELECT DISTINCT ON (other.chat_id) other.chat_id, other.user_id
+            FROM chat_participants me
+            JOIN chats c ON c.id = me.chat_id AND c.is_group
+            JOIN chat_participants other ON other.chat_id = me.chat_id AND other.user_id != me.user_id
+            WHERE me.user_id = $1 AND me.role = 'admin'
+              AND NOT EXISTS (
+                  SELECT 1 FROM chat_participants a
+                  WHERE a.chat_id = me.chat_id AND a.user_id != me.user_id AND a.role = 'admin'
+              )
+            ORDER BY other.chat_id, other.created_at ASC, other.user_id ASC
+        )
+    `
+	if _, err := tx.Exec(ctx, promoteQuery, userID); err != nil {
+		return nil, fmt.Errorf("failed to hand over admin rights of user %s: %w", userID, err)
+	}
+
+	leaveQuery := `
+        DELETE FROM chat_participants cp
+        USING chats c
+        WHERE c.id = cp.chat_id AND c.is_group AND cp.user_id = $1
+        RETURNING cp.chat_id
+    `
+	rows, err := tx.Query(ctx, leaveQuery, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to remove user %s from group chats: %w", userID, err)
+	}
+	groupIDs := make([]uuid.UUID, 0)
+	for rows.Next() {
+		var chatID uuid.UUID
+		if err := rows.Scan(&chatID); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan group chat of user %s: %w", userID, err)
+		}
+		groupIDs = append(groupIDs, chatID)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating group chats of user %s: %w", userID, err)
+	}
+
+	tombstoneQuery := `
+        UPDATE users SET
+            username = $2,
+            email = $3,
+            hashed_password = '',
+            last_seen_at = NULL,
+            hide_last_seen = TRUE,
+            email_verified_at = NULL,
+            email_visibility = 'nobody',
+            deleted_at = $4,
+            updated_at = $4
+        WHERE id = $1
+    `
+	if _, err := tx.Exec(ctx, tombstoneQuery, userID, tombstoneUsername(userID), tombstoneEmail(userID), at); err != nil {
+		return nil, fmt.Errorf("failed to anonymise user %s: %w", userID, err)
+	}
+	if _, err := tx.Exec(ctx, `UPDATE sessions SET revoked_at = $2 WHERE user_id = $1 AND revoked_at IS NULL`, userID, at); err != nil {
+		return nil, fmt.Errorf("failed to revoke sessions of user %s: %w", userID, err)
+	}
+	if _, err := tx.Exec(ctx, `DELETE FROM action_tokens WHERE user_id = $1`, userID); err != nil {
+		return nil, fmt.Errorf("failed to delete tokens of user %s: %w", userID, err)
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return groupIDs, nil
+}
+
+// SearchUsers returns one page of users matching the search, best matches first.
+func (s *PostgresUserStore) SearchUsers(ctx context.Context, search models.UserSearch) ([]*models.PublicUser, error) {
+	query := `
+        SELECT u.id, u.username, u.email, u.email_visibility, partner.known IS NOT NULL, u.created_at, u.updated_at
+        FROM users u
+        LEFT JOIN LATERAL (
+            SELECT TRUE AS known
+            FROM chat_participants mine
+            JOIN chat_participants theirs ON theirs.chat_id = mine.chat_id
+            WHERE mine.user_id = $1 AND theirs.user_id = u.id
+            LIMIT 1
+        ) partner ON TRUE
+        WHERE u.id != $1 AND u.deleted_at IS NULL
+          AND NOT EXISTS (
+              SELECT 1 FROM user_blocks b
+              WHERE (b.blocker_id = $1 AND b.blocked_id = u.id) OR (b.blocker_id = u.id AND b.blocked_id = $1)
+          )
+          AND (lower(u.username) LIKE $3 ESCAPE '\' OR lower(u.username) % $2 OR (lower(u.email) = $2 AND ` + emailVisibleSQL + `))
+        ORDER BY (lower(u.username) = $2 OR (lower(u.email) = $2 AND ` + emailVisibleSQL + `)) DESC,
+                 partner.known IS NOT NULL DESC,
+                 lower(u.username) LIKE $3 ESCAPE '\' DESC,
+                 similarity(lower(u.username), $2) DESC,
+                 lower(u.username) ASC,
+                 u.id ASC
+        LIMIT $4 OFFSET $5
+    `
+	term := strings.ToLower(strings.TrimSpace(search.Query))
+	rows, err := s.db.Query(ctx, query, search.SearcherID, term, escapeLike(term)+"%", search.Limit, search.Offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search users for %q: %w", search.Query, err)
+	}
+	defer rows.Close()
+
+	users := make([]*models.PublicUser, 0)
+	for rows.Next() {
+		var u models.User
+		var isPartner bool
+		if err := rows.Scan(&u.ID, &u.Username, &u.Email, &u.EmailVisibility, &isPartner, &u.CreatedAt, &u.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user search row: %w", err)
+		}
+		rel := models.RelationshipStranger
+		if isPartner {
+			rel = models.RelationshipContact
+		}
+		users = append(users, u.ProfileFor(rel))
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating user search rows: %w", err)
+	}
+	return users, nil
+}
+
+// emailVisibleSQL is true when the searcher may see u's email; partner.known marks contacts.
+const emailVisibleSQL = `(u.email_visibility = 'everyone' OR (u.email_visibility = 'contacts' AND partner.known IS NOT NULL))`
+
+// escapeLike escapes the LIKE wildcards in s, using backslash as the escape character.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// tombstoneUsername and tombstoneEmail replace the identity of a deleted account while
+// keeping both columns unique.
+func tombstoneUsername(userID uuid.UUID) string {
+	return "deleted_" + strings.ReplaceAll(userID.String(), "-", "")
+}
+
+func tombstoneEmail(userID uuid.UUID) string {
+	return userID.String() + "@deleted.invalid"
+}
+
 var (
 	ErrUserNotFound   = fmt.Errorf("user not found")
 	ErrEmailExists    = fmt.Errorf("email already exists")
@@ -4,10 +4,14 @@ import (
 	"errors"
 	"log"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
+	"blinkchat-backend/internal/access"
 	"blinkchat-backend/internal/models"
 	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/websocket"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
@@ -15,19 +19,28 @@ import (
 
 // UserHandler exposes user-related HTTP handlers.
 type UserHandler struct {
-	userStore store.UserStore
+	userStore  store.UserStore
+	blockStore store.BlockStore
+	guard      *access.Guard
+	wsHub      *websocket.Hub
 }
 
 // NewUserHandler creates a UserHandler.
-func NewUserHandler(userStore store.UserStore) *UserHandler {
-	return &UserHandler{userStore: userStore}
+func NewUserHandler(userStore store.UserStore, blockStore store.BlockStore, guard *access.Guard, hub *websocket.Hub) *UserHandler {
+	return &UserHandler{userStore: userStore, blockStore: blockStore, guard: guard, wsHub: hub}
 }
 
-// GetUserByID returns the public profile for a user.
+// GetUserByID returns a user's profile as the caller may see it: fields the user keeps
+// private from strangers or contacts are left out.
 func (h *UserHandler) GetUserByID(c *gin.Context) {
+	viewerID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
 	userIDParam := c.Param("id")
 
-	if _, err := uuid.Parse(userIDParam); err != nil {
+	userID, err := uuid.Parse(userIDParam)
+	if err != nil {
 		log.Printf("GetUserByID: Invalid user ID format: %s, error: %v", userIDParam, err)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
 		return
@@ -45,43 +58,235 @@ func (h *UserHandler) GetUserByID(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, user.ToPublicUser())
+	rel, err := h.guard.Relationship(c.Request.Context(), viewerID, userID)
+	if err != nil {
+		log.Printf("GetUserByID: Failed to resolve relationship of %s to user %s: %v", viewerID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user information"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.ProfileFor(rel))
 }
 
-// SearchUsers performs a minimal search over users by email.
+// SearchUsers finds other users by username prefix, fuzzy username match or exact email,
+// ranking people the caller already chats with above strangers and leaving out anyone on
+// either side of a block with them. Results are paged with limit and offset.
 func (h *UserHandler) SearchUsers(c *gin.Context) {
-	searchQuery := c.Query("search")
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	searchQuery := strings.TrimSpace(c.Query("search"))
 	if searchQuery == "" {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query parameter is required"})
 		return
 	}
 
-	log.Printf("SearchUsers: Received search query: %s", searchQuery)
+	limit, err := strconv.Atoi(c.DefaultQuery("limit", "20"))
+	if err != nil || limit <= 0 || limit > 50 {
+		limit = 20
+	}
+	offset, err := strconv.Atoi(c.DefaultQuery("offset", "0"))
+	if err != nil || offset < 0 {
+		offset = 0
+	}
+
+	users, err := h.userStore.SearchUsers(c.Request.Context(), models.UserSearch{
+		Query:      searchQuery,
+		SearcherID: userID,
+		Limit:      limit,
+		Offset:     offset,
+	})
+	if err != nil {
+		log.Printf("SearchUsers: Error during search for '%s': %v", searchQuery, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error during user search"})
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
 
-	var foundUser *models.User
-	var searchErr error
+// GetUserPresence returns whether a user is online and, unless they hide it, when they
+// were last seen.
+func (h *UserHandler) GetUserPresence(c *gin.Context) {
+	viewerID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	userIDParam := c.Param("id")
+	userID, err := uuid.Parse(userIDParam)
+	if err != nil {
+		log.Printf("GetUserPresence: Invalid user ID format: %s, error: %v", userIDParam, err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return
+	}
 
-	if strings.Contains(searchQuery, "@") {
-		user, err := h.userStore.GetUserByEmail(c.Request.Context(), searchQuery)
-		if err == nil && user != nil {
-			foundUser = user
-		} else if !errors.Is(err, store.ErrUserNotFound) {
-			searchErr = err
+	presence, err := h.wsHub.GetPresence(c.Request.Context(), viewerID, userID)
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
 		}
-	} else {
-		log.Println("SearchUsers: Searching by username is not yet implemented.")
+		log.Printf("GetUserPresence: Failed to get presence of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve presence"})
+		return
 	}
 
-	if searchErr != nil {
-		log.Printf("SearchUsers: Error during search for '%s': %v", searchQuery, searchErr)
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Error during user search"})
+	c.JSON(http.StatusOK, presence)
+}
+
+// GetSettings returns the current user's privacy settings.
+func (h *UserHandler) GetSettings(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(c.Request.Context(), userID.String())
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("GetSettings: Failed to get user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, user.Settings())
+}
+
+// UpdateSettings changes the current user's privacy settings and returns the result.
+func (h *UserHandler) UpdateSettings(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	var req models.UpdateUserSettingsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		log.Printf("UpdateSettings: Bad request data: %v", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data", "details": err.Error()})
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(c.Request.Context(), userID.String())
+	if err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		log.Printf("UpdateSettings: Failed to get user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		return
+	}
+
+	settings := user.Settings()
+	if req.HideLastSeen != nil {
+		settings.HideLastSeen = *req.HideLastSeen
+	}
+	if req.EmailVisibility != nil {
+		settings.EmailVisibility = *req.EmailVisibility
+	}
+	if err := h.userStore.UpdateUserSettings(c.Request.Context(), userID, settings); err != nil {
+		log.Printf("UpdateSettings: Failed to update settings of user %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update settings"})
+		return
+	}
+
+	c.JSON(http.StatusOK, settings)
+}
+
+// BlockUser stops the target user from messaging the caller and hides each user's typing
+// and presence from the other. Blocking someone already blocked is a no-op.
+func (h *UserHandler) BlockUser(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	targetID, ok := h.loadTargetUser(c, "BlockUser", userID)
+	if !ok {
 		return
 	}
 
-	if foundUser != nil {
-		c.JSON(http.StatusOK, []*models.PublicUser{foundUser.ToPublicUser()})
+	if err := h.blockStore.BlockUser(c.Request.Context(), userID, targetID, time.Now()); err != nil {
+		log.Printf("BlockUser: Failed to block user %s for user %s: %v", targetID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+
+	// Clients may still show the other user as online; make both appear offline.
+	h.wsHub.BroadcastToUser(userID, websocket.MessageTypePresenceUpdate, &models.Presence{UserID: targetID})
+	h.wsHub.BroadcastToUser(targetID, websocket.MessageTypePresenceUpdate, &models.Presence{UserID: userID})
+	c.Status(http.StatusNoContent)
+}
+
+// UnblockUser lifts a block the caller placed on the target user.
+func (h *UserHandler) UnblockUser(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+	targetID, ok := h.loadTargetUser(c, "UnblockUser", userID)
+	if !ok {
 		return
 	}
 
-	c.JSON(http.StatusOK, make([]*models.PublicUser, 0))
+	if err := h.blockStore.UnblockUser(c.Request.Context(), userID, targetID); err != nil {
+		log.Printf("UnblockUser: Failed to unblock user %s for user %s: %v", targetID, userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+	c.Status(http.StatusNoContent)
+}
+
+// GetBlockedUsers lists the users the caller has blocked, most recent first.
+func (h *UserHandler) GetBlockedUsers(c *gin.Context) {
+	userID, ok := userIDFromContext(c)
+	if !ok {
+		return
+	}
+
+	blocked, err := h.blockStore.GetBlockedUsers(c.Request.Context(), userID)
+	if err != nil {
+		log.Printf("GetBlockedUsers: Failed to list users blocked by %s: %v", userID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve blocked users"})
+		return
+	}
+	c.JSON(http.StatusOK, blocked)
+}
+
+// loadTargetUser parses the :id parameter and checks that it names another existing user.
+func (h *UserHandler) loadTargetUser(c *gin.Context, op string, userID uuid.UUID) (uuid.UUID, bool) {
+	targetID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+		return uuid.Nil, false
+	}
+	if targetID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Cannot block or unblock yourself"})
+		return uuid.Nil, false
+	}
+	if _, err := h.userStore.GetUserByID(c.Request.Context(), targetID.String()); err != nil {
+		if errors.Is(err, store.ErrUserNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return uuid.Nil, false
+		}
+		log.Printf("%s: Failed to get user %s: %v", op, targetID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to retrieve user information"})
+		return uuid.Nil, false
+	}
+	return targetID, true
+}
+
+func userIDFromContext(c *gin.Context) (uuid.UUID, bool) {
+	userIDString, _ := c.Get("userID")
+	idStr, _ := userIDString.(string)
+	userID, err := uuid.Parse(idStr)
+	if err != nil {
+		log.Printf("userIDFromContext: Invalid userID from token: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Invalid user session"})
+		return uuid.Nil, false
+	}
+	return userID, true
 }
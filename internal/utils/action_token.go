@@ -0,0 +1,49 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"blinkchat-backend/internal/config"
+)
+
+// actionTokenBytes is the amount of randomness in an action token.
+const actionTokenBytes = 32
+
+// GenerateActionToken returns a token for a mailed action (email verification, password
+// reset) signed for purpose, and the hash to store for it.
+func GenerateActionToken(purpose string) (token string, hash string, err error) {
+	if config.Cfg == nil || config.Cfg.JWTSecret == "" {
+		return "", "", fmt.Errorf("JWT secret is not configured")
+	}
+	buf := make([]byte, actionTokenBytes)
+	if _, err := rand.Read(buf); err != nil {
+		return "", "", fmt.Errorf("failed to generate %s token: %w", purpose, err)
+	}
+	nonce := base64.RawURLEncoding.EncodeToString(buf)
+	token = nonce + "." + signActionToken(purpose, nonce)
+	return token, HashRefreshToken(token), nil
+}
+
+// ActionTokenHash checks that token was signed by this server for purpose and returns
+// the hash it is stored under. Forged tokens are rejected without a store lookup.
+func ActionTokenHash(token string, purpose string) (string, error) {
+	if config.Cfg == nil || config.Cfg.JWTSecret == "" {
+		return "", fmt.Errorf("JWT secret is not configured for validation")
+	}
+	nonce, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(signActionToken(purpose, nonce))) {
+		return "", fmt.Errorf("token signature is invalid")
+	}
+	return HashRefreshToken(token), nil
+}
+
+func signActionToken(purpose string, nonce string) string {
+	mac := hmac.New(sha256.New, []byte(config.Cfg.JWTSecret))
+	mac.Write([]byte(purpose + "." + nonce))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/models"
+)
+
+func TestActionTokenRoundTrip(t *testing.T) {
+	config.Cfg = &config.AppConfig{JWTSecret: "test-secret"}
+	token, hash, err := GenerateActionToken(models.ActionResetPassword)
+	if err != nil {
+		t.Fatalf("GenerateActionToken: %v", err)
+	}
+	got, err := ActionTokenHash(token, models.ActionResetPassword)
+	if err != nil || got != hash {
+		t.Fatalf("ActionTokenHash = %q, %v; want the generated hash %q", got, err, hash)
+	}
+	other, _, err := GenerateActionToken(models.ActionResetPassword)
+	if err != nil || other == token {
+		t.Errorf("second token %q, %v; want a different one", other, err)
+	}
+}
+
+func TestActionTokenHashRejectsForgeries(t *testing.T) {
+	config.Cfg = &config.AppConfig{JWTSecret: "test-secret"}
+	token, _, err := GenerateActionToken(models.ActionResetPassword)
+	if err != nil {
+		t.Fatalf("GenerateActionToken: %v", err)
+	}
+	nonce, signature, _ := strings.Cut(token, ".")
+	flip := func(s string) string {
+		if s[0] == 'A' {
+			return "B" + s[1:]
+		}
+		return "A" + s[1:]
+	}
+
+	for _, tt := range []struct {
+		name    string
+		token   string
+		purpose string
+		secret  string
+	}{
+		{"wrong purpose", token, models.ActionVerifyEmail, "test-secret"},
+		{"tampered nonce", flip(nonce) + "." + signature, models.ActionResetPassword, "test-secret"},
+		{"tampered signature", nonce + "." + flip(signature), models.ActionResetPassword, "test-secret"},
+		{"no signature", nonce, models.ActionResetPassword, "test-secret"},
+		{"empty", "", models.ActionResetPassword, "test-secret"},
+		{"signed with another secret", token, models.ActionResetPassword, "rotated-secret"},
+		{"no secret configured", token, models.ActionResetPassword, ""},
+	} {
+		config.Cfg = &config.AppConfig{JWTSecret: tt.secret}
+		if hash, err := ActionTokenHash(tt.token, tt.purpose); err == nil {
+			t.Errorf("%s: ActionTokenHash = %q, want an error", tt.name, hash)
+		}
+	}
+}
@@ -0,0 +1,58 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"blinkchat-backend/internal/config"
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// SignAttachmentURLs fills in download links for each attachment and its thumbnails
+// that stay valid for config.Cfg.AttachmentURLMaxAge. Links are only handed to users
+// allowed to see the attachment, so the signature is what authorises a download.
+func SignAttachmentURLs(attachments []*models.Attachment, now time.Time) {
+	expiresAt := now.Add(config.Cfg.AttachmentURLMaxAge).Truncate(time.Second)
+	for _, a := range attachments {
+		expires := strconv.FormatInt(expiresAt.Unix(), 10)
+		query := "?" + url.Values{"expires": {expires}, "signature": {signAttachmentURL(a.ID, expires)}}.Encode()
+		base := "/api/v1/attachments/" + a.ID.String()
+		a.URL = base + "/content" + query
+		a.URLExpiresAt = &expiresAt
+		for _, t := range a.Thumbnails {
+			t.URL = base + "/thumbnails/" + strconv.Itoa(t.MaxEdge) + query
+		}
+	}
+}
+
+// VerifyAttachmentURL checks the expires and signature query values of a download link
+// for attachmentID.
+func VerifyAttachmentURL(attachmentID uuid.UUID, expires string, signature string, now time.Time) error {
+	if config.Cfg == nil || config.Cfg.JWTSecret == "" {
+		return fmt.Errorf("JWT secret is not configured for validation")
+	}
+	if !hmac.Equal([]byte(signature), []byte(signAttachmentURL(attachmentID, expires))) {
+		return fmt.Errorf("download link signature is invalid")
+	}
+	expiresUnix, err := strconv.ParseInt(expires, 10, 64)
+	if err != nil {
+		return fmt.Errorf("download link expiry is invalid")
+	}
+	if now.After(time.Unix(expiresUnix, 0)) {
+		return fmt.Errorf("download link has expired")
+	}
+	return nil
+}
+
+func signAttachmentURL(attachmentID uuid.UUID, expires string) string {
+	mac := hmac.New(sha256.New, []byte(config.Cfg.JWTSecret))
+	mac.Write([]byte("attachment." + attachmentID.String() + "." + expires))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -11,23 +11,25 @@ import (
 )
 
 type Claims struct {
-	UserID string `json:"user_id"`
+	UserID    string `json:"user_id"`
+	SessionID string `json:"sid"`
 	jwt.RegisteredClaims
 }
 
-// GenerateJWT builds a signed JWT for the supplied user ID.
-func GenerateJWT(userID uuid.UUID) (string, error) {
+// GenerateJWT builds a short-lived signed access token for the supplied user and session.
+func GenerateJWT(userID uuid.UUID, sessionID uuid.UUID) (string, error) {
 	if config.Cfg == nil || config.Cfg.JWTSecret == "" {
 		return "", fmt.Errorf("JWT secret is not configured")
 	}
-	if config.Cfg.TokenMaxAge <= 0 {
+	if config.Cfg.AccessTokenMaxAge <= 0 {
 		return "", fmt.Errorf("token max age is not configured or invalid")
 	}
 
 	claims := &Claims{
-		UserID: userID.String(),
+		UserID:    userID.String(),
+		SessionID: sessionID.String(),
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.Cfg.TokenMaxAge)),
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(config.Cfg.AccessTokenMaxAge)),
 			IssuedAt:  jwt.NewNumericDate(time.Now()),
 			NotBefore: jwt.NewNumericDate(time.Now()),
 			Issuer:    "blinkchat-backend",
@@ -0,0 +1,229 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"time"
+
+	"blinkchat-backend/internal/broker"
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// Envelope kinds exchanged between replicas over the broker.
+const (
+	envelopeDeliver           = "deliver"
+	envelopeEvent             = "event"
+	envelopeDisconnectSession = "disconnect_session"
+	envelopeDisconnectUser    = "disconnect_user"
+	envelopePresence          = "presence"
+	envelopePresenceRequest   = "presence_request"
+	envelopeHeartbeat         = "heartbeat"
+)
+
+const (
+	// heartbeatInterval is how often a replica announces it is alive.
+	heartbeatInterval = 15 * time.Second
+	// instanceTimeout is how long a silent replica's presence is still trusted.
+	instanceTimeout = 3 * heartbeatInterval
+	// presenceChunkSize bounds the users listed in one presence envelope.
+	presenceChunkSize = 100
+)
+
+// envelope is the unit of replica-to-replica traffic. Each replica delivers to its own
+// clients before publishing, and ignores envelopes it published itself.
+type envelope struct {
+	Origin    string            `json:"origin"`
+	Kind      string            `json:"kind"`
+	UserIDs   []uuid.UUID       `json:"userIds,omitempty"`
+	SessionID *uuid.UUID        `json:"sessionId,omitempty"`
+	Type      string            `json:"type,omitempty"`
+	Payload   json.RawMessage   `json:"payload,omitempty"`
+	Event     *models.ChatEvent `json:"event,omitempty"`
+	EventSeq  int64             `json:"eventSeq,omitempty"`
+	Online    bool              `json:"online,omitempty"`
+}
+
+// remoteInstance is what this replica knows about another one.
+type remoteInstance struct {
+	users    map[uuid.UUID]bool
+	lastSeen time.Time
+}
+
+// publish sends an envelope to the other replicas. Chat events too large for the broker
+// are sent by seq and loaded from the event log by the receivers.
+func (h *Hub) publish(env *envelope) {
+	env.Origin = h.instanceID
+	msg, err := json.Marshal(env)
+	if err != nil {
+		log.Printf("Hub (publish): Error encoding %s envelope: %v", env.Kind, err)
+		return
+	}
+	if limit := h.broker.MaxMessageSize(); limit > 0 && len(msg) > limit && env.Event != nil {
+		env.EventSeq, env.Event = env.Event.Seq, nil
+		if msg, err = json.Marshal(env); err != nil {
+			log.Printf("Hub (publish): Error encoding %s envelope: %v", env.Kind, err)
+			return
+		}
+	}
+
+	if err := h.broker.Publish(context.Background(), msg); err != nil {
+		if errors.Is(err, broker.ErrMessageTooLarge) {
+			log.Printf("Hub (publish): Dropping %s envelope of %d bytes for other replicas: %v", env.Kind, len(msg), err)
+			return
+		}
+		log.Printf("Hub (publish): Error publishing %s envelope: %v", env.Kind, err)
+	}
+}
+
+// handleBrokerMessage applies an envelope published by another replica.
+func (h *Hub) handleBrokerMessage(msg []byte) {
+	var env envelope
+	if err := json.Unmarshal(msg, &env); err != nil {
+		log.Printf("Hub (broker): Error decoding envelope: %v", err)
+		return
+	}
+	if env.Origin == h.instanceID {
+		return
+	}
+	h.touchInstance(env.Origin)
+
+	switch env.Kind {
+	case envelopeDeliver:
+		h.deliverLocal(env.UserIDs, func(c *Client) { c.SendMessage(env.Type, env.Payload) })
+
+	case envelopeEvent:
+		event := env.Event
+		if event == nil {
+			loaded, err := h.eventLog.Get(context.Background(), env.EventSeq)
+			if err != nil {
+				log.Printf("Hub (broker): Error loading event %d: %v", env.EventSeq, err)
+				return
+			}
+			event = loaded
+		}
+		h.deliverLocal(env.UserIDs, func(c *Client) { c.SendEvent(event) })
+
+	case envelopeDisconnectSession:
+		if env.SessionID != nil {
+			h.disconnectSessionLocal(*env.SessionID)
+		}
+
+	case envelopeDisconnectUser:
+		for _, userID := range env.UserIDs {
+			h.disconnectUserLocal(userID)
+		}
+
+	case envelopePresence:
+		h.presenceMux.Lock()
+		instance := h.remoteInstanceLocked(env.Origin)
+		for _, userID := range env.UserIDs {
+			if env.Online {
+				instance.users[userID] = true
+			} else {
+				delete(instance.users, userID)
+			}
+		}
+		h.presenceMux.Unlock()
+
+	case envelopePresenceRequest:
+		h.announceOnlineUsers()
+
+	case envelopeHeartbeat:
+		// touchInstance above is all a heartbeat needs.
+
+	default:
+		log.Printf("Hub (broker): Unknown envelope kind '%s' from replica %s", env.Kind, env.Origin)
+	}
+}
+
+// deliverLocal runs send for every client of the listed users connected to this replica.
+func (h *Hub) deliverLocal(userIDs []uuid.UUID, send func(*Client)) {
+	h.clientsMux.RLock()
+	defer h.clientsMux.RUnlock()
+	for _, userID := range userIDs {
+		for client := range h.clients[userID] {
+			send(client)
+		}
+	}
+}
+
+func (h *Hub) touchInstance(instanceID string) {
+	h.presenceMux.Lock()
+	defer h.presenceMux.Unlock()
+	h.remoteInstanceLocked(instanceID).lastSeen = time.Now()
+}
+
+// remoteInstanceLocked returns the record for a replica, creating it. Callers must hold presenceMux.
+func (h *Hub) remoteInstanceLocked(instanceID string) *remoteInstance {
+	instance, ok := h.remote[instanceID]
+	if !ok {
+		instance = &remoteInstance{users: make(map[uuid.UUID]bool), lastSeen: time.Now()}
+		h.remote[instanceID] = instance
+	}
+	return instance
+}
+
+// IsUserOnline reports whether the user has a connection on this or any live replica.
+func (h *Hub) IsUserOnline(userID uuid.UUID) bool {
+	h.clientsMux.RLock()
+	_, local := h.clients[userID]
+	h.clientsMux.RUnlock()
+	return local || h.isUserOnlineRemotely(userID)
+}
+
+// isUserOnlineRemotely reports whether another live replica has announced the user.
+func (h *Hub) isUserOnlineRemotely(userID uuid.UUID) bool {
+	h.presenceMux.RLock()
+	defer h.presenceMux.RUnlock()
+	for _, instance := range h.remote {
+		if instance.users[userID] && time.Since(instance.lastSeen) < instanceTimeout {
+			return true
+		}
+	}
+	return false
+}
+
+// announcePresence tells the other replicas that a user came online or went offline here.
+func (h *Hub) announcePresence(userID uuid.UUID, online bool) {
+	h.publish(&envelope{Kind: envelopePresence, UserIDs: []uuid.UUID{userID}, Online: online})
+}
+
+// announceOnlineUsers publishes every user connected to this replica, in chunks.
+func (h *Hub) announceOnlineUsers() {
+	h.clientsMux.RLock()
+	userIDs := make([]uuid.UUID, 0, len(h.clients))
+	for userID := range h.clients {
+		userIDs = append(userIDs, userID)
+	}
+	h.clientsMux.RUnlock()
+
+	for start := 0; start < len(userIDs); start += presenceChunkSize {
+		end := min(start+presenceChunkSize, len(userIDs))
+		h.publish(&envelope{Kind: envelopePresence, UserIDs: userIDs[start:end], Online: true})
+	}
+}
+
+// runBackplane joins the other replicas: it asks for their online users, then sends
+// heartbeats and forgets replicas that stopped sending theirs.
+func (h *Hub) runBackplane() {
+	h.publish(&envelope{Kind: envelopePresenceRequest})
+
+	ticker := time.NewTicker(heartbeatInterval)
+	defer ticker.Stop()
+	for range ticker.C {
+		h.publish(&envelope{Kind: envelopeHeartbeat})
+
+		h.presenceMux.Lock()
+		for instanceID, instance := range h.remote {
+			if time.Since(instance.lastSeen) >= instanceTimeout {
+				log.Printf("Hub (broker): Replica %s stopped sending heartbeats; dropping its %d online user(s)", instanceID, len(instance.users))
+				delete(h.remote, instanceID)
+			}
+		}
+		h.presenceMux.Unlock()
+	}
+}
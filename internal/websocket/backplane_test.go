@@ -0,0 +1,93 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/broker"
+	"blinkchat-backend/internal/events"
+	"blinkchat-backend/internal/ratelimit"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testDatabaseEnv names the DSN of a Postgres database for the broker between replicas.
+// Tests that need it are skipped when it is unset.
+const testDatabaseEnv = "TEST_DATABASE_URL"
+
+// newReplica returns a hub on db whose broker listens on its own Postgres pool, as a
+// separate server instance would.
+func newReplica(t *testing.T, dsn string, db *store.MemoryDB) *Hub {
+	t.Helper()
+	ctx := context.Background()
+	pool, err := pgxpool.New(ctx, dsn)
+	if err != nil {
+		t.Fatalf("connect to test database: %v", err)
+	}
+	t.Cleanup(pool.Close)
+	b, err := broker.NewPostgres(ctx, pool)
+	if err != nil {
+		t.Fatalf("NewPostgres: %v", err)
+	}
+	t.Cleanup(func() { _ = b.Close() })
+
+	us, cs, ms := store.NewMemoryUserStore(db), store.NewMemoryChatStore(db), store.NewMemoryMessageStore(db)
+	guard := access.NewGuard(cs, ms, store.NewMemoryBlockStore(db), store.NewMemoryAttachmentStore(db))
+	eventLog := events.NewLog(store.NewMemoryEventStore(db), guard)
+	return NewHub(us, cs, ms, guard, eventLog, b, ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil))
+}
+
+// connectLocal registers a client on the hub without going through Run.
+func connectLocal(h *Hub, client *Client) {
+	h.clientsMux.Lock()
+	defer h.clientsMux.Unlock()
+	h.clients[client.userID] = map[*Client]bool{client: true}
+}
+
+func TestOversizedEventsReachOtherReplicasBySeq(t *testing.T) {
+	dsn := os.Getenv(testDatabaseEnv)
+	if dsn == "" {
+		t.Skipf("%s is not set", testDatabaseEnv)
+	}
+	db := store.NewMemoryDB()
+	origin, remote := newReplica(t, dsn, db), newReplica(t, dsn, db)
+
+	sender, recipient := uuid.New(), uuid.New()
+	chat, err := store.NewMemoryChatStore(db).CreateChat(context.Background(), []uuid.UUID{sender, recipient})
+	if err != nil {
+		t.Fatalf("CreateChat: %v", err)
+	}
+	client := newTestClient(remote, recipient)
+	connectLocal(remote, client)
+
+	// NOTIFY payloads stop at 8000 bytes, so only the seq can travel through the broker.
+	content := strings.Repeat("x", 16<<10)
+	origin.PublishChatEvent(chat.ID, []uuid.UUID{recipient}, MessageTypeNewMessage, map[string]string{"content": content})
+
+	select {
+	case raw := <-client.send:
+		var frame struct {
+			Type    string            `json:"type"`
+			Seq     int64             `json:"seq"`
+			Payload map[string]string `json:"payload"`
+		}
+		if err := json.Unmarshal(raw, &frame); err != nil {
+			t.Fatalf("decode frame: %v", err)
+		}
+		if frame.Type != MessageTypeNewMessage || frame.Seq == 0 {
+			t.Errorf("got %s frame with seq %d, want a recorded %s event", frame.Type, frame.Seq, MessageTypeNewMessage)
+		}
+		if frame.Payload["content"] != content {
+			t.Errorf("delivered content has %d bytes, want the full %d", len(frame.Payload["content"]), len(content))
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("the other replica did not deliver the event")
+	}
+}
@@ -2,10 +2,14 @@ package websocket
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"log"
+	"sync"
 	"time"
 
+	"blinkchat-backend/internal/models"
+
 	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
 )
@@ -14,7 +18,10 @@ const (
 	writeWait      = 10 * time.Second
 	pongWait       = 60 * time.Second
 	pingPeriod     = (pongWait * 9) / 10
-	maxMessageSize = 2048
+	maxMessageSize = 16384
+
+	// closeCodeSessionRevoked is sent when the connection's login session is revoked.
+	closeCodeSessionRevoked = 4001
 )
 
 var (
@@ -24,19 +31,30 @@ var (
 
 // Client bridges a WebSocket connection with the hub.
 type Client struct {
-	hub    *Hub
-	conn   *websocket.Conn
-	send   chan []byte
-	userID uuid.UUID
+	hub       *Hub
+	conn      *websocket.Conn
+	send      chan []byte
+	userID    uuid.UUID
+	sessionID uuid.UUID
+	version   int
+
+	// While a sync is being streamed, live chat events are held back in deferred so the
+	// client sees the backlog first.
+	syncMux  sync.Mutex
+	syncing  bool
+	deferred []*models.ChatEvent
 }
 
-// NewClient constructs a Client for the given hub connection.
-func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID) *Client {
+// NewClient constructs a Client for the given hub connection, speaking the negotiated
+// protocol version.
+func NewClient(hub *Hub, conn *websocket.Conn, userID uuid.UUID, sessionID uuid.UUID, version int) *Client {
 	return &Client{
-		hub:    hub,
-		conn:   conn,
-		send:   make(chan []byte, 256),
-		userID: userID,
+		hub:       hub,
+		conn:      conn,
+		send:      make(chan []byte, 256),
+		userID:    userID,
+		sessionID: sessionID,
+		version:   version,
 	}
 }
 
@@ -69,11 +87,7 @@ func (c *Client) readPump() {
 			message = bytes.TrimSpace(bytes.Replace(message, newline, space, -1))
 			log.Printf("Client %s (User: %s) readPump: Received message: %s", c.conn.RemoteAddr(), c.userID, message)
 
-			hubMessage := HubMessage{
-				client:  c,
-				rawJSON: message,
-			}
-			c.hub.processMessage <- hubMessage
+			c.hub.dispatch(c, message)
 		} else {
 			log.Printf("Client %s (User: %s) readPump: Received non-text message type: %d", c.conn.RemoteAddr(), c.userID, messageType)
 		}
@@ -125,9 +139,65 @@ func (c *Client) writePump() {
 
 // SendMessage places a WebSocketMessage onto the outbound queue for this client.
 func (c *Client) SendMessage(msgType string, payload interface{}) {
-	wsMsg := WebSocketMessage{
+	c.enqueue(WebSocketMessage{
 		Type:    msgType,
 		Payload: payload,
+	})
+}
+
+// Reply answers the frame being handled in ctx, echoing its requestId.
+func (c *Client) Reply(ctx context.Context, msgType string, payload interface{}) {
+	c.enqueue(WebSocketMessage{
+		Type:      msgType,
+		RequestID: requestIDFromContext(ctx),
+		Payload:   payload,
+	})
+}
+
+// SendError replies to the frame being handled in ctx with a typed error.
+func (c *Client) SendError(ctx context.Context, code ErrorCode, message string) {
+	c.Reply(ctx, MessageTypeError, ErrorPayload{Code: code, Message: message})
+}
+
+// SendEvent delivers a logged chat event, tagged with its seq. During a sync the event
+// is deferred until the backlog has been sent.
+func (c *Client) SendEvent(event *models.ChatEvent) {
+	c.syncMux.Lock()
+	defer c.syncMux.Unlock()
+	if c.syncing {
+		c.deferred = append(c.deferred, event)
+		return
+	}
+	c.enqueue(eventMessage(event))
+}
+
+// beginSync starts holding back live chat events.
+func (c *Client) beginSync() {
+	c.syncMux.Lock()
+	defer c.syncMux.Unlock()
+	c.syncing = true
+}
+
+// endSync releases the events held back during a sync, skipping those the sync already
+// delivered: synced maps each replayed chat to the last seq sent for it.
+func (c *Client) endSync(synced map[uuid.UUID]int64) {
+	c.syncMux.Lock()
+	defer c.syncMux.Unlock()
+	for _, event := range c.deferred {
+		if lastSeq, ok := synced[event.ChatID]; ok && event.Seq <= lastSeq {
+			continue
+		}
+		c.enqueue(eventMessage(event))
+	}
+	c.syncing = false
+	c.deferred = nil
+}
+
+func (c *Client) enqueue(wsMsg WebSocketMessage) {
+	if c.version >= ProtocolVersionCurrent {
+		wsMsg.Version = c.version
+	} else {
+		wsMsg.RequestID = ""
 	}
 	jsonMsg, err := json.Marshal(wsMsg)
 	if err != nil {
@@ -138,12 +208,19 @@ func (c *Client) SendMessage(msgType string, payload interface{}) {
 	select {
 	case c.send <- jsonMsg:
 	default:
-		log.Printf("Client %s (User: %s) SendMessage: Send channel full. Dropping message of type %s.", c.conn.RemoteAddr(), c.userID, msgType)
+		log.Printf("Client %s (User: %s) SendMessage: Send channel full. Dropping message of type %s.", c.conn.RemoteAddr(), c.userID, wsMsg.Type)
 	}
 }
 
-// HubMessage holds raw JSON from a client awaiting processing.
-type HubMessage struct {
-	client  *Client
-	rawJSON []byte
+func eventMessage(event *models.ChatEvent) WebSocketMessage {
+	return WebSocketMessage{Type: event.Type, Seq: event.Seq, Payload: event.Payload}
+}
+
+// closeWithCode sends a close frame and tears down the connection; readPump then unregisters the client.
+func (c *Client) closeWithCode(code int, reason string) {
+	deadline := time.Now().Add(writeWait)
+	if err := c.conn.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(code, reason), deadline); err != nil {
+		log.Printf("Client %s (User: %s) closeWithCode: Error sending close frame: %v", c.conn.RemoteAddr(), c.userID, err)
+	}
+	_ = c.conn.Close()
 }
@@ -0,0 +1,123 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"blinkchat-backend/internal/ratelimit"
+
+	"github.com/gin-gonic/gin/binding"
+)
+
+// handlerTimeout bounds the work done for one incoming frame.
+const handlerTimeout = 30 * time.Second
+
+// incomingMessage is a client frame. Its payload is decoded once, by the handler
+// registered for its type.
+type incomingMessage struct {
+	Version   int             `json:"v,omitempty"`
+	Type      string          `json:"type"`
+	RequestID string          `json:"requestId,omitempty"`
+	Payload   json.RawMessage `json:"payload,omitempty"`
+}
+
+// messageHandler decodes, validates and handles the payload of one frame type.
+type messageHandler func(ctx context.Context, client *Client, payload json.RawMessage)
+
+// handle registers fn for msgType. The payload is decoded into P and checked against its
+// binding tags, the same ones REST requests use, before fn runs.
+func handle[P any](h *Hub, msgType string, fn func(ctx context.Context, client *Client, payload P)) {
+	h.handlers[msgType] = func(ctx context.Context, client *Client, raw json.RawMessage) {
+		var payload P
+		if len(raw) > 0 {
+			if err := json.Unmarshal(raw, &payload); err != nil {
+				log.Printf("WebSocket Hub: Error decoding %s payload from User %s: %v", msgType, client.userID, err)
+				client.SendError(ctx, ErrCodeInvalidPayload, fmt.Sprintf("Invalid %s payload", msgType))
+				return
+			}
+		}
+		if err := binding.Validator.ValidateStruct(&payload); err != nil {
+			client.SendError(ctx, ErrCodeValidation, fmt.Sprintf("Invalid %s payload: %v", msgType, err))
+			return
+		}
+		fn(ctx, client, payload)
+	}
+}
+
+// registerHandlers wires every client message type to its handler.
+func (h *Hub) registerHandlers() {
+	handle(h, MessageTypeNewMessage, h.handleNewChatMessageViaWS)
+	handle(h, MessageTypeMessageStatusUpdate, h.handleMessageStatusUpdate)
+	handle(h, MessageTypeMarkChatRead, h.handleMarkChatRead)
+	handle(h, MessageTypeSync, h.handleSync)
+	handle(h, MessageTypeTypingIndicator, h.handleTypingIndicator)
+}
+
+// dispatch handles one frame from a client. It runs on the client's read goroutine, so
+// each client's frames are handled in order and a slow handler only holds up its own
+// connection, never the hub loop.
+func (h *Hub) dispatch(client *Client, rawJSON []byte) {
+	ctx, cancel := context.WithTimeout(context.Background(), handlerTimeout)
+	defer cancel()
+
+	var msg incomingMessage
+	if err := json.Unmarshal(rawJSON, &msg); err != nil {
+		if !h.allowFrame(ctx, client, ratelimit.PolicyWSDefault) {
+			return
+		}
+		log.Printf("WebSocket Hub: Error unmarshalling message from User %s: %v. Raw: %s", client.userID, err, string(rawJSON))
+		client.SendError(ctx, ErrCodeInvalidFrame, "Invalid message format")
+		return
+	}
+	if len(msg.RequestID) > maxRequestIDLength {
+		if !h.allowFrame(ctx, client, ratelimit.PolicyWSDefault) {
+			return
+		}
+		client.SendError(ctx, ErrCodeInvalidFrame, "requestId is too long")
+		return
+	}
+	ctx = withRequestID(ctx, msg.RequestID)
+	if !h.allowFrame(ctx, client, h.framePolicy(msg.Type)) {
+		return
+	}
+	if msg.Version != 0 && msg.Version != client.version {
+		client.SendError(ctx, ErrCodeUnsupportedVersion, fmt.Sprintf("Connection speaks protocol version %d", client.version))
+		return
+	}
+
+	handler, ok := h.handlers[msg.Type]
+	if !ok {
+		log.Printf("WebSocket Hub: Unknown message type '%s' from User %s", msg.Type, client.userID)
+		client.SendError(ctx, ErrCodeUnknownType, "Unknown message type")
+		return
+	}
+	log.Printf("WebSocket Hub: Processing message type '%s' from User %s", msg.Type, client.userID)
+	handler(ctx, client, msg.Payload)
+}
+
+// framePolicy returns the rate-limit policy for a frame type, falling back to the default.
+func (h *Hub) framePolicy(msgType string) string {
+	if _, ok := h.limiter.Policy(ratelimit.WSPolicy(msgType)); ok {
+		return ratelimit.WSPolicy(msgType)
+	}
+	return ratelimit.PolicyWSDefault
+}
+
+// allowFrame charges a frame to the client's user under policyName and replies with a
+// rate_limited error if the budget is spent.
+func (h *Hub) allowFrame(ctx context.Context, client *Client, policyName string) bool {
+	result := h.limiter.Allow(ctx, policyName, client.userID.String())
+	if result.Allowed {
+		return true
+	}
+	log.Printf("WebSocket Hub: User %s is over the %s rate limit", client.userID, policyName)
+	client.Reply(ctx, MessageTypeError, ErrorPayload{
+		Code:         ErrCodeRateLimited,
+		Message:      "Too many messages, please slow down",
+		RetryAfterMs: result.RetryAfter.Milliseconds(),
+	})
+	return false
+}
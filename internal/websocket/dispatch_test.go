@@ -0,0 +1,27 @@
+package websocket
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/ratelimit"
+)
+
+func TestDispatchChargesRejectedRequestIDs(t *testing.T) {
+	f := newAccessFixture(t)
+	f.hub.limiter = ratelimit.NewLimiter(ratelimit.NewMemoryStore(), map[string]ratelimit.Policy{
+		ratelimit.PolicyWSDefault: {Burst: 1, Period: time.Minute},
+	})
+	client := newTestClient(f.hub, f.member)
+	frame := []byte(`{"type":"` + MessageTypeTypingIndicator + `","requestId":"` + strings.Repeat("r", maxRequestIDLength+1) + `"}`)
+
+	f.hub.dispatch(client, frame)
+	if got := nextFrame(t, client); got.Payload.Code != ErrCodeInvalidFrame {
+		t.Fatalf("first frame: got code %q, want %q", got.Payload.Code, ErrCodeInvalidFrame)
+	}
+	f.hub.dispatch(client, frame)
+	if got := nextFrame(t, client); got.Payload.Code != ErrCodeRateLimited {
+		t.Fatalf("second frame: got code %q, want %q", got.Payload.Code, ErrCodeRateLimited)
+	}
+}
@@ -3,7 +3,9 @@ package websocket
 import (
 	"log"
 	"net/http"
+	"strconv"
 
+	"blinkchat-backend/internal/store"
 	"blinkchat-backend/internal/utils"
 
 	"github.com/gin-gonic/gin"
@@ -21,12 +23,13 @@ var upgrader = websocket.Upgrader{
 
 // WSHandler upgrades HTTP connections and attaches them to the hub.
 type WSHandler struct {
-	hub *Hub
+	hub          *Hub
+	sessionStore store.SessionStore
 }
 
 // NewWSHandler returns a WebSocket handler bound to the hub.
-func NewWSHandler(hub *Hub) *WSHandler {
-	return &WSHandler{hub: hub}
+func NewWSHandler(hub *Hub, sessionStore store.SessionStore) *WSHandler {
+	return &WSHandler{hub: hub, sessionStore: sessionStore}
 }
 
 // HandleWebSocketConnection upgrades the request and registers the resulting client.
@@ -52,7 +55,33 @@ func (h *WSHandler) HandleWebSocketConnection(c *gin.Context) {
 		return
 	}
 
-	log.Printf("WS Handler: Authenticated user %s for WebSocket connection", userID)
+	sessionID, err := uuid.Parse(claims.SessionID)
+	if err != nil {
+		log.Printf("WS Handler: Token for user %s has no valid session: %v", userID, err)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+	session, err := h.sessionStore.GetSession(c.Request.Context(), sessionID)
+	if err != nil || !session.IsActive() || session.UserID != userID {
+		log.Printf("WS Handler: Session %s for user %s is not active: %v", sessionID, userID, err)
+		c.AbortWithStatus(http.StatusUnauthorized)
+		return
+	}
+
+	log.Printf("WS Handler: Authenticated user %s (session %s) for WebSocket connection", userID, sessionID)
+
+	requested := ProtocolVersionLegacy
+	if v := c.Query("v"); v != "" {
+		if requested, err = strconv.Atoi(v); err != nil {
+			requested = 0
+		}
+	}
+	version, ok := negotiateProtocolVersion(requested)
+	if !ok {
+		log.Printf("WS Handler: User %s asked for unsupported protocol version '%s'", userID, c.Query("v"))
+		c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Unsupported protocol version", "supportedVersions": SupportedProtocolVersions})
+		return
+	}
 
 	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
@@ -61,7 +90,14 @@ func (h *WSHandler) HandleWebSocketConnection(c *gin.Context) {
 	}
 	log.Printf("WS Handler: Connection upgraded for user %s from %s", userID, conn.RemoteAddr())
 
-	client := NewClient(h.hub, conn, userID)
+	client := NewClient(h.hub, conn, userID, sessionID, version)
+	if version >= ProtocolVersionCurrent {
+		client.SendMessage(MessageTypeWelcome, WelcomePayload{
+			Version:           version,
+			SupportedVersions: SupportedProtocolVersions,
+			UserID:            userID,
+		})
+	}
 	h.hub.register <- client
 
 	go client.writePump()
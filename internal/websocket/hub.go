@@ -8,118 +8,107 @@ import (
 	"sync"
 	"time"
 
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/broker"
+	"blinkchat-backend/internal/events"
 	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/ratelimit"
 	"blinkchat-backend/internal/store"
+	"blinkchat-backend/internal/utils"
 
 	"github.com/google/uuid"
 )
 
-// Hub maintains active WebSocket clients and broadcasts messages.
+// Hub maintains active WebSocket clients and broadcasts messages. Fan-out to users goes
+// to local clients directly and to other replicas through the broker.
 type Hub struct {
 	clients    map[uuid.UUID]map[*Client]bool
 	clientsMux sync.RWMutex
 
-	processMessage chan HubMessage
-	register       chan *Client
-	unregister     chan *Client
+	broker      broker.Broker
+	instanceID  string
+	remote      map[string]*remoteInstance // replica ID -> its online users
+	presenceMux sync.RWMutex
+
+	register   chan *Client
+	unregister chan *Client
+	handlers   map[string]messageHandler
+
+	// presenceChanges carries first-connect/last-disconnect transitions to runPresence,
+	// which does the store work for them off the Run loop, in order.
+	presenceChanges *presenceQueue
 
 	userStore    store.UserStore
 	chatStore    store.ChatStore
 	messageStore store.MessageStore
+	guard        *access.Guard
+	eventLog     *events.Log
+	limiter      *ratelimit.Limiter
 }
 
-// NewHub returns a Hub wired to the provided stores.
-func NewHub(us store.UserStore, cs store.ChatStore, ms store.MessageStore) *Hub {
-	return &Hub{
-		clients:        make(map[uuid.UUID]map[*Client]bool),
-		processMessage: make(chan HubMessage),
-		register:       make(chan *Client),
-		unregister:     make(chan *Client),
-		userStore:      us,
-		chatStore:      cs,
-		messageStore:   ms,
+// NewHub returns a Hub wired to the provided stores and subscribed to the broker.
+func NewHub(us store.UserStore, cs store.ChatStore, ms store.MessageStore, guard *access.Guard, eventLog *events.Log, b broker.Broker, limiter *ratelimit.Limiter) *Hub {
+	h := &Hub{
+		clients:         make(map[uuid.UUID]map[*Client]bool),
+		broker:          b,
+		instanceID:      uuid.NewString(),
+		remote:          make(map[string]*remoteInstance),
+		register:        make(chan *Client),
+		unregister:      make(chan *Client),
+		handlers:        make(map[string]messageHandler),
+		presenceChanges: newPresenceQueue(),
+		userStore:       us,
+		chatStore:       cs,
+		messageStore:    ms,
+		guard:           guard,
+		eventLog:        eventLog,
+		limiter:         limiter,
 	}
+	h.registerHandlers()
+	b.Subscribe(h.handleBrokerMessage)
+	return h
 }
 
 // Run processes hub events until the process exits.
 func (h *Hub) Run() {
-	log.Println("WebSocket Hub: Starting...")
+	log.Printf("WebSocket Hub: Starting (replica %s)...", h.instanceID)
+	go h.runBackplane()
+	go h.runPresence()
 	for {
 		select {
 		case client := <-h.register:
 			h.clientsMux.Lock()
+			firstConnection := false
 			if _, ok := h.clients[client.userID]; !ok {
 				h.clients[client.userID] = make(map[*Client]bool)
+				firstConnection = true
 			}
 			h.clients[client.userID][client] = true
 			log.Printf("WebSocket Hub: Client registered (User: %s, RemoteAddr: %s). Total for user: %d", client.userID, client.conn.RemoteAddr(), len(h.clients[client.userID]))
 			h.clientsMux.Unlock()
+			if firstConnection {
+				h.presenceChanges.push(presenceChange{userID: client.userID, online: true})
+			}
 
 		case client := <-h.unregister:
 			h.clientsMux.Lock()
+			lastConnection := false
 			if userClients, ok := h.clients[client.userID]; ok {
 				if _, clientExists := userClients[client]; clientExists {
 					close(client.send)
 					delete(userClients, client)
 					if len(userClients) == 0 {
 						delete(h.clients, client.userID)
+						lastConnection = true
 					}
 					log.Printf("WebSocket Hub: Client unregistered (User: %s, RemoteAddr: %s). Remaining for user: %d", client.userID, client.conn.RemoteAddr(), len(userClients))
 				}
 			}
 			h.clientsMux.Unlock()
-
-		case hubMsg := <-h.processMessage:
-			h.handleIncomingMessage(hubMsg.client, hubMsg.rawJSON)
-		}
-	}
-}
-
-func (h *Hub) handleIncomingMessage(senderClient *Client, rawJSON []byte) {
-	var wsMsg WebSocketMessage
-	if err := json.Unmarshal(rawJSON, &wsMsg); err != nil {
-		log.Printf("WebSocket Hub: Error unmarshalling message from User %s: %v. Raw: %s", senderClient.userID, err, string(rawJSON))
-		senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Invalid message format"})
-		return
-	}
-
-	log.Printf("WebSocket Hub: Processing message type '%s' from User %s", wsMsg.Type, senderClient.userID)
-	ctx := context.Background()
-
-	switch wsMsg.Type {
-	case MessageTypeNewMessage:
-		var payload NewMessagePayload
-		payloadBytes, _ := json.Marshal(wsMsg.Payload)
-		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-			log.Printf("WebSocket Hub: Error unmarshalling NewMessagePayload from User %s: %v", senderClient.userID, err)
-			senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Invalid new_message payload"})
-			return
-		}
-		h.handleNewChatMessageViaWS(ctx, senderClient, payload)
-
-	case MessageTypeMessageStatusUpdate:
-		var payload MessageStatusUpdatePayload
-		payloadBytes, _ := json.Marshal(wsMsg.Payload)
-		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-			log.Printf("WebSocket Hub: Error unmarshalling MessageStatusUpdatePayload from User %s: %v", senderClient.userID, err)
-			senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Invalid message_status_update payload"})
-			return
-		}
-		h.handleMessageStatusUpdate(ctx, senderClient, payload)
-
-	case MessageTypeTypingIndicator:
-		var payload TypingIndicatorPayload
-		payloadBytes, _ := json.Marshal(wsMsg.Payload)
-		if err := json.Unmarshal(payloadBytes, &payload); err != nil {
-			log.Printf("WebSocket Hub: Error unmarshalling TypingIndicatorPayload from User %s: %v", senderClient.userID, err)
-			senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Invalid typing_indicator payload"})
-			return
+			if lastConnection {
+				h.presenceChanges.push(presenceChange{userID: client.userID, online: false})
+			}
 		}
-		h.handleTypingIndicator(ctx, senderClient, payload)
-
-	default:
-		log.Printf("WebSocket Hub: Unknown message type '%s' from User %s", wsMsg.Type, senderClient.userID)
-		senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Unknown message type"})
 	}
 }
 
@@ -128,12 +117,31 @@ func (h *Hub) handleNewChatMessageViaWS(ctx context.Context, senderClient *Clien
 	var createdChat *models.Chat
 	var targetUserIDs []uuid.UUID
 
+	if payload.Content == "" && len(payload.AttachmentIDs) == 0 {
+		senderClient.SendError(ctx, ErrCodeValidation, "Message needs content or attachments")
+		return
+	}
+	attachments, err := h.guard.RequireSendableAttachments(ctx, senderClient.userID, payload.AttachmentIDs)
+	if err != nil {
+		log.Printf("WS Hub (NewMsgViaWS): User %s sent invalid attachments: %v", senderClient.userID, err)
+		h.sendAccessError(ctx, senderClient, err)
+		return
+	}
+
 	if payload.ChatID != nil {
 		chatID = *payload.ChatID
+		if !h.requireParticipant(ctx, senderClient, chatID) {
+			return
+		}
+		if err := h.guard.RequireCanMessage(ctx, chatID, senderClient.userID); err != nil {
+			log.Printf("WS Hub (NewMsgViaWS): User %s may not message chat %s: %v", senderClient.userID, chatID, err)
+			h.sendAccessError(ctx, senderClient, err)
+			return
+		}
 		allParticipants, err := h.chatStore.GetAllParticipantsInChat(ctx, chatID)
 		if err != nil {
 			log.Printf("WS Hub (NewMsgViaWS): Error fetching participants for chat %s: %v", chatID, err)
-			senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Error processing message details"})
+			senderClient.SendError(ctx, ErrCodeInternal, "Error processing message details")
 			return
 		}
 		for _, p := range allParticipants {
@@ -144,23 +152,31 @@ func (h *Hub) handleNewChatMessageViaWS(ctx context.Context, senderClient *Clien
 	} else if payload.ReceiverID != nil {
 		receiverID := *payload.ReceiverID
 		if senderClient.userID == receiverID {
-			senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Cannot send message to yourself"})
+			senderClient.SendError(ctx, ErrCodeValidation, "Cannot send message to yourself")
+			return
+		}
+		if err := h.guard.RequireNotBlocked(ctx, senderClient.userID, receiverID); err != nil {
+			log.Printf("WS Hub (NewMsgViaWS): User %s may not message user %s: %v", senderClient.userID, receiverID, err)
+			h.sendAccessError(ctx, senderClient, err)
 			return
 		}
 		participantIDs := []uuid.UUID{senderClient.userID, receiverID}
 		existingChat, err := h.chatStore.GetChatByParticipantIDs(ctx, participantIDs)
 		if err != nil && !errors.Is(err, store.ErrChatNotFound) {
 			log.Printf("WS Hub (NewMsgViaWS): Error finding chat: %v", err)
-			senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Error processing message"})
+			senderClient.SendError(ctx, ErrCodeInternal, "Error processing message")
 			return
 		}
 		if existingChat != nil {
 			chatID = existingChat.ID
+		} else if payload.ReplyToID != nil {
+			h.sendAccessError(ctx, senderClient, access.ErrInvalidReply)
+			return
 		} else {
 			newChat, createErr := h.chatStore.CreateChat(ctx, participantIDs)
 			if createErr != nil {
 				log.Printf("WS Hub (NewMsgViaWS): Error creating chat: %v", createErr)
-				senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Error creating chat for message"})
+				senderClient.SendError(ctx, ErrCodeInternal, "Error creating chat for message")
 				return
 			}
 			chatID = newChat.ID
@@ -168,10 +184,20 @@ func (h *Hub) handleNewChatMessageViaWS(ctx context.Context, senderClient *Clien
 		}
 		targetUserIDs = append(targetUserIDs, receiverID)
 	} else {
-		senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "New message requires chatId or receiverId"})
+		senderClient.SendError(ctx, ErrCodeValidation, "New message requires chatId or receiverId")
 		return
 	}
 
+	var parent *models.Message
+	if payload.ReplyToID != nil {
+		var err error
+		if parent, err = h.guard.RequireReplyTarget(ctx, chatID, *payload.ReplyToID); err != nil {
+			log.Printf("WS Hub (NewMsgViaWS): User %s sent an invalid reply to %s: %v", senderClient.userID, *payload.ReplyToID, err)
+			h.sendAccessError(ctx, senderClient, err)
+			return
+		}
+	}
+
 	dbMessage := &models.Message{
 		ID:        uuid.New(),
 		ChatID:    chatID,
@@ -179,20 +205,31 @@ func (h *Hub) handleNewChatMessageViaWS(ctx context.Context, senderClient *Clien
 		Content:   payload.Content,
 		Timestamp: time.Now(),
 		Status:    models.StatusSent,
+		ReplyToID: payload.ReplyToID,
+
+		Attachments: attachments,
+	}
+	if parent != nil {
+		dbMessage.ReplyTo = parent.Quote()
 	}
 	if err := h.messageStore.CreateMessage(ctx, dbMessage); err != nil {
+		if errors.Is(err, store.ErrAttachmentUnavailable) {
+			h.sendAccessError(ctx, senderClient, access.ErrInvalidAttachment)
+			return
+		}
 		log.Printf("WS Hub (NewMsgViaWS): Error saving message to DB: %v", err)
-		senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Failed to send message (DB error)"})
+		senderClient.SendError(ctx, ErrCodeInternal, "Failed to send message (DB error)")
 		return
 	}
 
 	senderUser, err := h.userStore.GetUserByID(ctx, senderClient.userID.String())
 	if err == nil && senderUser != nil {
-		dbMessage.Sender = senderUser.ToPublicUser()
+		dbMessage.Sender = senderUser.ProfileFor(models.RelationshipContact)
 	} else {
 		log.Printf("WS Hub (NewMsgViaWS): Could not fetch sender details for user %s: %v", senderClient.userID, err)
 		dbMessage.Sender = &models.PublicUser{ID: senderClient.userID, Username: "Unknown"}
 	}
+	utils.SignAttachmentURLs(dbMessage.Attachments, dbMessage.Timestamp)
 
 	ackPayload := MessageSentAckPayload{
 		ClientTempID: payload.ClientTempID,
@@ -201,7 +238,7 @@ func (h *Hub) handleNewChatMessageViaWS(ctx context.Context, senderClient *Clien
 		Timestamp:    models.JSONTime(dbMessage.Timestamp),
 		Status:       dbMessage.Status,
 	}
-	senderClient.SendMessage(MessageTypeMessageSentAck, ackPayload)
+	senderClient.Reply(ctx, MessageTypeMessageSentAck, ackPayload)
 
 	h.broadcastMessageToTargets(dbMessage, targetUserIDs, createdChat)
 }
@@ -228,60 +265,90 @@ func (h *Hub) BroadcastChatMessage(message *models.Message, initialChat *models.
 }
 
 func (h *Hub) broadcastMessageToTargets(message *models.Message, targetUserIDs []uuid.UUID, newChatInfo *models.Chat) {
-	h.clientsMux.RLock()
-	defer h.clientsMux.RUnlock()
+	log.Printf("Hub: Broadcasting message %s to %d recipient(s) in chat %s", message.ID, len(targetUserIDs), message.ChatID)
+	h.publishChatEvent(message.ChatID, &message.ID, targetUserIDs, MessageTypeNewMessage, message)
+}
 
-	for _, targetUserID := range targetUserIDs {
-		if userClients, found := h.clients[targetUserID]; found {
-			log.Printf("Hub: Broadcasting message %s to user %s (chat %s)", message.ID, targetUserID, message.ChatID)
-			for clientInstance := range userClients {
-				clientInstance.SendMessage(MessageTypeNewMessage, message)
-			}
-		} else {
-			log.Printf("Hub: Recipient %s for chat %s is not connected for message %s.", targetUserID, message.ChatID, message.ID)
-		}
+// publishChatEvent records a chat event in the event log and delivers it, tagged with its
+// seq, to the recipients' connected clients. Recipients who are offline pick it up on sync.
+func (h *Hub) publishChatEvent(chatID uuid.UUID, messageID *uuid.UUID, userIDs []uuid.UUID, msgType string, payload interface{}) {
+	event, err := h.eventLog.Append(context.Background(), chatID, messageID, msgType, payload)
+	if err != nil {
+		log.Printf("Hub (publishChatEvent): Error recording %s event for chat %s, delivering without seq: %v", msgType, chatID, err)
+		h.BroadcastToUsers(userIDs, msgType, payload)
+		return
 	}
+
+	h.deliverLocal(userIDs, func(c *Client) { c.SendEvent(event) })
+	h.publish(&envelope{Kind: envelopeEvent, UserIDs: userIDs, Event: event})
+}
+
+// PublishChatEvent records a chat event and delivers it to the listed users, who need not
+// all be members any more (e.g. a user who was just removed).
+func (h *Hub) PublishChatEvent(chatID uuid.UUID, userIDs []uuid.UUID, msgType string, payload interface{}) {
+	h.publishChatEvent(chatID, nil, userIDs, msgType, payload)
 }
 
 func (h *Hub) handleMessageStatusUpdate(ctx context.Context, senderClient *Client, payload MessageStatusUpdatePayload) {
-	err := h.messageStore.UpdateMessageStatus(ctx, payload.MessageID, payload.Status)
+	originalMessage, err := h.guard.RequireMessageAccess(ctx, payload.MessageID, senderClient.userID)
 	if err != nil {
-		log.Printf("WebSocket Hub (StatusUpdate): Error updating message status in DB: %v", err)
-		senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Failed to update message status (DB error)"})
+		log.Printf("WebSocket Hub (StatusUpdate): User %s denied status update for message %s: %v", senderClient.userID, payload.MessageID, err)
+		h.sendAccessError(ctx, senderClient, err)
+		return
+	}
+	if originalMessage.ChatID != payload.ChatID {
+		senderClient.SendError(ctx, ErrCodeValidation, "Message does not belong to the given chat")
 		return
 	}
-	log.Printf("WebSocket Hub (StatusUpdate): Message %s status updated to %s for chat %s by user %s",
-		payload.MessageID, payload.Status, payload.ChatID, senderClient.userID)
 
-	originalMessage, err := h.messageStore.GetMessageByID(ctx, payload.MessageID)
+	// Receipts are per-user pointers: acknowledging a message implicitly acknowledges
+	// everything before it in the chat.
+	advanced, err := h.messageStore.MarkMessagesUpTo(ctx, payload.ChatID, senderClient.userID, payload.MessageID, payload.Status)
 	if err != nil {
-		log.Printf("WebSocket Hub (StatusUpdate): Could not find original message %s: %v", payload.MessageID, err)
+		log.Printf("WebSocket Hub (StatusUpdate): Error updating receipt pointer in DB: %v", err)
+		senderClient.SendError(ctx, ErrCodeInternal, "Failed to update message status (DB error)")
+		return
+	}
+	if !advanced {
 		return
 	}
+	log.Printf("WebSocket Hub (StatusUpdate): User %s marked chat %s %s up to message %s",
+		senderClient.userID, payload.ChatID, payload.Status, payload.MessageID)
 
-	broadcastPayload := MessageStatusUpdatePayload{
+	h.BroadcastToChat(payload.ChatID, MessageTypeMessageStatusUpdate, MessageStatusUpdatePayload{
 		MessageID: payload.MessageID,
 		ChatID:    payload.ChatID,
 		Status:    payload.Status,
 		UserID:    senderClient.userID,
 		Timestamp: models.JSONTime(time.Now()),
-	}
-
-	h.clientsMux.RLock()
-	defer h.clientsMux.RUnlock()
+	})
+}
 
-	if originalMessage.SenderID != senderClient.userID {
-		if senderUserClients, found := h.clients[originalMessage.SenderID]; found {
-			for clientInstance := range senderUserClients {
-				clientInstance.SendMessage(MessageTypeMessageStatusUpdate, broadcastPayload)
-			}
-		}
+func (h *Hub) handleMarkChatRead(ctx context.Context, senderClient *Client, payload MarkChatReadPayload) {
+	if !h.requireParticipant(ctx, senderClient, payload.ChatID) {
+		return
 	}
-	if recipientUserClients, found := h.clients[senderClient.userID]; found {
-		for clientInstance := range recipientUserClients {
-			clientInstance.SendMessage(MessageTypeMessageStatusUpdate, broadcastPayload)
-		}
+	lastMessageID, advanced, err := h.messageStore.MarkChatRead(ctx, payload.ChatID, senderClient.userID)
+	if err != nil {
+		log.Printf("WebSocket Hub (MarkChatRead): Error marking chat %s read for user %s: %v", payload.ChatID, senderClient.userID, err)
+		senderClient.SendError(ctx, ErrCodeInternal, "Failed to mark chat as read (DB error)")
+		return
 	}
+	if advanced {
+		h.BroadcastReadReceipt(payload.ChatID, senderClient.userID, lastMessageID)
+	}
+}
+
+// BroadcastReadReceipt tells chat members that userID has read the chat up to messageID,
+// which also lets the reader's other devices clear their unread badges.
+func (h *Hub) BroadcastReadReceipt(chatID, userID, messageID uuid.UUID) {
+	h.BroadcastToChat(chatID, MessageTypeMessageStatusUpdate, MessageStatusUpdatePayload{
+		MessageID: messageID,
+		ChatID:    chatID,
+		Status:    models.StatusRead,
+		UserID:    userID,
+		Timestamp: models.JSONTime(time.Now()),
+	})
 }
 
 func (h *Hub) handleTypingIndicator(ctx context.Context, senderClient *Client, payload TypingIndicatorPayload) {
@@ -290,14 +357,18 @@ func (h *Hub) handleTypingIndicator(ctx context.Context, senderClient *Client, p
 
 	if payload.UserID != senderClient.userID {
 		log.Printf("WS Hub (Typing): Mismatched UserID in payload (%s) and client session (%s)", payload.UserID, senderClient.userID)
-		senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Typing indicator user ID mismatch"})
+		senderClient.SendError(ctx, ErrCodeValidation, "Typing indicator user ID mismatch")
+		return
+	}
+
+	if !h.requireParticipant(ctx, senderClient, payload.ChatID) {
 		return
 	}
 
 	allParticipants, err := h.chatStore.GetAllParticipantsInChat(ctx, payload.ChatID)
 	if err != nil {
 		log.Printf("WS Hub (Typing): Error fetching participants for chat %s: %v", payload.ChatID, err)
-		senderClient.SendMessage(MessageTypeError, ErrorPayload{Message: "Chat not found for typing indicator"})
+		senderClient.SendError(ctx, ErrCodeInternal, "Failed to load chat participants")
 		return
 	}
 
@@ -307,25 +378,155 @@ func (h *Hub) handleTypingIndicator(ctx context.Context, senderClient *Client, p
 			targetUserIDs = append(targetUserIDs, p.ID)
 		}
 	}
+	targetUserIDs, err = h.guard.WithoutBlockedPeers(ctx, senderClient.userID, targetUserIDs)
+	if err != nil {
+		log.Printf("WS Hub (Typing): Error filtering blocked users for %s: %v", senderClient.userID, err)
+		return
+	}
+
+	h.BroadcastToUsers(targetUserIDs, MessageTypeTypingIndicator, payload)
+}
+
+// BroadcastToUser sends a message to all connected clients for a user.
+func (h *Hub) BroadcastToUser(userID uuid.UUID, msgType string, payload interface{}) {
+	h.BroadcastToUsers([]uuid.UUID{userID}, msgType, payload)
+}
+
+// BroadcastToUsers sends a message to all connected clients of each listed user, on
+// every replica.
+func (h *Hub) BroadcastToUsers(userIDs []uuid.UUID, msgType string, payload interface{}) {
+	h.deliverLocal(userIDs, func(c *Client) { c.SendMessage(msgType, payload) })
+
+	raw, err := json.Marshal(payload)
+	if err != nil {
+		log.Printf("Hub (BroadcastToUsers): Error encoding %s payload: %v", msgType, err)
+		return
+	}
+	h.publish(&envelope{Kind: envelopeDeliver, UserIDs: userIDs, Type: msgType, Payload: raw})
+}
+
+// requireParticipant sends an error frame and returns false unless the client's user belongs to chatID.
+func (h *Hub) requireParticipant(ctx context.Context, client *Client, chatID uuid.UUID) bool {
+	if err := h.guard.RequireParticipant(ctx, chatID, client.userID); err != nil {
+		log.Printf("WebSocket Hub: User %s denied access to chat %s: %v", client.userID, chatID, err)
+		h.sendAccessError(ctx, client, err)
+		return false
+	}
+	return true
+}
+
+// sendAccessError maps access.Guard errors onto WebSocket error frames.
+func (h *Hub) sendAccessError(ctx context.Context, client *Client, err error) {
+	switch {
+	case errors.Is(err, access.ErrForbidden):
+		client.SendError(ctx, ErrCodeNotParticipant, "You are not a participant of this chat")
+	case errors.Is(err, access.ErrNotAdmin):
+		client.SendError(ctx, ErrCodeNotAdmin, "Only chat admins can perform this action")
+	case errors.Is(err, access.ErrBlocked):
+		client.SendError(ctx, ErrCodeRecipientUnavailable, "Unable to send messages to this user")
+	case errors.Is(err, access.ErrInvalidReply):
+		client.SendError(ctx, ErrCodeValidation, "Reply target must be an existing message in the same chat")
+	case errors.Is(err, access.ErrInvalidAttachment):
+		client.SendError(ctx, ErrCodeValidation, "Attachments must be your own uploads that have not been sent yet")
+	case errors.Is(err, store.ErrMessageNotFound):
+		client.SendError(ctx, ErrCodeMessageNotFound, "Message not found")
+	default:
+		client.SendError(ctx, ErrCodeInternal, "Failed to verify chat membership")
+	}
+}
+
+// DisconnectSession closes every connection authenticated with the given session, on
+// every replica.
+func (h *Hub) DisconnectSession(sessionID uuid.UUID) {
+	h.disconnectSessionLocal(sessionID)
+	h.publish(&envelope{Kind: envelopeDisconnectSession, SessionID: &sessionID})
+}
 
+func (h *Hub) disconnectSessionLocal(sessionID uuid.UUID) {
 	h.clientsMux.RLock()
-	for _, targetUserID := range targetUserIDs {
-		if userClients, found := h.clients[targetUserID]; found {
-			for clientInstance := range userClients {
-				clientInstance.SendMessage(MessageTypeTypingIndicator, payload)
+	defer h.clientsMux.RUnlock()
+	for _, userClients := range h.clients {
+		for client := range userClients {
+			if client.sessionID == sessionID {
+				log.Printf("WebSocket Hub: Closing connection of user %s, session %s revoked", client.userID, sessionID)
+				client.closeWithCode(closeCodeSessionRevoked, "session revoked")
 			}
 		}
 	}
-	h.clientsMux.RUnlock()
 }
 
-// BroadcastToUser sends a message to all connected clients for a user.
-func (h *Hub) BroadcastToUser(userID uuid.UUID, msgType string, payload interface{}) {
+// DisconnectUser closes every connection of the given user, on every replica.
+func (h *Hub) DisconnectUser(userID uuid.UUID) {
+	h.disconnectUserLocal(userID)
+	h.publish(&envelope{Kind: envelopeDisconnectUser, UserIDs: []uuid.UUID{userID}})
+}
+
+func (h *Hub) disconnectUserLocal(userID uuid.UUID) {
 	h.clientsMux.RLock()
 	defer h.clientsMux.RUnlock()
-	if userClients, found := h.clients[userID]; found {
-		for client := range userClients {
-			client.SendMessage(msgType, payload)
+	for client := range h.clients[userID] {
+		log.Printf("WebSocket Hub: Closing connection of user %s, all sessions revoked", userID)
+		client.closeWithCode(closeCodeSessionRevoked, "session revoked")
+	}
+}
+
+// BroadcastToChat sends a message to every connected participant of a chat, including
+// the acting user's other devices.
+func (h *Hub) BroadcastToChat(chatID uuid.UUID, msgType string, payload interface{}) {
+	h.broadcastChatEvent(chatID, nil, msgType, payload)
+}
+
+// BroadcastMessageEvent is BroadcastToChat for an event carrying a message's content,
+// which is recorded against the message so that it can be redacted later.
+func (h *Hub) BroadcastMessageEvent(message *models.Message, msgType string, payload interface{}) {
+	h.broadcastChatEvent(message.ChatID, &message.ID, msgType, payload)
+}
+
+func (h *Hub) broadcastChatEvent(chatID uuid.UUID, messageID *uuid.UUID, msgType string, payload interface{}) {
+	participants, err := h.chatStore.GetAllParticipantsInChat(context.Background(), chatID)
+	if err != nil {
+		log.Printf("Hub (BroadcastToChat): Error fetching participants for chat %s: %v", chatID, err)
+		return
+	}
+	userIDs := make([]uuid.UUID, 0, len(participants))
+	for _, p := range participants {
+		userIDs = append(userIDs, p.ID)
+	}
+	h.publishChatEvent(chatID, messageID, userIDs, msgType, payload)
+}
+
+// syncBatchSize is the number of replayed events per sync_batch frame.
+const syncBatchSize = 100
+
+// handleSync replays the events the client missed, in sync_batch frames followed by
+// sync_complete. Live chat events for this client are held back until then.
+func (h *Hub) handleSync(ctx context.Context, client *Client, payload models.SyncRequest) {
+	client.beginSync()
+	synced := make(map[uuid.UUID]int64)
+	defer func() { client.endSync(synced) }()
+
+	result, err := h.eventLog.Sync(ctx, client.userID, payload)
+	if err != nil {
+		if errors.Is(err, access.ErrForbidden) {
+			h.sendAccessError(ctx, client, err)
+			return
 		}
+		log.Printf("WebSocket Hub (Sync): Error syncing user %s: %v", client.userID, err)
+		client.SendError(ctx, ErrCodeInternal, "Failed to sync (DB error)")
+		return
+	}
+
+	for start := 0; start < len(result.Events); start += syncBatchSize {
+		end := min(start+syncBatchSize, len(result.Events))
+		client.Reply(ctx, MessageTypeSyncBatch, SyncBatchPayload{Events: result.Events[start:end]})
+	}
+	for _, state := range result.Chats {
+		synced[state.ChatID] = state.LastSeq
 	}
+	client.Reply(ctx, MessageTypeSyncComplete, SyncCompletePayload{
+		Chats:   result.Chats,
+		LastSeq: result.LastSeq,
+		HasMore: result.HasMore,
+	})
+	log.Printf("WebSocket Hub (Sync): Replayed %d event(s) to user %s", len(result.Events), client.userID)
 }
@@ -0,0 +1,139 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/broker"
+	"blinkchat-backend/internal/events"
+	"blinkchat-backend/internal/models"
+	"blinkchat-backend/internal/ratelimit"
+	"blinkchat-backend/internal/store"
+
+	"github.com/google/uuid"
+)
+
+// accessFixture is a hub on the memory store with a direct chat between two members,
+// one message in it, and a user outside the chat.
+type accessFixture struct {
+	hub      *Hub
+	member   uuid.UUID
+	outsider uuid.UUID
+	chatID   uuid.UUID
+	message  *models.Message
+}
+
+func newAccessFixture(t *testing.T) *accessFixture {
+	t.Helper()
+	ctx := context.Background()
+	db := store.NewMemoryDB()
+	us := store.NewMemoryUserStore(db)
+	cs := store.NewMemoryChatStore(db)
+	ms := store.NewMemoryMessageStore(db)
+	guard := access.NewGuard(cs, ms, store.NewMemoryBlockStore(db), store.NewMemoryAttachmentStore(db))
+	eventLog := events.NewLog(store.NewMemoryEventStore(db), guard)
+	limiter := ratelimit.NewLimiter(ratelimit.NewMemoryStore(), nil)
+	hub := NewHub(us, cs, ms, guard, eventLog, broker.NewInProcess(), limiter)
+
+	var ids [3]uuid.UUID
+	for i := range ids {
+		u := &models.User{ID: uuid.New(), CreatedAt: time.Now(), UpdatedAt: time.Now()}
+		u.Username = fmt.Sprintf("user%d_%s", i, u.ID.String()[:8])
+		u.Email = u.Username + "@example.com"
+		if err := us.CreateUser(ctx, u); err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		ids[i] = u.ID
+	}
+	chat, err := cs.CreateChat(ctx, []uuid.UUID{ids[0], ids[1]})
+	if err != nil {
+		t.Fatalf("CreateChat: %v", err)
+	}
+	msg := &models.Message{ID: uuid.New(), ChatID: chat.ID, SenderID: ids[0], Content: "hi", Timestamp: time.Now(), Status: models.StatusSent}
+	if err := ms.CreateMessage(ctx, msg); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	return &accessFixture{hub: hub, member: ids[0], outsider: ids[2], chatID: chat.ID, message: msg}
+}
+
+// newTestClient returns a client without a connection; frames sent to it stay queued.
+func newTestClient(hub *Hub, userID uuid.UUID) *Client {
+	return &Client{hub: hub, send: make(chan []byte, 16), userID: userID, version: ProtocolVersionCurrent}
+}
+
+// sentFrame is an outgoing frame as the client sees it.
+type sentFrame struct {
+	Type      string       `json:"type"`
+	RequestID string       `json:"requestId"`
+	Payload   ErrorPayload `json:"payload"`
+}
+
+func nextFrame(t *testing.T, client *Client) sentFrame {
+	t.Helper()
+	select {
+	case raw := <-client.send:
+		var frame sentFrame
+		if err := json.Unmarshal(raw, &frame); err != nil {
+			t.Fatalf("decode frame %s: %v", raw, err)
+		}
+		return frame
+	default:
+		t.Fatalf("no frame was sent")
+		return sentFrame{}
+	}
+}
+
+func TestHubRejectsNonParticipants(t *testing.T) {
+	f := newAccessFixture(t)
+	tests := []struct {
+		name    string
+		msgType string
+		payload any
+	}{
+		{"new message", MessageTypeNewMessage, NewMessagePayload{ChatID: &f.chatID, Content: "let me in"}},
+		{"status update", MessageTypeMessageStatusUpdate, MessageStatusUpdatePayload{MessageID: f.message.ID, ChatID: f.chatID, Status: models.StatusRead}},
+		{"mark chat read", MessageTypeMarkChatRead, MarkChatReadPayload{ChatID: f.chatID}},
+		{"typing indicator", MessageTypeTypingIndicator, TypingIndicatorPayload{ChatID: f.chatID, UserID: f.outsider, IsTyping: true}},
+		{"sync", MessageTypeSync, models.SyncRequest{Chats: []models.SyncChatCursor{{ChatID: f.chatID}}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := newTestClient(f.hub, f.outsider)
+			payload, err := json.Marshal(tt.payload)
+			if err != nil {
+				t.Fatalf("encode payload: %v", err)
+			}
+			frame, err := json.Marshal(incomingMessage{Version: ProtocolVersionCurrent, Type: tt.msgType, RequestID: "req-1", Payload: payload})
+			if err != nil {
+				t.Fatalf("encode frame: %v", err)
+			}
+			f.hub.dispatch(client, frame)
+
+			got := nextFrame(t, client)
+			if got.Type != MessageTypeError || got.Payload.Code != ErrCodeNotParticipant {
+				t.Errorf("got %s frame with code %q, want error with code %q", got.Type, got.Payload.Code, ErrCodeNotParticipant)
+			}
+			if got.RequestID != "req-1" {
+				t.Errorf("requestId = %q, want it echoed", got.RequestID)
+			}
+		})
+	}
+}
+
+func TestHubLetsParticipantsMarkRead(t *testing.T) {
+	f := newAccessFixture(t)
+	client := newTestClient(f.hub, f.member)
+	payload, _ := json.Marshal(MarkChatReadPayload{ChatID: f.chatID})
+	frame, _ := json.Marshal(incomingMessage{Type: MessageTypeMarkChatRead, Payload: payload})
+	f.hub.dispatch(client, frame)
+
+	select {
+	case raw := <-client.send:
+		t.Fatalf("member got unexpected frame %s", raw)
+	default:
+	}
+}
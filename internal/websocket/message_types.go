@@ -11,20 +11,49 @@ const (
 	MessageTypeMessageStatusUpdate = "message_status_update"
 	MessageTypeError               = "error"
 	MessageTypeTypingIndicator     = "typing_indicator"
+	MessageTypeChatMemberAdded     = "chat_member_added"
+	MessageTypeChatMemberRemoved   = "chat_member_removed"
+	MessageTypeChatMemberUpdated   = "chat_member_updated"
+	MessageTypeMessageEdited       = "message_edited"
+	MessageTypeMessageDeleted      = "message_deleted"
+	MessageTypeMarkChatRead        = "mark_chat_read"
+	MessageTypeSync                = "sync"
+	MessageTypeSyncBatch           = "sync_batch"
+	MessageTypeSyncComplete        = "sync_complete"
+	MessageTypePresenceUpdate      = "presence_update"
+	MessageTypeWelcome             = "welcome"
+	MessageTypeReactionAdded       = "reaction_added"
+	MessageTypeReactionRemoved     = "reaction_removed"
+	MessageTypeAttachmentProcessed = "attachment_processed"
 )
 
-// WebSocketMessage wraps all WebSocket traffic.
+// WebSocketMessage wraps all WebSocket traffic. Seq is set on chat events recorded in the
+// event log and is the cursor clients send back when they sync. Version and RequestID are
+// only sent to protocol version 2 clients; RequestID echoes the frame being answered.
 type WebSocketMessage struct {
-	Type    string      `json:"type"`
-	Payload interface{} `json:"payload,omitempty"`
+	Version   int         `json:"v,omitempty"`
+	Type      string      `json:"type"`
+	RequestID string      `json:"requestId,omitempty"`
+	Seq       int64       `json:"seq,omitempty"`
+	Payload   interface{} `json:"payload,omitempty"`
 }
 
-// NewMessagePayload describes a chat message sent by a client.
+// WelcomePayload is the first frame of a version 2 connection.
+type WelcomePayload struct {
+	Version           int       `json:"version"`
+	SupportedVersions []int     `json:"supportedVersions"`
+	UserID            uuid.UUID `json:"userId"`
+}
+
+// NewMessagePayload describes a chat message sent by a client. It needs content,
+// attachments or both.
 type NewMessagePayload struct {
-	ChatID       *uuid.UUID `json:"chatId,omitempty"`
-	ReceiverID   *uuid.UUID `json:"receiverId,omitempty"`
-	Content      string     `json:"content"`
-	ClientTempID *string    `json:"clientTempId,omitempty"`
+	ChatID        *uuid.UUID  `json:"chatId,omitempty"`
+	ReceiverID    *uuid.UUID  `json:"receiverId,omitempty"`
+	ReplyToID     *uuid.UUID  `json:"replyToId,omitempty"`
+	Content       string      `json:"content" binding:"max=4096"`
+	AttachmentIDs []uuid.UUID `json:"attachmentIds,omitempty" binding:"max=10"`
+	ClientTempID  *string     `json:"clientTempId,omitempty"`
 }
 
 // MessageSentAckPayload acknowledges message persistence.
@@ -38,22 +67,65 @@ type MessageSentAckPayload struct {
 
 // MessageStatusUpdatePayload notifies clients of delivery/read updates.
 type MessageStatusUpdatePayload struct {
-	MessageID uuid.UUID            `json:"messageId"`
-	ChatID    uuid.UUID            `json:"chatId"`
-	Status    models.MessageStatus `json:"status"`
+	MessageID uuid.UUID            `json:"messageId" binding:"required"`
+	ChatID    uuid.UUID            `json:"chatId" binding:"required"`
+	Status    models.MessageStatus `json:"status" binding:"required,oneof=delivered read"`
 	UserID    uuid.UUID            `json:"userId,omitempty"`
 	Timestamp models.JSONTime      `json:"timestamp"`
 }
 
+// MarkChatReadPayload asks to mark every message in a chat as read.
+type MarkChatReadPayload struct {
+	ChatID uuid.UUID `json:"chatId" binding:"required"`
+}
+
+// SyncBatchPayload carries a slice of replayed events, in seq order.
+type SyncBatchPayload struct {
+	Events []*models.ChatEvent `json:"events"`
+}
+
+// SyncCompletePayload ends a sync; live delivery resumes after it.
+type SyncCompletePayload struct {
+	Chats   []models.SyncChatState `json:"chats"`
+	LastSeq int64                  `json:"lastSeq"`
+	HasMore bool                   `json:"hasMore"`
+}
+
 // ErrorPayload represents an error message to the client.
 type ErrorPayload struct {
-	Message string `json:"message"`
-	Code    int    `json:"code,omitempty"`
+	Code         ErrorCode `json:"code"`
+	Message      string    `json:"message"`
+	RetryAfterMs int64     `json:"retryAfterMs,omitempty"`
 }
 
 // TypingIndicatorPayload signals typing state in a chat.
 type TypingIndicatorPayload struct {
-	ChatID   uuid.UUID `json:"chatId"`
+	ChatID   uuid.UUID `json:"chatId" binding:"required"`
 	UserID   uuid.UUID `json:"userId"`
 	IsTyping bool      `json:"isTyping"`
 }
+
+// ChatMemberEventPayload notifies participants that a group's membership changed.
+type ChatMemberEventPayload struct {
+	ChatID   uuid.UUID          `json:"chatId"`
+	ChatName string             `json:"chatName,omitempty"`
+	Member   *models.PublicUser `json:"member"`
+	Role     models.ChatRole    `json:"role,omitempty"`
+	ActorID  uuid.UUID          `json:"actorId"`
+}
+
+// MessageDeletedPayload tells clients to replace a message with a tombstone.
+type MessageDeletedPayload struct {
+	MessageID uuid.UUID       `json:"messageId"`
+	ChatID    uuid.UUID       `json:"chatId"`
+	DeletedAt models.JSONTime `json:"deletedAt"`
+}
+
+// ReactionEventPayload tells chat members that a user added or removed a reaction.
+type ReactionEventPayload struct {
+	MessageID uuid.UUID       `json:"messageId"`
+	ChatID    uuid.UUID       `json:"chatId"`
+	UserID    uuid.UUID       `json:"userId"`
+	Emoji     string          `json:"emoji"`
+	Timestamp models.JSONTime `json:"timestamp"`
+}
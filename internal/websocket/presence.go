@@ -0,0 +1,145 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"blinkchat-backend/internal/access"
+	"blinkchat-backend/internal/models"
+
+	"github.com/google/uuid"
+)
+
+// presenceChange is a user's first connection to, or last disconnection from, this replica.
+type presenceChange struct {
+	userID uuid.UUID
+	online bool
+}
+
+// presenceQueue hands presence transitions from Run to runPresence without ever blocking
+// Run. Transitions of a user still waiting in the queue collapse into the latest one.
+type presenceQueue struct {
+	mu      sync.Mutex
+	pending map[uuid.UUID]bool // user -> online
+	order   []uuid.UUID
+	ready   chan struct{}
+}
+
+func newPresenceQueue() *presenceQueue {
+	return &presenceQueue{pending: make(map[uuid.UUID]bool), ready: make(chan struct{}, 1)}
+}
+
+// push records the user's latest transition and wakes runPresence.
+func (q *presenceQueue) push(change presenceChange) {
+	q.mu.Lock()
+	if _, queued := q.pending[change.userID]; !queued {
+		q.order = append(q.order, change.userID)
+	}
+	q.pending[change.userID] = change.online
+	q.mu.Unlock()
+
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// drain returns the queued transitions, oldest user first, and empties the queue.
+func (q *presenceQueue) drain() []presenceChange {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	changes := make([]presenceChange, 0, len(q.order))
+	for _, userID := range q.order {
+		changes = append(changes, presenceChange{userID: userID, online: q.pending[userID]})
+	}
+	clear(q.pending)
+	q.order = q.order[:0]
+	return changes
+}
+
+// runPresence applies presence transitions in the order Run saw them. A user who connects
+// and disconnects again before runPresence gets to them is only reported in their final state.
+func (h *Hub) runPresence() {
+	for range h.presenceChanges.ready {
+		for _, change := range h.presenceChanges.drain() {
+			if change.online {
+				h.userCameOnline(change.userID)
+			} else {
+				h.userWentOffline(change.userID)
+			}
+		}
+	}
+}
+
+// userCameOnline runs when a user's first client registers on this replica. Chat partners
+// are only notified if the user was not already online on another replica.
+func (h *Hub) userCameOnline(userID uuid.UUID) {
+	onlineElsewhere := h.isUserOnlineRemotely(userID)
+	h.announcePresence(userID, true)
+	if onlineElsewhere {
+		return
+	}
+	h.pushPresence(userID, &models.Presence{UserID: userID, Online: true})
+}
+
+// userWentOffline runs when a user's last client on this replica unregisters. It records
+// last-seen and notifies chat partners unless the user is still online on another replica.
+func (h *Hub) userWentOffline(userID uuid.UUID) {
+	h.announcePresence(userID, false)
+
+	ctx := context.Background()
+	if err := h.userStore.UpdateLastSeen(ctx, userID, time.Now()); err != nil {
+		log.Printf("Hub (presence): Error updating last seen of user %s: %v", userID, err)
+	}
+	if h.isUserOnlineRemotely(userID) {
+		return
+	}
+
+	user, err := h.userStore.GetUserByID(ctx, userID.String())
+	if err != nil {
+		log.Printf("Hub (presence): Error fetching user %s: %v", userID, err)
+		return
+	}
+	h.pushPresence(userID, user.PresenceFor(uuid.Nil, false))
+}
+
+// pushPresence sends a presence_update to everyone who shares a chat with the user,
+// except people on either side of a block.
+func (h *Hub) pushPresence(userID uuid.UUID, presence *models.Presence) {
+	ctx := context.Background()
+	partnerIDs, err := h.chatStore.GetChatPartnerIDs(ctx, userID)
+	if err != nil {
+		log.Printf("Hub (presence): Error fetching chat partners of user %s: %v", userID, err)
+		return
+	}
+	partnerIDs, err = h.guard.WithoutBlockedPeers(ctx, userID, partnerIDs)
+	if err != nil {
+		log.Printf("Hub (presence): Error filtering blocked users for %s: %v", userID, err)
+		return
+	}
+	if len(partnerIDs) == 0 {
+		return
+	}
+	h.BroadcastToUsers(partnerIDs, MessageTypePresenceUpdate, presence)
+}
+
+// GetPresence returns userID's presence as seen by viewerID. Across a block the user
+// always appears offline with no last-seen time.
+func (h *Hub) GetPresence(ctx context.Context, viewerID, userID uuid.UUID) (*models.Presence, error) {
+	user, err := h.userStore.GetUserByID(ctx, userID.String())
+	if err != nil {
+		return nil, err
+	}
+	if viewerID != userID {
+		if err := h.guard.RequireNotBlocked(ctx, viewerID, userID); err != nil {
+			if errors.Is(err, access.ErrBlocked) {
+				return &models.Presence{UserID: userID}, nil
+			}
+			return nil, err
+		}
+	}
+	return user.PresenceFor(viewerID, h.IsUserOnline(userID)), nil
+}
@@ -0,0 +1,38 @@
+package websocket
+
+import (
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestPresenceQueueCoalescesPerUser(t *testing.T) {
+	q := newPresenceQueue()
+	alice, bob := uuid.New(), uuid.New()
+
+	// Far more transitions than any buffer would hold; push must never block.
+	for i := 0; i < 1000; i++ {
+		q.push(presenceChange{userID: alice, online: i%2 == 0})
+	}
+	q.push(presenceChange{userID: bob, online: true})
+	q.push(presenceChange{userID: alice, online: true})
+
+	select {
+	case <-q.ready:
+	default:
+		t.Fatal("push did not signal ready")
+	}
+	got := q.drain()
+	want := []presenceChange{{userID: alice, online: true}, {userID: bob, online: true}}
+	if len(got) != len(want) {
+		t.Fatalf("drained %d changes, want %d: %v", len(got), len(want), got)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("change %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if rest := q.drain(); len(rest) != 0 {
+		t.Errorf("queue still holds %v after drain", rest)
+	}
+}
@@ -0,0 +1,63 @@
+package websocket
+
+import (
+	"context"
+	"slices"
+)
+
+// Protocol versions. Clients ask for one with the v query parameter at /ws; the server
+// speaks the highest version that does not exceed the request.
+//
+//	1: {type, seq, payload} frames; the default when v is omitted.
+//	2: adds v to every frame, echoes the client's requestId on replies and sends a
+//	   welcome frame on connect.
+const (
+	ProtocolVersionLegacy  = 1
+	ProtocolVersionCurrent = 2
+)
+
+// SupportedProtocolVersions lists the versions /ws accepts, oldest first.
+var SupportedProtocolVersions = []int{ProtocolVersionLegacy, ProtocolVersionCurrent}
+
+// negotiateProtocolVersion picks the version to speak with a client asking for requested.
+func negotiateProtocolVersion(requested int) (int, bool) {
+	version := min(requested, ProtocolVersionCurrent)
+	return version, slices.Contains(SupportedProtocolVersions, version)
+}
+
+// maxRequestIDLength bounds client-supplied request IDs.
+const maxRequestIDLength = 128
+
+// ErrorCode is the machine-readable reason carried by error frames.
+type ErrorCode string
+
+const (
+	ErrCodeInvalidFrame         ErrorCode = "invalid_frame"       // not a JSON envelope
+	ErrCodeUnsupportedVersion   ErrorCode = "unsupported_version" // frame v differs from the negotiated version
+	ErrCodeUnknownType          ErrorCode = "unknown_type"        // no handler for the frame type
+	ErrCodeInvalidPayload       ErrorCode = "invalid_payload"     // payload does not decode for the type
+	ErrCodeValidation           ErrorCode = "validation_failed"   // payload decodes but a field is invalid
+	ErrCodeNotParticipant       ErrorCode = "not_participant"     // user is not a member of the chat
+	ErrCodeNotAdmin             ErrorCode = "not_admin"           // action needs the chat admin role
+	ErrCodeChatNotFound         ErrorCode = "chat_not_found"
+	ErrCodeMessageNotFound      ErrorCode = "message_not_found"
+	ErrCodeRecipientUnavailable ErrorCode = "recipient_unavailable" // a block stops messages between the users
+	ErrCodeRateLimited          ErrorCode = "rate_limited"          // too many frames; retry after retryAfterMs
+	ErrCodeInternal             ErrorCode = "internal_error"        // server-side failure; retrying may help
+)
+
+type requestIDKey struct{}
+
+// withRequestID returns a context carrying the requestId of the frame being handled.
+func withRequestID(ctx context.Context, requestID string) context.Context {
+	if requestID == "" {
+		return ctx
+	}
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+// requestIDFromContext returns the requestId to echo on replies, if any.
+func requestIDFromContext(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}